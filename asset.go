@@ -0,0 +1,119 @@
+package commonjs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+// Wraps a module providing a non JavaScript asset, for example to export its
+// content.
+type AssetWrapper func(m Module) Module
+
+// Provides the default wrappers used by providers for names with an
+// extension, keyed by the extension. The result may be modified and passed to
+// NewAssetDirProvider or NewAssetFSProvider, for example to add text assets:
+//
+//	assets := commonjs.DefaultAssets()
+//	assets[".html"] = commonjs.NewTextModule
+func DefaultAssets() map[string]AssetWrapper {
+	return map[string]AssetWrapper{
+		".json":  NewJSONContentModule,
+		".css":   NewStyleContentModule,
+		".png":   NewBinaryModule,
+		".jpg":   NewBinaryModule,
+		".jpeg":  NewBinaryModule,
+		".gif":   NewBinaryModule,
+		".webp":  NewBinaryModule,
+		".svg":   NewBinaryModule,
+		".ico":   NewBinaryModule,
+		".woff":  NewBinaryModule,
+		".woff2": NewBinaryModule,
+		".ttf":   NewBinaryModule,
+		".eot":   NewBinaryModule,
+	}
+}
+
+var defaultAssets = DefaultAssets()
+
+// Finds the wrapper for the name based on its extension.
+func assetWrapper(assets map[string]AssetWrapper, name string) (AssetWrapper, bool) {
+	if assets == nil {
+		assets = defaultAssets
+	}
+	wrap, ok := assets[path.Ext(name)]
+	return wrap, ok
+}
+
+type jsonContentModule struct {
+	Module
+}
+
+// Wraps a module where the content is JSON, exporting the JSON value. This
+// mirrors the ability of Node to require .json files.
+func NewJSONContentModule(m Module) Module {
+	return &jsonContentModule{Module: m}
+}
+
+func (m *jsonContentModule) Content() ([]byte, error) {
+	content, err := m.Module.Content()
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimSpace(content)
+	if !json.Valid(content) {
+		return nil, fmt.Errorf("module %s does not contain valid JSON", m.Name())
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("module.exports=")
+	buf.Write(content)
+	buf.WriteString(";")
+	return buf.Bytes(), nil
+}
+
+func (m *jsonContentModule) Require() ([]string, error) {
+	return nil, nil
+}
+
+func (m *jsonContentModule) Ext() string {
+	return jsExt
+}
+
+func (m *jsonContentModule) unwrap() Module { return m.Module }
+
+type textModule struct {
+	Module
+}
+
+// Wraps a module where the content is text, like a HTML template or SVG image,
+// exporting the content as a string.
+func NewTextModule(m Module) Module {
+	return &textModule{Module: m}
+}
+
+func (m *textModule) Content() ([]byte, error) {
+	content, err := m.Module.Content()
+	if err != nil {
+		return nil, err
+	}
+	quoted, err := json.Marshal(string(content))
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("module.exports=")
+	buf.Write(quoted)
+	buf.WriteString(";")
+	return buf.Bytes(), nil
+}
+
+func (m *textModule) Require() ([]string, error) {
+	return nil, nil
+}
+
+func (m *textModule) Ext() string {
+	return jsExt
+}
+
+func (m *textModule) unwrap() Module { return m.Module }
@@ -0,0 +1,102 @@
+package commonjs_test
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestDirProviderJSON(t *testing.T) {
+	t.Parallel()
+	p := commonjs.NewDirProvider("_test")
+	m, err := p.Module("c/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `module.exports={"debug": true, "name": "require(\"x\")"};`
+	if string(content) != expected {
+		t.Fatalf("expected %s got %s", expected, content)
+	}
+	require, err := m.Require()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(require) != 0 {
+		t.Fatalf("was not expecting any requires, got %v", require)
+	}
+	if _, err := p.Module("c/missing.json"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestFSProviderJSON(t *testing.T) {
+	t.Parallel()
+	p := commonjs.NewFSProvider(fstest.MapFS{
+		"good.json": &fstest.MapFile{Data: []byte("[1,2]\n")},
+		"bad.json":  &fstest.MapFile{Data: []byte("{")},
+	})
+	m, err := p.Module("good.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "module.exports=[1,2];" {
+		t.Fatalf("unexpected content %s", content)
+	}
+	m, err = p.Module("bad.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Content(); err == nil {
+		t.Fatal("was expecting an error")
+	}
+	if _, err := commonjs.NewFSProvider(os.DirFS("_test")).Module("missing.json"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestTextAsset(t *testing.T) {
+	t.Parallel()
+	assets := commonjs.DefaultAssets()
+	assets[".html"] = commonjs.NewTextModule
+	for _, p := range []commonjs.Provider{
+		commonjs.NewAssetDirProvider("_test", assets),
+		commonjs.NewAssetFSProvider(os.DirFS("_test"), assets),
+	} {
+		m, err := p.Module("tmpl/foo.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `module.exports="\u003cdiv class=\"x\"\u003e\n  require(\"y\")\n\u003c/div\u003e\n";`
+		if string(content) != expected {
+			t.Fatalf("expected %s got %s", expected, content)
+		}
+		require, err := m.Require()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(require) != 0 {
+			t.Fatalf("was not expecting any requires, got %v", require)
+		}
+	}
+}
+
+func TestAssetNotConfigured(t *testing.T) {
+	t.Parallel()
+	if _, err := commonjs.NewDirProvider("_test").Module("tmpl/foo.html"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
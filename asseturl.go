@@ -0,0 +1,131 @@
+package commonjs
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var reStyleURL = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+
+type binaryModule struct {
+	Module
+}
+
+// Wraps a module providing a binary asset like an image or font. These are
+// served by App.AssetURL, and cannot be required.
+func NewBinaryModule(m Module) Module {
+	return &binaryModule{Module: m}
+}
+
+func (m *binaryModule) Require() ([]string, error) {
+	return nil, nil
+}
+
+func (m *binaryModule) Ext() string {
+	return strings.TrimPrefix(path.Ext(m.Name()), ".")
+}
+
+func (m *binaryModule) unwrap() Module { return m.Module }
+
+// Returns a hashed URL for the named asset, like an image or font. The URL
+// ends with the extension of the name.
+func (a *App) AssetURL(name string) (string, error) {
+	name = a.Alias(name)
+	a.mu.Lock()
+	url, ok := a.assetURLs[name]
+	a.mu.Unlock()
+	if ok {
+		return url, nil
+	}
+
+	m, err := a.Module(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := m.Content()
+	if err != nil {
+		return "", err
+	}
+	suffix := path.Ext(name)
+	if suffix == "" {
+		return "", fmt.Errorf("asset %s does not have an extension", name)
+	}
+	if url, err = a.storePackage([]string{name}, content, suffix); err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	if a.assetURLs == nil {
+		a.assetURLs = make(map[string]string)
+	}
+	a.assetURLs[name] = url
+	a.mu.Unlock()
+	return url, nil
+}
+
+// Check if the URL refers to something other than an asset module.
+func isExternalURL(url string) bool {
+	return isExternalImport(url) || strings.HasPrefix(url, "/") ||
+		strings.HasPrefix(url, "#")
+}
+
+// Resolves a url() reference in the named stylesheet. Like in browsers, it is
+// relative to the directory of the stylesheet even without a leading "./".
+func resolveStyleURL(name, ref string) string {
+	if !strings.HasPrefix(ref, "./") && !strings.HasPrefix(ref, "../") {
+		ref = "./" + ref
+	}
+	return ResolveName(name, ref)
+}
+
+// Replaces the first submatch of each match of the pattern with the hashed
+// URL of the asset it names, resolved relative to the named module.
+func (a *App) rewriteAssets(name string, content []byte, re *regexp.Regexp, resolve func(from, name string) string) ([]byte, error) {
+	matches := re.FindAllSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, nil
+	}
+	out := new(bytes.Buffer)
+	last := 0
+	for _, match := range matches {
+		if len(match) < 4 || match[2] < 0 {
+			continue
+		}
+		asset := string(content[match[2]:match[3]])
+		if isExternalURL(asset) {
+			continue
+		}
+		url, err := a.AssetURL(resolve(name, asset))
+		if err != nil {
+			return nil, err
+		}
+		out.Write(content[last:match[2]])
+		out.WriteString(url)
+		last = match[3]
+	}
+	out.Write(content[last:])
+	return out.Bytes(), nil
+}
+
+// Rewrites the asset references in JavaScript content using AssetPatterns.
+func (a *App) rewriteScriptAssets(name string, content []byte) ([]byte, error) {
+	var err error
+	for _, re := range a.AssetPatterns {
+		if content, err = a.rewriteAssets(name, content, re, ResolveName); err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
+
+// The Content-Type for an asset served at the URL path.
+func assetContentType(urlPath string) string {
+	if t := mime.TypeByExtension(path.Ext(urlPath)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,112 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestAssetURL(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := app.AssetURL("img/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(actualURL, "/r/") || !strings.HasSuffix(actualURL, ".png") {
+		t.Fatalf("unexpected url %s", actualURL)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if contentType := w.Header().Get("Content-Type"); contentType != "image/png" {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")) {
+		t.Fatal("did not find expected content")
+	}
+}
+
+func TestStyleAssetURL(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	logoURL, err := app.AssetURL("img/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := app.Styles([]string{"css/logo.css"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`url("` + logoURL + `")`, "url(data:image/png;base64,AAA=)"} {
+		if !strings.Contains(string(content), expected) {
+			println(string(content))
+			t.Fatalf("did not find %s in content above", expected)
+		}
+	}
+}
+
+func TestStyleAssetURLRelative(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewStyleModule("img/icons.css", []byte(".logo { background: url(logo.png); }")),
+		},
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	logoURL, err := app.AssetURL("img/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := app.Styles([]string{"img/icons.css"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "url("+logoURL+")") {
+		println(string(content))
+		t.Fatalf("did not find %s in content above", logoURL)
+	}
+}
+
+func TestScriptAssetPatterns(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("a/logo", []byte("img.src = asset('../img/logo.png')")),
+		},
+		Providers:     []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore:  commonjs.NewMemoryStore(),
+		AssetPatterns: []*regexp.Regexp{regexp.MustCompile(`asset\('([^']+)'\)`)},
+	}
+	logoURL, err := app.AssetURL("img/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualURL, err := app.ModulesURL([]string{"a/logo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	expected := "asset('" + logoURL + "')"
+	if !strings.Contains(w.Body.String(), expected) {
+		println(w.Body.String())
+		t.Fatalf("did not find %s in content above", expected)
+	}
+}
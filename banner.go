@@ -0,0 +1,49 @@
+package commonjs
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var reLicense = regexp.MustCompile(`(?s)/\*!.*?\*/`)
+
+// Find all license comments, those starting with /*!, in the given content.
+func ParseLicenses(content []byte) [][]byte {
+	return reLicense.FindAll(content, -1)
+}
+
+// Collects the license comments from the content of a module in the build.
+func (b *build) addLicenses(content []byte) {
+	for _, license := range ParseLicenses(content) {
+		b.addLicense(license)
+	}
+}
+
+// Collects the license comment if it has not been seen.
+func (b *build) addLicense(license []byte) {
+	if b.seenLicenses == nil {
+		b.seenLicenses = make(map[string]bool)
+	}
+	if !b.seenLicenses[string(license)] {
+		b.seenLicenses[string(license)] = true
+		b.licenses = append(b.licenses, license)
+	}
+}
+
+// The Banner, collected licenses and embedded prelude written at the top of a
+// package.
+func (a *App) header(b *build) []byte {
+	out := new(bytes.Buffer)
+	if a.Banner != "" {
+		out.WriteString("/*! ")
+		out.WriteString(strings.Replace(a.Banner, "*/", "* /", -1))
+		out.WriteString(" */\n")
+	}
+	for _, license := range b.licenses {
+		out.Write(license)
+		out.WriteString("\n")
+	}
+	out.Write(b.prelude)
+	return out.Bytes()
+}
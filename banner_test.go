@@ -0,0 +1,49 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestPreserveLicenses(t *testing.T) {
+	t.Parallel()
+	const license = "/*! lib v1 | MIT */"
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("lib", []byte(license+"\n/* not a license */ lib")),
+			commonjs.NewScriptModule("other", []byte(license+" require('lib')")),
+		},
+		ContentStore:     commonjs.NewMemoryStore(),
+		Banner:           "app 1.2.3 */ abc",
+		PreserveLicenses: true,
+	}
+	actualURL, err := app.ModulesURL([]string{"other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	expected := "/*! app 1.2.3 * / abc */\n" + license + "\ndefine("
+	if !strings.HasPrefix(w.Body.String(), expected) {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+	if strings.Contains(w.Body.String(), "not a license */\n") {
+		println(w.Body.String())
+		t.Fatal("was not expecting a regular comment in the header")
+	}
+}
+
+func TestParseLicenses(t *testing.T) {
+	t.Parallel()
+	actual := commonjs.ParseLicenses([]byte("/*! a\n b */ x /* c */ /*!d*/"))
+	if len(actual) != 2 || string(actual[0]) != "/*! a\n b */" || string(actual[1]) != "/*!d*/" {
+		t.Fatalf("unexpected licenses %q", actual)
+	}
+}
@@ -0,0 +1,57 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newBenchApp() *commonjs.App {
+	return &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+}
+
+func BenchmarkModulesURLCached(b *testing.B) {
+	app := newBenchApp()
+	modules := []string{"a/foo", "b/baz"}
+	if _, err := app.ModulesURL(modules); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := app.ModulesURL(modules); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkModulesURLUncached(b *testing.B) {
+	modules := []string{"a/foo", "b/baz"}
+	for i := 0; i < b.N; i++ {
+		if _, err := newBenchApp().ModulesURL(modules); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	app := newBenchApp()
+	actualURL, err := app.ModulesURL([]string{"a/foo", "b/baz"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	r := &http.Request{URL: &url.URL{Path: actualURL}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		if w.Code != 200 {
+			b.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package commonjs
+
+import (
+	"context"
+)
+
+// Returns a URL like ModulesURL for a package which additionally runs the
+// bootstrap code after defining the modules, for example
+// `require("app").init()`. This removes the need for an inline script on
+// simple pages. The code runs as soon as the package loads, so modules in the
+// separate Vendor package should be used via execute() which waits for them.
+func (a *App) ModulesURLWithBootstrap(modules []string, bootstrap []byte) (string, error) {
+	exclude, err := a.vendorSet()
+	if err != nil {
+		return "", err
+	}
+	return a.packageURL(context.Background(), packageSpec{modules: modules, bootstrap: bootstrap}, exclude)
+}
+
+// Returns a URL for a self-sufficient package with the prelude, along with
+// the PreludeExtensions it needs and the loader configuration, prepended to
+// the modules. This is useful for embeds and third-party widgets where the host
+// page cannot include the inline scripts. Vendor modules are included in the
+// package. Since the prelude defines the globals, the package should not be
+// used on pages which already include the prelude.
+func (a *App) ModulesURLWithPrelude(modules []string) (string, error) {
+	return a.packageURL(context.Background(), packageSpec{modules: modules, prelude: true}, nil)
+}
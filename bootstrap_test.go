@@ -0,0 +1,118 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestModulesURLWithBootstrap(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("app", []byte("exports.init = 1"))},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	plain, err := app.ModulesURL([]string{"app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrap := []byte(`require("app").init();`)
+	withBootstrap, err := app.ModulesURLWithBootstrap([]string{"app"}, bootstrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain == withBootstrap {
+		t.Fatal("was expecting a different url for the package with bootstrap code")
+	}
+	other, err := app.ModulesURLWithBootstrap([]string{"app"}, []byte(`require("app").other();`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == withBootstrap {
+		t.Fatal("was expecting a different url for different bootstrap code")
+	}
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: withBootstrap}})
+	expected := "define(\"app\",\"exports.init = 1\");\n" + string(bootstrap)
+	if w.Body.String() != expected {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+
+	manifest, err := app.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &commonjs.App{MountPath: "r", ContentStore: commonjs.NewMemoryStore()}
+	if err := loaded.LoadManifest(bytes.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	again, err := loaded.ModulesURLWithBootstrap([]string{"app"}, bootstrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != withBootstrap {
+		t.Fatalf("was expecting %s from the manifest, got %s", withBootstrap, again)
+	}
+}
+
+func TestModulesURLWithBootstrapStream(t *testing.T) {
+	t.Parallel()
+	newApp := func(store commonjs.ByteStore) *commonjs.App {
+		return &commonjs.App{
+			MountPath:    "r",
+			Modules:      []commonjs.Module{commonjs.NewScriptModule("app", []byte("exports.init = 1"))},
+			ContentStore: store,
+		}
+	}
+	bootstrap := []byte(`require("app").init();`)
+	buffered, err := newApp(commonjs.NewMemoryStore()).ModulesURLWithBootstrap([]string{"app"}, bootstrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := newApp(commonjs.NewDiskStore(t.TempDir())).ModulesURLWithBootstrap([]string{"app"}, bootstrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buffered != streamed {
+		t.Fatalf("was expecting the same url when streaming, got %s and %s", buffered, streamed)
+	}
+}
+
+func TestModulesURLWithPrelude(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("widget", []byte("exports.w = 1"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		Vendor:       []string{"widget"},
+	}
+	u, err := app.ModulesURLWithPrelude([]string{"widget"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prelude, err := app.ScriptPrelude()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u}})
+	expected := string(prelude) + "define(\"widget\",\"exports.w = 1\");\n"
+	if w.Body.String() != expected {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+	plain, err := app.VendorURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain == u {
+		t.Fatal("was expecting a different url for the package with the prelude")
+	}
+}
@@ -0,0 +1,109 @@
+package commonjs
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// Caches the parsed dependencies and transformed content of modules across
+// builds, keyed by the module name and checked against a hash of the module
+// content. Rebuilding a package after a module changes only parses and
+// transforms the changed module, though the others are still read to check
+// their hash. The cache must be discarded if the Transform or RequireParser
+// changes.
+type BuildCache struct {
+	mu      sync.Mutex
+	entries map[string]*buildCacheEntry
+}
+
+type buildCacheEntry struct {
+	sum         [sha256.Size]byte
+	require     []string
+	parsed      bool
+	transformed []byte
+}
+
+// Create an empty BuildCache.
+func NewBuildCache() *BuildCache {
+	return &BuildCache{entries: make(map[string]*buildCacheEntry)}
+}
+
+// Invalidate the cached entry for the named module.
+func (c *BuildCache) Invalidate(name string) {
+	c.mu.Lock()
+	delete(c.entries, name)
+	c.mu.Unlock()
+}
+
+// Invalidate all cached entries.
+func (c *BuildCache) InvalidateAll() {
+	c.mu.Lock()
+	c.entries = make(map[string]*buildCacheEntry)
+	c.mu.Unlock()
+}
+
+// The entry for the named module if it matches the content hash, or a new
+// entry replacing a stale one. Must be called with the lock held.
+func (c *BuildCache) entry(name string, sum [sha256.Size]byte) *buildCacheEntry {
+	e := c.entries[name]
+	if e == nil || e.sum != sum {
+		e = &buildCacheEntry{sum: sum}
+		c.entries[name] = e
+	}
+	return e
+}
+
+// The required modules of the module with the given content, if cached.
+func (c *BuildCache) require(name string, content []byte) ([]string, bool) {
+	sum := sha256.Sum256(content)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[name]
+	if e == nil || e.sum != sum || !e.parsed {
+		return nil, false
+	}
+	return append([]string(nil), e.require...), true
+}
+
+func (c *BuildCache) setRequire(name string, content []byte, require []string) {
+	sum := sha256.Sum256(content)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(name, sum)
+	e.require = append([]string(nil), require...)
+	e.parsed = true
+}
+
+// The transformed content of the module with the given content, if cached.
+func (c *BuildCache) transformed(name string, content []byte) ([]byte, bool) {
+	sum := sha256.Sum256(content)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[name]
+	if e == nil || e.sum != sum || e.transformed == nil {
+		return nil, false
+	}
+	return e.transformed, true
+}
+
+func (c *BuildCache) setTransformed(name string, content, transformed []byte) {
+	sum := sha256.Sum256(content)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entry(name, sum).transformed = transformed
+}
+
+// Discards the cached package URLs, so the next request for a package builds
+// it again. Along with a BuildCache, this allows quickly picking up changed
+// modules during development.
+func (a *App) InvalidatePackages() {
+	a.mu.Lock()
+	a.packageURLs = nil
+	a.standaloneURLs = nil
+	a.styleURLs = nil
+	a.inlineOnly = nil
+	a.inlineEntries = nil
+	a.extensionNames = nil
+	a.conflicts = nil
+	a.mu.Unlock()
+}
@@ -0,0 +1,83 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type countingTransform struct {
+	count int32
+}
+
+func (c *countingTransform) Transform(m commonjs.Module) (commonjs.Module, error) {
+	atomic.AddInt32(&c.count, 1)
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	return commonjs.NewScriptModule(m.Name(), bytes.ToUpper(content)), nil
+}
+
+type countingParser struct {
+	count int32
+}
+
+func (c *countingParser) Parse(content []byte) ([]string, error) {
+	atomic.AddInt32(&c.count, 1)
+	return commonjs.NewScriptModule("", content).Require()
+}
+
+func TestBuildCache(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name, content string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name+".js"), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "require('b')")
+	write("b", "b")
+	transform := &countingTransform{}
+	parser := &countingParser{}
+	app := &commonjs.App{
+		MountPath:     "r",
+		Providers:     []commonjs.Provider{commonjs.NewDirProvider(dir)},
+		ContentStore:  commonjs.NewMemoryStore(),
+		Transform:     transform,
+		RequireParser: parser,
+		BuildCache:    commonjs.NewBuildCache(),
+	}
+	if _, err := app.ModulesURL([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	transforms, parses := transform.count, parser.count
+
+	write("b", "changed")
+	app.InvalidatePackages()
+	u, err := app.ModulesURL([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := transform.count - transforms; n != 1 {
+		t.Fatalf("was expecting 1 transform, got %d", n)
+	}
+	if n := parser.count - parses; n != 1 {
+		t.Fatalf("was expecting 1 parse, got %d", n)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u}})
+	const expected = "define(\"a\",\"REQUIRE('B')\");\ndefine(\"b\",\"CHANGED\");\n"
+	if w.Body.String() != expected {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
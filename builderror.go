@@ -0,0 +1,60 @@
+package commonjs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The operation that failed while building a package.
+type BuildOp string
+
+const (
+	OpFind        BuildOp = "find"        // finding the module
+	OpRead        BuildOp = "read"        // reading the module content
+	OpParse       BuildOp = "parse"       // parsing the module dependencies
+	OpTransform   BuildOp = "transform"   // applying the Transform
+	OpRewrite     BuildOp = "rewrite"     // rewriting asset references
+	OpFormat      BuildOp = "format"      // writing the module in the OutputFormat
+	OpPostProcess BuildOp = "postprocess" // applying the PostProcess transforms to the package
+)
+
+// Provides context about a failure building a package, which allows for
+// actionable error pages in development.
+type BuildError struct {
+	Op     BuildOp
+	Module string   // name of the module
+	Origin string   // where the module came from, if known
+	Chain  []string // dependency chain leading to the module, if known
+	Err    error
+}
+
+func (e *BuildError) Error() string {
+	msg := fmt.Sprintf("%s module %s", e.Op, e.Module)
+	if e.Origin != "" {
+		msg += " (" + e.Origin + ")"
+	}
+	if len(e.Chain) > 1 {
+		msg += " required via " + strings.Join(e.Chain, " > ")
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// Wraps the error in a BuildError, unless it already is one.
+func buildError(op BuildOp, name, origin string, chain []string, err error) error {
+	var be *BuildError
+	if errors.As(err, &be) || err == errBuildTooLarge {
+		return err
+	}
+	return &BuildError{
+		Op:     op,
+		Module: name,
+		Origin: origin,
+		Chain:  append([]string(nil), chain...),
+		Err:    err,
+	}
+}
@@ -0,0 +1,60 @@
+package commonjs_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type failingTransform int
+
+func (failingTransform) Transform(m commonjs.Module) (commonjs.Module, error) {
+	return nil, errors.New("transform failed")
+}
+
+func TestBuildErrorTransform(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Transform:    failingTransform(0),
+	}
+	_, err := app.ModulesURL([]string{"bar"})
+	var be *commonjs.BuildError
+	if !errors.As(err, &be) {
+		t.Fatalf("was expecting a build error, got %v", err)
+	}
+	if be.Op != commonjs.OpTransform || be.Module != "bar" || be.Origin == "" {
+		t.Fatalf("unexpected build error %#v", be)
+	}
+}
+
+func TestBuildErrorChain(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("page", []byte("require('widget')")),
+			commonjs.NewScriptModule("widget", []byte("require('gone')")),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	_, err := app.ModulesURL([]string{"page"})
+	var be *commonjs.BuildError
+	if !errors.As(err, &be) {
+		t.Fatalf("was expecting a build error, got %v", err)
+	}
+	expected := []string{"page", "widget", "gone"}
+	if be.Op != commonjs.OpFind || !reflect.DeepEqual(be.Chain, expected) {
+		t.Fatalf("unexpected build error %#v", be)
+	}
+	if commonjs.IsNotFound(err) {
+		t.Fatal("a missing dependency should not be a not found error")
+	}
+
+	_, err = app.ModulesURL([]string{"gone"})
+	if !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package commonjs
+
+import (
+	"sync"
+	"time"
+)
+
+// A Provider that memoizes module lookups and module content from another
+// Provider. This is useful for URL backed and other slow Providers.
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+	mu       sync.Mutex
+	entries  map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	module  Module
+	err     error
+	expires time.Time
+}
+
+// Wraps another Provider and caches the modules it provides, including the
+// fact that a module was not found. Entries expire after the given duration,
+// or never if it is zero.
+func NewCachingProvider(p Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider: p,
+		ttl:      ttl,
+		entries:  make(map[string]*cacheEntry),
+	}
+}
+
+func (p *CachingProvider) Module(name string) (Module, error) {
+	p.mu.Lock()
+	e := p.entries[name]
+	p.mu.Unlock()
+	if e != nil && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		return e.module, e.err
+	}
+
+	e = &cacheEntry{}
+	m, err := p.provider.Module(name)
+	if err == nil {
+		e.module, err = newCachedModule(m)
+	}
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	e.err = err
+	if p.ttl > 0 {
+		e.expires = time.Now().Add(p.ttl)
+	}
+	p.mu.Lock()
+	p.entries[name] = e
+	p.mu.Unlock()
+	return e.module, e.err
+}
+
+// Invalidate the cached entry for the named module.
+func (p *CachingProvider) Invalidate(name string) {
+	p.mu.Lock()
+	delete(p.entries, name)
+	p.mu.Unlock()
+}
+
+// Invalidate all cached entries.
+func (p *CachingProvider) InvalidateAll() {
+	p.mu.Lock()
+	p.entries = make(map[string]*cacheEntry)
+	p.mu.Unlock()
+}
+
+// A module with its content and required modules read up front.
+type cachedModule struct {
+	Module
+	content []byte
+	require []string
+}
+
+func newCachedModule(m Module) (Module, error) {
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	require, err := m.Require()
+	if err != nil {
+		return nil, err
+	}
+	return &cachedModule{Module: m, content: content, require: require}, nil
+}
+
+func (m *cachedModule) Content() ([]byte, error) {
+	return m.content, nil
+}
+
+func (m *cachedModule) Require() ([]string, error) {
+	return m.require, nil
+}
+
+// The wrapped module, so its Origin and file are still found.
+func (m *cachedModule) unwrap() Module { return m.Module }
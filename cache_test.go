@@ -0,0 +1,84 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type countingProvider struct {
+	commonjs.Provider
+	count int
+}
+
+func (p *countingProvider) Module(name string) (commonjs.Module, error) {
+	p.count++
+	return p.Provider.Module(name)
+}
+
+func TestCachingProvider(t *testing.T) {
+	t.Parallel()
+	counter := &countingProvider{Provider: commonjs.NewDirProvider("_test")}
+	p := commonjs.NewCachingProvider(counter, 0)
+	for i := 0; i < 2; i++ {
+		m, err := p.Module("b/baz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "require('bar')\n" {
+			t.Fatalf("did not find expected content, found %s", content)
+		}
+		if _, err := p.Module("xyz"); !commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting a not found error, got %v", err)
+		}
+	}
+	if counter.count != 2 {
+		t.Fatalf("was expecting 2 lookups, got %d", counter.count)
+	}
+	p.Invalidate("b/baz")
+	if _, err := p.Module("b/baz"); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count != 3 {
+		t.Fatalf("was expecting 3 lookups, got %d", counter.count)
+	}
+	p.InvalidateAll()
+	p.Module("b/baz")
+	p.Module("xyz")
+	if counter.count != 5 {
+		t.Fatalf("was expecting 5 lookups, got %d", counter.count)
+	}
+}
+
+func TestCachingProviderTTL(t *testing.T) {
+	t.Parallel()
+	counter := &countingProvider{Provider: commonjs.NewDirProvider("_test")}
+	p := commonjs.NewCachingProvider(counter, time.Millisecond)
+	p.Module("bar")
+	time.Sleep(5 * time.Millisecond)
+	p.Module("bar")
+	if counter.count != 2 {
+		t.Fatalf("was expecting 2 lookups, got %d", counter.count)
+	}
+}
+
+func TestCachingProviderOrigin(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers: []commonjs.Provider{
+			commonjs.NewCachingProvider(commonjs.NewDirProvider("_test"), 0),
+		},
+	}
+	origin, err := app.Origin("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != filepath.Join("_test", "bar.js") {
+		t.Fatalf("was expecting the file as the origin, got %s", origin)
+	}
+}
@@ -0,0 +1,6 @@
+var $ = require('jquery')
+var config = require('config').module
+
+exports.show = function(id) {
+  $('#' + id).html('about ' + config.title + (config.dev ? ' (dev)' : ''))
+}
@@ -1,71 +1,124 @@
 // Command cjse provides an example of an application built using go.commonjs
 // and go.h.
+//
+// It serves two pages sharing a vendor package containing jQuery and
+// Bootstrap, along with per page packages built from the modules in this
+// directory. Run it with -dev to disable minification.
+//
+// Running "cjse vendor <dir>" downloads the third party libraries to the
+// directory, which can then be served offline using -vendored <dir>.
 package main
 
 import (
+	"embed"
+	"flag"
 	"github.com/daaku/go.commonjs"
 	"github.com/daaku/go.commonjs/jsh"
 	"github.com/daaku/go.commonjs/jslib"
-	"github.com/daaku/go.fs/pkgfs"
 	"github.com/daaku/go.h"
 	"log"
 	"net/http"
 )
 
-var jsApp = &commonjs.App{
-	MountPath:    "/r/",
-	ContentStore: commonjs.NewMemoryStore(),
-	Transform:    commonjs.JSMin,
-	Providers: []commonjs.Provider{
-		commonjs.NewFileSystemProvider(
-			pkgfs.New(pkgfs.Config{
-				ImportPath: "github.com/daaku/go.commonjs/cjse",
-				Glob:       "*.js",
-			})),
-	},
-	Modules: []commonjs.Module{
-		jslib.JQuery_1_8_2,
-		jslib.Bootstrap_2_2_2,
-	},
+//go:embed *.js
+var scripts embed.FS
+
+// Configuration made available to the modules as the "config" module.
+type config struct {
+	Title string `json:"title"`
+	Dev   bool   `json:"dev"`
+}
+
+func newApp(dev bool) *commonjs.App {
+	app := &commonjs.App{
+		MountPath:    "/r/",
+		ContentStore: commonjs.NewMemoryStore(),
+		Providers: []commonjs.Provider{
+			commonjs.NewCachingProvider(commonjs.NewFSProvider(scripts), 0),
+		},
+		Modules: []commonjs.Module{
+			jslib.JQuery("1.8.2"),
+			jslib.Bootstrap_2_2_2,
+			commonjs.NewJSONModule("config", &config{
+				Title: "CommonJS Example",
+				Dev:   dev,
+			}),
+		},
+		Vendor: []string{"jquery", "bootstrap"},
+	}
+	if !dev {
+		app.Transform = commonjs.JSMin
+	}
+	return app
+}
+
+type page struct {
+	app   *commonjs.App
+	title string
+	calls []jsh.Call
 }
 
 func main() {
-	http.Handle(jsApp.MountPath, jsApp)
-	http.HandleFunc("/", handler)
-	log.Println("Listening on 8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	dev := flag.Bool("dev", false, "disable minification")
+	vendored := flag.String("vendored", "", "serve libraries vendored to this directory")
+	flag.Parse()
+
+	app := newApp(*dev)
+	if flag.Arg(0) == "vendor" {
+		if flag.NArg() != 2 {
+			log.Fatal("usage: cjse vendor <dir>")
+		}
+		if err := app.VendorModules(flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *vendored != "" {
+		if err := app.UseVendoredModules(*vendored); err != nil {
+			log.Fatal(err)
+		}
+	}
+	http.Handle(app.MountPath, app)
+	http.Handle("/", &page{
+		app:   app,
+		title: "Home",
+		calls: []jsh.Call{{Module: "cjse", Function: "log", Args: []interface{}{elementID}}},
+	})
+	http.Handle("/about", &page{
+		app:   app,
+		title: "About",
+		calls: []jsh.Call{{Module: "about", Function: "show", Args: []interface{}{elementID}}},
+	})
+	log.Printf("Listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	const elementID = "cjse-log"
+const elementID = "cjse-log"
+
+func (p *page) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_, err := h.Write(w, &h.Document{
 		Inner: &h.Frag{
 			&h.Head{
 				Inner: &h.Frag{
 					&h.Meta{Charset: "utf-8"},
-					&h.Title{h.String("CommonJS Example")},
+					&h.Title{h.String(p.title)},
 				},
 			},
 			&h.Body{
 				Inner: &h.Frag{
 					&h.H1{ID: elementID},
 					&jsh.AppScripts{
-						App: jsApp,
-						Calls: []jsh.Call{
-							jsh.Call{
-								Module:   "cjse",
-								Function: "log",
-								Args:     []interface{}{elementID},
-							},
-						},
+						App:   p.app,
+						Calls: p.calls,
 					},
 				},
 			},
 		},
 	})
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
 	}
 }
@@ -0,0 +1,59 @@
+package commonjs
+
+import (
+	"context"
+)
+
+// A ByteStore that buffers writes may implement StoreFlusher to allow them to
+// be flushed when the App is closed.
+type StoreFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Registers a function to be called by Close. Subsystems running in the
+// background use this to be stopped cleanly.
+func (a *App) onClose(fn func(ctx context.Context) error) {
+	a.mu.Lock()
+	a.closers = append(a.closers, fn)
+	a.mu.Unlock()
+}
+
+// Close stops background subsystems, waits for in-flight builds to finish,
+// rejecting new ones, and
+// flushes the ContentStore if it implements StoreFlusher. The context bounds
+// the time spent waiting. The first error encountered is returned, though all
+// subsystems are asked to stop.
+func (a *App) Close(ctx context.Context) error {
+	a.mu.Lock()
+	closers := a.closers
+	a.closers = nil
+	a.mu.Unlock()
+
+	var first error
+	for ix := len(closers) - 1; ix >= 0; ix-- {
+		if err := closers[ix](ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.buildLimiter().close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if first == nil {
+			first = ctx.Err()
+		}
+		return first
+	}
+
+	if f, ok := a.ContentStore.(StoreFlusher); ok {
+		if err := f.Flush(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,61 @@
+package commonjs_test
+
+import (
+	"context"
+	"github.com/daaku/go.commonjs"
+	"testing"
+)
+
+type flushStore struct {
+	commonjs.ByteStore
+	flushed bool
+}
+
+func (s *flushStore) Flush(ctx context.Context) error {
+	s.flushed = true
+	return nil
+}
+
+func TestAppClose(t *testing.T) {
+	t.Parallel()
+	store := &flushStore{ByteStore: commonjs.NewMemoryStore()}
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: store,
+	}
+	if _, err := a.ModulesURL([]string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !store.flushed {
+		t.Fatal("was expecting the store to be flushed")
+	}
+}
+
+func TestAppCloseRejectsBuilds(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			a.InvalidatePackages()
+			a.ModulesURL([]string{"bar"})
+		}
+	}()
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	a.InvalidatePackages()
+	if _, err := a.ModulesURL([]string{"bar"}); err == nil {
+		t.Fatal("was expecting builds to be rejected after close")
+	}
+}
@@ -0,0 +1,96 @@
+package closure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/daaku/go.commonjs"
+)
+
+// The globals and properties used by the prelude, the loader and the HMR
+// runtime, which must survive renaming.
+var preludeProperties = []string{
+	"accept", "args", "base", "define", "dispose", "execute", "exports", "fn",
+	"hot", "init", "load", "module", "name", "redefine", "require",
+}
+
+var reIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// Compiles whole packages with ADVANCED_OPTIMIZATIONS, renaming properties
+// consistently across modules. This usually yields much smaller packages than
+// minifying each module, but only works for modules written with advanced
+// optimizations in mind. Use it as a PostProcess transform along with the
+// FunctionFormat, since the StringFormat hides the code from the compiler:
+//
+//	app := &commonjs.App{
+//		OutputFormat: commonjs.FunctionFormat,
+//		PostProcess:  []commonjs.Transform{&closure.Bundle{Exports: []string{"main"}}},
+//	}
+//
+// The properties used by the prelude are always preserved.
+type Bundle struct {
+	Exports []string // properties used from outside the package, like the functions called by jsh.Call
+	Externs string   // optional additional externs
+	URL     string   // optional URL of the Closure API
+}
+
+// The generated externs declaring the prelude globals and the preserved
+// properties.
+func (b *Bundle) GeneratedExterns() (string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(append([]string(nil), preludeProperties...), b.Exports...) {
+		if !reIdentifier.MatchString(name) {
+			return "", fmt.Errorf("closure: export %q is not an identifier", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var out strings.Builder
+	out.WriteString("var define = function(name, payload) {};\n")
+	out.WriteString("var require = function(name) {};\n")
+	out.WriteString("var execute = function(call) {};\n")
+	out.WriteString("var __commonjs = {};\n")
+	for _, name := range names {
+		fmt.Fprintf(&out, "__commonjs.%s;\n", name)
+	}
+	out.WriteString(b.Externs)
+	return out.String(), nil
+}
+
+// Compiles the package.
+func (b *Bundle) Transform(m commonjs.Module) (commonjs.Module, error) {
+	return b.TransformContext(context.Background(), m)
+}
+
+// Compiles the package, using the context for the request to the Closure API.
+func (b *Bundle) TransformContext(ctx context.Context, m commonjs.Module) (commonjs.Module, error) {
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	externs, err := b.GeneratedExterns()
+	if err != nil {
+		return nil, err
+	}
+	val := url.Values{}
+	val.Add("js_code", string(content))
+	val.Add("js_externs", externs)
+	val.Add("compilation_level", string(AdvancedOptimizations))
+	val.Add("output_info", "errors")
+	cr, err := compile(ctx, b.URL, val)
+	if err != nil {
+		return nil, err
+	}
+	if err := cr.err(); err != nil {
+		return nil, err
+	}
+	return commonjs.NewScriptModule(m.Name(), []byte(cr.CompiledCode)), nil
+}
@@ -0,0 +1,73 @@
+package closure_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/closure"
+)
+
+func TestBundleExterns(t *testing.T) {
+	t.Parallel()
+	b := &closure.Bundle{Exports: []string{"main", "main"}}
+	externs, err := b.GeneratedExterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"var define", "__commonjs.exports;", "__commonjs.main;\n"} {
+		if !strings.Contains(externs, e) {
+			println(externs)
+			t.Fatalf("did not find %s", e)
+		}
+	}
+	if strings.Count(externs, "__commonjs.main;") != 1 {
+		t.Fatal("was expecting exports once")
+	}
+	b.Exports = []string{"not-an-identifier"}
+	if _, err := b.GeneratedExterns(); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("compilation_level") != string(closure.AdvancedOptimizations) {
+			t.Errorf("unexpected compilation level %s", r.FormValue("compilation_level"))
+		}
+		if !strings.Contains(r.FormValue("js_externs"), "__commonjs.main;") {
+			t.Errorf("did not find externs in %s", r.FormValue("js_externs"))
+		}
+		if !strings.Contains(r.FormValue("js_code"), `define("a",function(require,exports,module){`) {
+			t.Errorf("was expecting the function format in %s", r.FormValue("js_code"))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"compiledCode": "compiled"})
+	}))
+	defer server.Close()
+	app := &commonjs.App{
+		ContentStore: commonjs.NewMemoryStore(),
+		OutputFormat: commonjs.FunctionFormat,
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("a", []byte("exports.main=1"))},
+		PostProcess:  []commonjs.Transform{&closure.Bundle{Exports: []string{"main"}, URL: server.URL}},
+	}
+	if _, err := app.ModulesURL([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundleErrors(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors":[{"error":"Parse error","lineno":3}]}`))
+	}))
+	defer server.Close()
+	b := &closure.Bundle{URL: server.URL}
+	_, err := b.Transform(commonjs.NewScriptModule("a", []byte("(")))
+	if err == nil || !strings.Contains(err.Error(), "line 3: Parse error") {
+		t.Fatalf("was expecting the compile error, got %v", err)
+	}
+}
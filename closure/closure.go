@@ -3,9 +3,12 @@
 package closure
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 // Defines the various compilation levels provided by the Closure API.
@@ -22,14 +25,28 @@ const defaultURL = "http://closure-compiler.appspot.com/compile"
 // Defines a set of options for minifying JavaScript code.
 type Closure struct {
 	Level CompilationLevel
+	URL   string // optional URL of the Closure API
+}
+
+type closureMessage struct {
+	Error  string `json:"error"`
+	Lineno int    `json:"lineno"`
 }
 
 type closureResponse struct {
-	CompiledCode string `json:"compiledCode"`
+	CompiledCode string           `json:"compiledCode"`
+	Errors       []closureMessage `json:"errors"`
+	ServerErrors []closureMessage `json:"serverErrors"`
 }
 
 // Minifies the given JavaScript code.
 func (c *Closure) Transform(content []byte) ([]byte, error) {
+	return c.TransformContext(context.Background(), content)
+}
+
+// Minifies the given JavaScript code, using the context for the request to
+// the Closure API.
+func (c *Closure) TransformContext(ctx context.Context, content []byte) ([]byte, error) {
 	l := string(c.Level)
 	if l == "" {
 		l = string(SimpleOptimizations)
@@ -37,9 +54,27 @@ func (c *Closure) Transform(content []byte) ([]byte, error) {
 	val := url.Values{}
 	val.Add("js_code", string(content))
 	val.Add("compilation_level", l)
+	cr, err := compile(ctx, c.URL, val)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(cr.CompiledCode), nil
+}
+
+// Sends the compile request to the Closure API.
+func compile(ctx context.Context, apiURL string, val url.Values) (*closureResponse, error) {
+	if apiURL == "" {
+		apiURL = defaultURL
+	}
 	val.Add("output_format", "json")
 	val.Add("output_info", "compiled_code")
-	resp, err := http.PostForm(defaultURL, val)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", apiURL, strings.NewReader(val.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -48,5 +83,15 @@ func (c *Closure) Transform(content []byte) ([]byte, error) {
 	if err = json.NewDecoder(resp.Body).Decode(cr); err != nil {
 		return nil, err
 	}
-	return []byte(cr.CompiledCode), nil
+	return cr, nil
+}
+
+// The first error in the response, if any.
+func (cr *closureResponse) err() error {
+	for _, msgs := range [][]closureMessage{cr.ServerErrors, cr.Errors} {
+		if len(msgs) > 0 {
+			return fmt.Errorf("closure: line %d: %s", msgs[0].Lineno, msgs[0].Error)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,52 @@
+// Command bundlediff compares two manifests written by App.WriteManifest and
+// reports the added, removed and changed packages along with the per module
+// size changes. It exits with a non zero status if a package grew by more
+// than the allowed number of bytes, which allows for use in code review:
+//
+//	bundlediff -max-growth 10240 base/manifest.json head/manifest.json
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func main() {
+	maxGrowth := flag.Int("max-growth", -1, "fail if a package grows by more than this many bytes, -1 to disable")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		log.Fatal("usage: bundlediff [-max-growth bytes] old.json new.json")
+	}
+
+	before, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer before.Close()
+	after, err := os.Open(flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer after.Close()
+
+	diff, err := commonjs.DiffManifests(before, after)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := diff.WriteReport(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if *maxGrowth < 0 {
+		return
+	}
+	grown := diff.Grown(*maxGrowth)
+	for _, p := range grown {
+		log.Printf("package %s grew by %d bytes", p, p.Delta())
+	}
+	if len(grown) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+// Command jshgen generates typed Go functions constructing jsh.Call values for
+// the functions exported by the JavaScript modules in a directory.
+//
+// It is intended for use with go generate:
+//
+//	//go:generate jshgen -dir js -pkg bindings -out bindings.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/jshgen"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory containing the modules")
+	pattern := flag.String("pattern", "*.js", "pattern matching the module files")
+	pkg := flag.String("pkg", "bindings", "name of the generated package")
+	out := flag.String("out", "", "output file, defaults to stdout")
+	flag.Parse()
+
+	modules, err := commonjs.NewGlobModules(*dir, *pattern)
+	if err != nil {
+		log.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := jshgen.Generate(buf, *pkg, modules); err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := ioutil.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
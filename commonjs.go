@@ -3,12 +3,15 @@ package commonjs
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
+	iofs "io/fs"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
 	"path"
@@ -16,6 +19,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/daaku/go.fs"
 )
@@ -62,6 +67,34 @@ type ByteStore interface {
 	Get(key string) ([]byte, error)
 }
 
+// A RequireParser finds the names of modules required by some content.
+type RequireParser interface {
+	Parse(content []byte) ([]string, error)
+}
+
+// Adapts a function into a RequireParser.
+type RequireParserFunc func(content []byte) ([]string, error)
+
+// Parse calls f(content).
+func (f RequireParserFunc) Parse(content []byte) ([]string, error) {
+	return f(content)
+}
+
+// Provides the file name, without the extension, for a package. The name must
+// end with the hash, which is used to find the package when serving it. This
+// allows for human readable names like "home-7a1b2c3".
+type URLNamer interface {
+	Name(hash string, modules []string) string
+}
+
+// Provides a URLNamer for a function.
+type URLNamerFunc func(hash string, modules []string) string
+
+// Name calls f(hash, modules).
+func (f URLNamerFunc) Name(hash string, modules []string) string {
+	return f(hash, modules)
+}
+
 // Package content may be transformed. This is useful for minification for
 // example.
 type Transform interface {
@@ -76,8 +109,8 @@ func (e errModuleNotFound) Error() string {
 
 // Check if the error indicates the module was not found.
 func IsNotFound(err error) bool {
-	_, ok := err.(errModuleNotFound)
-	return ok
+	var notFound errModuleNotFound
+	return errors.As(err, &notFound)
 }
 
 type literalModule struct {
@@ -123,14 +156,31 @@ func (m *literalModule) Ext() string {
 type jsonModule struct {
 	name  string
 	value interface{}
+	opts  JSONOptions
+}
+
+// Options for NewJSONModuleWithOptions.
+type JSONOptions struct {
+	Export  string                              // optional export target, defaults to "module.exports"
+	Indent  string                              // optional indent for pretty printing
+	Marshal func(v interface{}) ([]byte, error) // optional encoding instead of json.Marshal, for types like time.Time
 }
 
 // Define a module as a JSON data structure. This is useful to inject
-// configuration data for example.
+// configuration data for example. The value is available as the "module"
+// property of the exports, use NewJSONModuleWithOptions to export the value
+// itself.
 func NewJSONModule(name string, v interface{}) Module {
+	return NewJSONModuleWithOptions(name, v, JSONOptions{Export: "exports.module"})
+}
+
+// Define a module as a JSON data structure exported as configured by the
+// options, by default as the module itself like a required .json file.
+func NewJSONModuleWithOptions(name string, v interface{}, opts JSONOptions) Module {
 	return &jsonModule{
 		name:  name,
 		value: v,
+		opts:  opts,
 	}
 }
 
@@ -139,11 +189,29 @@ func (m *jsonModule) Name() string {
 }
 
 func (m *jsonModule) Content() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	buf.WriteString("exports.module=")
-	if err := json.NewEncoder(buf).Encode(m.value); err != nil {
+	marshal := m.opts.Marshal
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+	data, err := marshal(m.value)
+	if err != nil {
 		return nil, err
 	}
+	buf := new(bytes.Buffer)
+	export := m.opts.Export
+	if export == "" {
+		export = "module.exports"
+	}
+	buf.WriteString(export)
+	buf.WriteString("=")
+	if m.opts.Indent != "" {
+		if err := json.Indent(buf, data, "", m.opts.Indent); err != nil {
+			return nil, err
+		}
+	} else {
+		buf.Write(data)
+	}
+	buf.WriteString("\n")
 	return buf.Bytes(), nil
 }
 
@@ -155,49 +223,6 @@ func (m *jsonModule) Ext() string {
 	return jsExt
 }
 
-type urlModule struct {
-	name    string
-	url     string
-	content []byte
-	ext     string
-}
-
-// Define a module where the content is pulled from a URL.
-func NewURLModule(name string, url string) Module {
-	return &urlModule{
-		name: name,
-		url:  url,
-		ext:  filepath.Ext(url),
-	}
-}
-
-func (m *urlModule) Name() string {
-	return m.name
-}
-
-func (m *urlModule) Content() ([]byte, error) {
-	if m.content == nil {
-		resp, err := http.Get(m.url)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		m.content, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return m.content, nil
-}
-
-func (m *urlModule) Require() ([]string, error) {
-	return requireFromModule(m)
-}
-
-func (m *urlModule) Ext() string {
-	return m.ext
-}
-
 type fileModule struct {
 	name    string
 	path    string
@@ -254,9 +279,38 @@ func (w *wrapModule) Content() ([]byte, error) {
 	return bytes.Join([][]byte{w.prelude, c, w.postlude}, nil), nil
 }
 
+type parserModule struct {
+	Module
+	parser RequireParser
+}
+
+// Wraps another module and uses the given RequireParser to find the modules it
+// requires. This is useful for modules where the default require() based
+// parsing does not apply.
+func NewRequireParserModule(m Module, p RequireParser) Module {
+	return &parserModule{
+		Module: m,
+		parser: p,
+	}
+}
+
+func (m *parserModule) Require() ([]string, error) {
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	return m.parser.Parse(content)
+}
+
 // Provides modules from a directory.
 type dirProvider struct {
-	path string
+	path     string
+	packages bool
+	assets   map[string]AssetWrapper
+	ignore   []string
+	symlinks SymlinkPolicy
+	maxDepth int
+	exts     []string
 }
 
 // Provide modules from a directory.
@@ -264,37 +318,181 @@ func NewDirProvider(dirname string) Provider {
 	return &dirProvider{path: dirname}
 }
 
+// Provide modules from a directory, additionally resolving names referring to
+// a directory to the main module listed in the package.json in that directory,
+// or to the index.js in that directory.
+func NewPackageDirProvider(dirname string) Provider {
+	return &dirProvider{path: dirname, packages: true}
+}
+
+// Provide modules from a directory, using the given wrappers for names with a
+// matching extension instead of the defaults from DefaultAssets.
+func NewAssetDirProvider(dirname string, assets map[string]AssetWrapper) Provider {
+	return &dirProvider{path: dirname, assets: assets}
+}
+
 func (d *dirProvider) Module(name string) (Module, error) {
-	filename := filepath.Join(d.path, name+ext)
-	if stat, err := os.Stat(filename); os.IsNotExist(err) || stat.IsDir() {
+	// never resolve names outside the directory
+	if path.Clean("/"+name) != "/"+name {
 		return nil, errModuleNotFound(name)
 	}
-	return NewFileModule(name, filename), nil
+	if wrap, ok := assetWrapper(d.assets, name); ok {
+		filename, err := d.file(name)
+		if err != nil {
+			return nil, err
+		}
+		if filename == "" {
+			return nil, errModuleNotFound(name)
+		}
+		return wrap(NewFileModule(name, filename)), nil
+	}
+	for _, e := range d.extensions() {
+		filename, err := d.file(name + e)
+		if err != nil {
+			return nil, err
+		}
+		if filename == "" {
+			continue
+		}
+		m := NewFileModule(name, filename)
+		if e != ext {
+			if wrap, ok := assetWrapper(d.assets, e); ok {
+				m = wrap(m)
+			}
+		}
+		return m, nil
+	}
+	if d.packages {
+		skip, err := d.skip(name)
+		if err != nil {
+			return nil, err
+		}
+		dirname := filepath.Join(d.path, name)
+		if stat, err := os.Stat(dirname); !skip && err == nil && stat.IsDir() {
+			pkg, _ := ioutil.ReadFile(filepath.Join(dirname, packageJSON))
+			target, err := packageTarget(name, pkg)
+			if err != nil {
+				return nil, err
+			}
+			return d.packageModule(name, target)
+		}
+	}
+	return nil, errModuleNotFound(name)
+}
+
+// The file name for the slash separated path relative to the directory, or an
+// empty string if it does not exist or is skipped.
+func (d *dirProvider) file(rel string) (string, error) {
+	skip, err := d.skip(rel)
+	if err != nil || skip {
+		return "", err
+	}
+	filename := filepath.Join(d.path, rel)
+	stat, err := os.Stat(filename)
+	if err == nil && !stat.IsDir() {
+		return filename, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return "", nil
+}
+
+func (d *dirProvider) packageModule(name, target string) (Module, error) {
+	if _, err := d.Module(target); err != nil {
+		if IsNotFound(err) {
+			return nil, errModuleNotFound(name)
+		}
+		return nil, err
+	}
+	return newPackageModule(name, target), nil
 }
 
 type fsProvider struct {
-	fs fs.System
+	read     func(name string) ([]byte, error)
+	fsys     iofs.FS // only available for io/fs.FS backed Providers
+	packages bool
+	assets   map[string]AssetWrapper
 }
 
 // Provides a FileSystem backed Provider.
+//
+// Deprecated: Use NewFSProvider with an io/fs.FS instead.
 func NewFileSystemProvider(s fs.System) Provider {
-	return &fsProvider{fs: s}
+	return &fsProvider{read: systemReader(s)}
+}
+
+// Provides a FileSystem backed Provider, additionally resolving names
+// referring to a directory to the main module listed in the package.json in
+// that directory, or to the index.js in that directory.
+//
+// Deprecated: Use NewPackageFSProvider with an io/fs.FS instead.
+func NewPackageFileSystemProvider(s fs.System) Provider {
+	return &fsProvider{read: systemReader(s), packages: true}
+}
+
+// Returns a function to read the named file from the FileSystem, returning
+// errModuleNotFound if it does not exist.
+func systemReader(s fs.System) func(string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		reader, err := s.Open(name)
+		if err != nil {
+			if s.IsNotExist(err) {
+				return nil, errModuleNotFound(name)
+			}
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	}
 }
 
 func (p *fsProvider) Module(name string) (Module, error) {
-	reader, err := p.fs.Open(name + ext)
-	if err != nil {
-		if p.fs.IsNotExist(err) {
-			return nil, errModuleNotFound(name)
+	if wrap, ok := assetWrapper(p.assets, name); ok {
+		content, err := p.read(name)
+		if err != nil {
+			if IsNotFound(err) {
+				return nil, errModuleNotFound(name)
+			}
+			return nil, err
 		}
+		return wrap(NewScriptModule(name, content)), nil
+	}
+	content, err := p.read(name + ext)
+	if err == nil {
+		return NewOriginModule(NewScriptModule(name, content), name+ext), nil
+	}
+	if !IsNotFound(err) {
 		return nil, err
 	}
-	defer reader.Close()
-	content, err := ioutil.ReadAll(reader)
+	if !p.packages {
+		return nil, errModuleNotFound(name)
+	}
+	pkg, err := p.read(path.Join(name, packageJSON))
+	if err != nil {
+		if !IsNotFound(err) {
+			return nil, err
+		}
+		index := path.Join(name, indexName)
+		if _, err := p.read(index + ext); err != nil {
+			if IsNotFound(err) {
+				return nil, errModuleNotFound(name)
+			}
+			return nil, err
+		}
+		return newPackageModule(name, index), nil
+	}
+	target, err := packageTarget(name, pkg)
 	if err != nil {
 		return nil, err
 	}
-	return NewScriptModule(name, content), nil
+	if _, err := p.Module(target); err != nil {
+		if IsNotFound(err) {
+			return nil, errModuleNotFound(name)
+		}
+		return nil, err
+	}
+	return newPackageModule(name, target), nil
 }
 
 func requireFromModule(m Module) ([]string, error) {
@@ -316,192 +514,1089 @@ func ParseRequire(content []byte) ([]string, error) {
 	return l, nil
 }
 
+// Resolves a module name relative to the module requiring it. Names starting
+// with "./" or "../" are resolved against the directory of the requiring
+// module, others are returned as is.
+func ResolveName(from, name string) string {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		return path.Join(path.Dir(from), name)
+	}
+	return name
+}
+
+// Rewrites relative and aliased require() calls in the content of the named
+// module to use the resolved names.
+func (a *App) rewriteRequire(name string, content []byte) []byte {
+	return reFunCall.ReplaceAllFunc(content, func(call []byte) []byte {
+		sub := reFunCall.FindSubmatch(call)
+		resolved := a.Alias(ResolveName(name, string(sub[1])))
+		if resolved == string(sub[1]) {
+			return call
+		}
+		quote := call[len("require(")]
+		return []byte(fmt.Sprintf("require(%c%s%c)", quote, resolved, quote))
+	})
+}
+
 // An App provides a way to source modules, transform code and serves as a
 // http.Handler.
 type App struct {
-	MountPath    string     // URL the http.Handler is serving on
-	ContentStore ByteStore  // ByteStore used for storing Content to be served
-	Transform    Transform  // optional Transform applied to the code
-	Modules      []Module   // optional Modules directly provided by the App
-	Providers    []Provider // optional fallback Providers
-	prelude      []byte
-	packageURLs  map[string]string
+	MountPath          string                      // URL the http.Handler is serving on
+	BaseURL            string                      // optional base URL like a CDN prefixed to package URLs
+	ContentStore       ByteStore                   // ByteStore used for storing Content to be served
+	Transform          Transform                   // optional Transform applied to the code
+	PostProcess        []Transform                 // optional transforms applied in order to the package content, like whole package minification
+	FingerprintBuild   bool                        // include the BuildFingerprint in package hashes and the URL cache
+	BuildVersion       string                      // optional version of the build configuration included in the BuildFingerprint
+	Modules            []Module                    // optional Modules directly provided by the App
+	Providers          []Provider                  // optional fallback Providers
+	Vendor             []string                    // optional modules served in a separate package
+	RequireParser      RequireParser               // optional parser used instead of Module.Require
+	OutputFormat       OutputFormat                // optional format of the modules, defaults to StringFormat
+	MaxBuilds          int                         // optional limit on concurrent package builds
+	MaxPackageSize     int                         // optional gzipped size budget for packages, checked by Precompile
+	MaxBuildBytes      int64                       // optional limit on bytes buffered by all builds
+	DefaultLocale      string                      // optional locale used for LocalizedModules outside ModulesURLForLocale
+	Conflicts          ConflictMode                // optional handling of names provided by multiple sources
+	Unused             UnusedMode                  // optional handling of App.Modules and Vendor modules unreachable from the Precompile entry points
+	Overrides          map[string]bool             // optional names intentionally provided by multiple sources
+	Aliases            map[string]string           // optional aliases, "p/*" keys alias a prefix
+	OnDemand           bool                        // build packages requested via OnDemandName
+	Banner             string                      // optional banner like a version written at the top of packages
+	PreserveLicenses   bool                        // write /*! license comments at the top of packages
+	SaveContent        bool                        // include package content in SaveState
+	VerifyManifest     bool                        // ignore packages missing from the store in LoadManifest
+	VerifyContent      bool                        // re-hash content stored by the App or listed in a loaded manifest when read from the store, rebuilding corrupted packages instead of serving them
+	Logger             Logger                      // optional Logger, defaults to the standard logger for LogInfo and above
+	Metrics            Metrics                     // optional Metrics receiving build and serving events
+	DebugAuth          func(*http.Request) bool    // optional check enabling the endpoints under DebugPath
+	Route              func(string) (string, bool) // optional URL path to key mapping instead of DefaultRoute
+	AssetPatterns      []*regexp.Regexp            // optional patterns whose first submatch names an asset to replace with its URL
+	PreludeExtensions  map[string]Module           // optional prelude extensions, included as needed by BundlePrelude
+	URLNamer           URLNamer                    // optional naming of packages, defaults to the hash
+	Hash               func() hash.Hash            // optional hash used for package URLs, defaults to sha256
+	HashLength         int                         // optional number of hex characters in package URLs, defaults to 7
+	ContentTypes       map[string]string           // optional Content-Type by extension like ".js", overriding the defaults
+	Headers            http.Header                 // optional headers added to served packages, modules and assets
+	BuildParallelism   int                         // optional number of modules fetched and transformed at once, the Transform must be concurrency safe
+	ErrorHandler       ErrorHandlerFunc            // optional handler for error responses, receiving a *HTTPError
+	BuildCache         *BuildCache                 // optional cache of parsed and transformed modules reused across builds
+	HotReload          bool                        // serve HMRPath and add the HMR runtime to BundlePrelude, for development along with Watch
+	LiveReload         bool                        // serve EventsPath and add a script reloading the page on changes to BundlePrelude, for development along with Watch
+	NameValidator      func(string) error          // optional validation of module names while building, names from requests default to ValidateName
+	PagePreludeVersion int                         // optional prelude version loaded by pages, like from an older PreludeURL, builds fail if the OutputFormat needs a newer one
+	mu                 sync.Mutex
+	limiter            *buildLimiter
+	closers            []func(context.Context) error
+	prelude            []byte
+	preludeURL         string
+	extensions         map[string][]byte
+	packageURLs        map[string]*packageEntry
+	flights            map[string]*flight
+	standaloneURLs     map[string]string
+	styleURLs          map[string]string
+	assetURLs          map[string]string
+	scriptURLs         map[string]string
+	inlineOnly         map[string][]string
+	inlineEntries      map[string]*inlineEntry
+	extensionNames     map[string][]string
+	sharedStore        bool                      // the ContentStore was given by a Mux
+	keyFingerprints    map[string]string         // the BuildFingerprint of the content stored for each key
+	verifiedKeys       map[string]bool           // keys whose stored content was verified
+	conflicts          map[string]*ConflictError // nil for names checked without conflicts
+	bundles            map[string]*BundleInfo
+	vendor             map[string]bool
+	vendorKey          string
+	watcher            *Watcher
 }
 
 // Returns a URL for a given set of modules. This caches URLs for a requested
-// set of modules.
+// set of modules. Vendor modules and their dependencies are excluded from the
+// package, they are served by the URL returned by VendorURL.
 func (a *App) ModulesURL(modules []string) (string, error) {
-	key := strings.Join(modules, "")
-	url := a.packageURLs[key]
-	if url != "" {
+	return a.ModulesURLContext(context.Background(), modules)
+}
+
+// Returns a URL for the package containing the Vendor modules and their
+// dependencies. Since the package only changes when one of the Vendor modules
+// changes, it can be cached by clients across deploys. An empty URL is
+// returned if the App has no Vendor modules.
+func (a *App) VendorURL() (string, error) {
+	if len(a.Vendor) == 0 {
+		return "", nil
+	}
+	return a.packageURL(context.Background(), packageSpec{modules: a.Vendor, vendor: true}, nil)
+}
+
+// The set of Vendor modules including their dependencies. This is only
+// recomputed when the Vendor modules change, in which case the cached URLs are
+// also discarded.
+func (a *App) vendorSet() (map[string]bool, error) {
+	key := strings.Join(a.Vendor, "\x00")
+	a.mu.Lock()
+	if a.vendor != nil && a.vendorKey == key {
+		a.mu.Unlock()
+		return a.vendor, nil
+	}
+	a.mu.Unlock()
+	set := make(map[string]bool)
+	if err := a.buildDeps(a.Vendor, set); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	if a.vendor != nil {
+		a.packageURLs = nil
+		a.inlineOnly = nil
+		a.inlineEntries = nil
+		a.extensionNames = nil
+	}
+	a.vendor = set
+	a.vendorKey = key
+	a.mu.Unlock()
+	return set, nil
+}
+
+// Describes a package by the modules it was requested for and how it is built.
+type packageSpec struct {
+	modules   []string
+	vendor    bool
+	locale    string
+	inline    []string // modules provided inline, excluded from the package
+	bootstrap []byte   // code appended to the package
+	prelude   bool     // prelude prepended to the package
+}
+
+// A cached package URL along with the spec it was built for.
+type packageEntry struct {
+	packageSpec
+	url         string
+	integrity   string
+	size        int
+	moduleSizes map[string]int // size of each module in the package, if known
+}
+
+// The key used to cache the package URL for the spec. The order of the
+// modules, and any duplicates, do not change the package.
+func (s packageSpec) key() string {
+	key := joinNames(s.modules)
+	if s.prelude {
+		key = "\x00prelude" + key
+	}
+	if len(s.bootstrap) > 0 {
+		key = "\x00bootstrap:" + fmt.Sprintf("%x", sha256.Sum256(s.bootstrap)) + "\x00" + key
+	}
+	if len(s.inline) > 0 {
+		key = "\x00inline:" + joinNames(s.inline) + "\x00" + key
+	}
+	if s.locale != "" {
+		key = "\x00locale:" + s.locale + "\x00" + key
+	}
+	if s.vendor {
+		return "\x00vendor" + key
+	}
+	return key
+}
+
+// Joins the sorted unique names with a separator which cannot appear in a
+// module name.
+func joinNames(names []string) string {
+	sorted := make([]string, len(names))
+	copy(sorted, names)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for _, name := range sorted {
+		if len(unique) == 0 || name != unique[len(unique)-1] {
+			unique = append(unique, name)
+		}
+	}
+	return strings.Join(unique, "\x00")
+}
+
+func (a *App) packageURL(ctx context.Context, spec packageSpec, exclude map[string]bool) (string, error) {
+	key := fingerprintKey(a.BuildFingerprint(), spec)
+	modules := spec.modules
+	for {
+		a.mu.Lock()
+		entry := a.packageURLs[key]
+		var f *flight
+		leader := false
+		if entry == nil {
+			if f = a.flights[key]; f == nil {
+				f = &flight{done: make(chan struct{})}
+				if a.flights == nil {
+					a.flights = make(map[string]*flight)
+				}
+				a.flights[key] = f
+				leader = true
+			}
+		}
+		a.mu.Unlock()
+		if entry != nil {
+			if a.Metrics != nil {
+				a.Metrics.CacheHit(modules)
+			}
+			return entry.url, nil
+		}
+		if leader {
+			return a.lead(ctx, f, key, spec, exclude)
+		}
+
+		// wait for the identical build already running, retrying if it was
+		// cancelled by its own context
+		a.log(LogDebug, "waiting for running build of package for %v", modules)
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if f.err != nil && isContextError(f.err) && ctx.Err() == nil {
+			continue
+		}
+		return f.url, f.err
+	}
+}
+
+// A running build of a package, which identical builds wait for.
+type flight struct {
+	done chan struct{} // closed once the build finishes
+	url  string
+	err  error
+}
+
+// Runs the build for the flight, always releasing the waiters. A panic is
+// passed on to the waiters as an error before being propagated.
+func (a *App) lead(ctx context.Context, f *flight, key string, spec packageSpec, exclude map[string]bool) (string, error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = fmt.Errorf("panic building package for %v: %v", spec.modules, r)
+			a.finishFlight(f, key)
+			panic(r)
+		}
+		a.finishFlight(f, key)
+	}()
+	f.url, f.err = a.buildPackageURL(ctx, key, spec, exclude)
+	return f.url, f.err
+}
+
+// Removes the finished flight and releases its waiters.
+func (a *App) finishFlight(f *flight, key string) {
+	a.mu.Lock()
+	delete(a.flights, key)
+	a.mu.Unlock()
+	close(f.done)
+}
+
+// Check if the error is from a cancelled context or an exceeded deadline.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Builds and stores the package, caching its URL.
+func (a *App) buildPackageURL(ctx context.Context, key string, spec packageSpec, exclude map[string]bool) (string, error) {
+	modules := spec.modules
+	if a.Metrics != nil {
+		a.Metrics.CacheMiss(modules)
+	}
+	a.log(LogDebug, "building package for %v", modules)
+
+	if err := a.checkPreludeVersion(spec.prelude); err != nil {
+		return "", err
+	}
+
+	b, err := a.buildLimiter().start(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer b.done()
+	b.locale = spec.locale
+	b.bootstrap = spec.bootstrap
+	if spec.prelude {
+		prelude, err := a.BundlePrelude(modules)
+		if err != nil {
+			return "", err
+		}
+		b.prelude = append(append([]byte(nil), prelude...), a.LoaderConfig()...)
+	}
+	start := time.Now()
+	var p *builtPackage
+	if s, ok := a.ContentStore.(StreamStore); ok && !a.PreserveLicenses && len(a.PostProcess) == 0 {
+		p, err = a.streamPackage(s, modules, exclude, b)
+	} else {
+		p, err = a.bufferPackage(modules, exclude, b)
+	}
+	if err != nil {
+		return "", err
+	}
+	duration := time.Since(start)
+	if a.Metrics != nil {
+		a.Metrics.PackageBuilt(modules, duration, p.size)
+	}
+	url := p.url
+
+	a.mu.Lock()
+	if a.packageURLs == nil {
+		a.packageURLs = make(map[string]*packageEntry)
+	}
+	a.packageURLs[key] = &packageEntry{
+		packageSpec: spec,
+		url:         url,
+		integrity:   p.integrity,
+		size:        p.size,
+		moduleSizes: moduleSizes(p.info),
+	}
+	if a.bundles == nil {
+		a.bundles = make(map[string]*BundleInfo)
+	}
+	a.bundles[url] = &BundleInfo{URL: url, Modules: p.info}
+	a.mu.Unlock()
+
+	a.log(LogDebug, "built package %s for %v in %s", url, modules, duration)
+	return url, nil
+}
+
+// Stores the script content and returns the URL it is served at. This allows
+// inline scripts to be served externally, for pages with a Content-Security-Policy
+// disallowing inline scripts. The URLs are cached by content, so the script is
+// only stored once. The content is public and kept until collected by
+// GCStore, so it should not contain per-user data.
+func (a *App) ScriptURL(content []byte) (string, error) {
+	hash := a.hash(content)
+	a.mu.Lock()
+	url, ok := a.scriptURLs[hash]
+	a.mu.Unlock()
+	if ok {
 		return url, nil
 	}
 
-	content, err := a.content(modules)
+	url, err := a.storePackage(nil, content, ext)
 	if err != nil {
 		return "", err
 	}
 
-	sha := sha256.New()
-	sha.Write(content)
-	hash := fmt.Sprintf("%x", sha.Sum(nil))[:hashLen]
-	err = a.ContentStore.Store(hash, content)
+	a.mu.Lock()
+	if a.scriptURLs == nil {
+		a.scriptURLs = make(map[string]string)
+	}
+	a.scriptURLs[hash] = url
+	a.mu.Unlock()
+	return url, nil
+}
+
+// Stores the package content and returns the URL it is served at, ending with
+// the suffix.
+func (a *App) storePackage(modules []string, content []byte, suffix string) (string, error) {
+	fingerprint := a.BuildFingerprint()
+	hash := a.hashWith(fingerprint, content)
+	name, err := a.packageName(hash, modules)
 	if err != nil {
 		return "", err
 	}
+	if ps, ok := a.ContentStore.(PublishStore); ok {
+		err = ps.Publish(hash, name+suffix, content)
+	} else {
+		err = a.ContentStore.Store(hash, content)
+	}
+	if err != nil {
+		return "", err
+	}
+	a.storedKey(hash, fingerprint)
+	return a.packagePath(name + suffix), nil
+}
 
-	url = path.Join("/", a.MountPath, hash+ext)
+// The file name, without the extension, for the package with the hash.
+func (a *App) packageName(hash string, modules []string) (string, error) {
+	if a.URLNamer == nil {
+		return hash, nil
+	}
+	name := a.URLNamer.Name(hash, modules)
+	if strings.Contains(name, "/") || !strings.HasSuffix(name, hash) {
+		return "", fmt.Errorf("package name %q does not end with hash %s", name, hash)
+	}
+	return name, nil
+}
 
-	if a.packageURLs == nil {
-		a.packageURLs = make(map[string]string)
+// The URL for the file name under the MountPath.
+func (a *App) packagePath(filename string) string {
+	return strings.TrimSuffix(a.BaseURL, "/") + path.Join("/", a.MountPath, filename)
+}
+
+// Information about a package built by an App.
+type BundleInfo struct {
+	URL     string       // URL the package is served at
+	Modules []ModuleInfo // modules included in the package
+}
+
+// Information about a module included in a package.
+type ModuleInfo struct {
+	Name     string   // name of the module
+	Provider Provider // Provider that supplied the module, nil for App.Modules
+	Origin   string   // where the module came from, like a file path or URL
+	Size     int      // size of the module content in bytes
+	RawSize  int      // size of the module content before Transform
+	Gzipped  int      // gzipped size of the module content
+}
+
+// Returns information about the package served at the given URL, as returned
+// by ModulesURL or VendorURL. Returns nil if the package was not built by this
+// App.
+func (a *App) BundleInfo(url string) *BundleInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.bundles[url]
+}
+
+// Returns the name the given module name is aliased to, or the name itself if
+// it has no alias. Aliases with keys ending in "/*" apply to all names with
+// the preceding prefix, in which case the longest matching prefix is used.
+func (a *App) Alias(name string) string {
+	if target, ok := a.Aliases[name]; ok {
+		return target
 	}
-	a.packageURLs[key] = url
+	var prefix, target string
+	for from, to := range a.Aliases {
+		if !strings.HasSuffix(from, "/*") || !strings.HasSuffix(to, "/*") {
+			continue
+		}
+		from = from[:len(from)-1]
+		if strings.HasPrefix(name, from) && len(from) > len(prefix) {
+			prefix, target = from, to[:len(to)-1]
+		}
+	}
+	if prefix == "" {
+		return name
+	}
+	return target + name[len(prefix):]
+}
 
-	return url, nil
+// Returns statistics about the package builds performed by the App.
+func (a *App) BuildStats() BuildStats {
+	return a.buildLimiter().Stats()
+}
+
+func (a *App) buildLimiter() *buildLimiter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limiter == nil {
+		a.limiter = newBuildLimiter(a.MaxBuilds, a.MaxBuildBytes)
+	}
+	return a.limiter
 }
 
 // Retrive a Module by name.
-func (a *App) Module(name string) (m Module, err error) {
-	for _, m = range a.Modules {
+func (a *App) Module(name string) (Module, error) {
+	m, _, err := a.find(name)
+	return m, err
+}
+
+// Find a Module by name along with the Provider that supplied it. The Provider
+// is nil for Modules directly provided by the App.
+func (a *App) find(name string) (Module, Provider, error) {
+	return a.findContext(context.Background(), name)
+}
+
+// Find a Module by name like find, using the context with providers that
+// support it.
+func (a *App) findContext(ctx context.Context, name string) (Module, Provider, error) {
+	if err := a.validateName(name); err != nil {
+		return nil, nil, err
+	}
+	for _, m := range a.Modules {
 		if m.Name() == name {
-			return m, nil
+			if err := a.checkConflict(name, -1); err != nil {
+				return nil, nil, err
+			}
+			return m, nil, nil
 		}
 	}
 
-	for _, p := range a.Providers {
-		m, err = p.Module(name)
+	for ix, p := range a.Providers {
+		m, err := moduleContext(ctx, p, name)
 		if err == nil {
-			return m, err
+			if err := a.checkConflict(name, ix); err != nil {
+				return nil, nil, err
+			}
+			return m, p, nil
 		}
 		if IsNotFound(err) {
 			continue
 		}
-		return nil, err
+		return nil, nil, err
 	}
-	return nil, errModuleNotFound(name)
+	return nil, nil, errModuleNotFound(name)
+}
+
+// The default strategy for mapping a URL path to the ContentStore key of a
+// package. The key is the hash in the last path segment.
+func DefaultRoute(urlPath string) (key string, ok bool) {
+	return routeHash(urlPath, hashLen, ext)
+}
+
+// Maps a URL path to a hash of the given length in the last path segment,
+// followed by the suffix.
+func routeHash(urlPath string, length int, suffix string) (string, bool) {
+	name := path.Base(urlPath)
+	nameLen := len(name)
+	if nameLen != length+len(suffix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return name[:nameLen-len(suffix)], true
+}
+
+// Maps a URL path to a ContentStore key using Route, or the configured
+// URLNamer and hash length.
+func (a *App) route(urlPath string) (string, bool) {
+	if a.Route != nil {
+		return a.Route(urlPath)
+	}
+	suffix := path.Ext(urlPath)
+	if suffix == "" {
+		return "", false
+	}
+	if a.URLNamer != nil {
+		return routeNamed(urlPath, a.hashLength(), suffix)
+	}
+	return routeHash(urlPath, a.hashLength(), suffix)
+}
+
+// Maps a URL path to the hash of the given length at the end of the last
+// path segment followed by the suffix, as generated by a URLNamer.
+func routeNamed(urlPath string, length int, suffix string) (string, bool) {
+	name := path.Base(urlPath)
+	nameLen := len(name)
+	if nameLen < length+len(suffix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return name[nameLen-len(suffix)-length : nameLen-len(suffix)], true
+}
+
+// The Content-Type for the package or asset served at the URL path.
+func (a *App) contentType(urlPath string) string {
+	e := path.Ext(urlPath)
+	if t, ok := a.ContentTypes[e]; ok {
+		return t
+	}
+	switch e {
+	case ext:
+		return "application/javascript; charset=utf-8"
+	case styleExt:
+		return "text/css; charset=utf-8"
+	}
+	return assetContentType(urlPath)
+}
+
+// Sets the headers for content served at the URL path.
+func (a *App) setHeaders(w http.ResponseWriter, urlPath string) {
+	h := w.Header()
+	h.Set("Content-Type", a.contentType(urlPath))
+	h.Set("X-Content-Type-Options", "nosniff")
+	for k, v := range a.Headers {
+		h[k] = append(h[k], v...)
+	}
+}
+
+// The number of hex characters of the hash used in package URLs. This is
+// limited to the full length of the hex encoded hash.
+func (a *App) hashLength() int {
+	length := a.HashLength
+	if length <= 0 {
+		length = hashLen
+	}
+	if size := a.newHash().Size() * 2; length > size {
+		length = size
+	}
+	return length
+}
+
+func (a *App) newHash() hash.Hash {
+	if a.Hash != nil {
+		return a.Hash()
+	}
+	return sha256.New()
+}
+
+// A hash for content, which starts with the fingerprint if there is one.
+func (a *App) contentHash(fingerprint string) hash.Hash {
+	h := a.newHash()
+	if fingerprint != "" {
+		h.Write([]byte(fingerprint + "\x00"))
+	}
+	return h
+}
+
+// The hash used as the ContentStore key for the given content.
+func (a *App) hash(content []byte) string {
+	return a.hashWith(a.BuildFingerprint(), content)
+}
+
+// The hash used as the ContentStore key for content built with the
+// fingerprint.
+func (a *App) hashWith(fingerprint string, content []byte) string {
+	h := a.contentHash(fingerprint)
+	h.Write(content)
+	return hashKey(h, a.hashLength())
 }
 
 // Serves HTTP requests for resources.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	name := path.Base(r.URL.Path)
-	nameLen := len(name)
-	if nameLen != hashLen+extLen {
-		w.WriteHeader(404)
-		w.Write([]byte("invalid url\n"))
+	if a.Metrics != nil {
+		a.serveWithMetrics(w, r)
+		return
+	}
+	a.serve(w, r)
+}
+
+func (a *App) serve(w http.ResponseWriter, r *http.Request) {
+	// an empty method means GET
+	if r.Method != "" && r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		a.serveError(w, r, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	if a.serveDebug(w, r) {
+		return
+	}
+	if a.HotReload && r.URL.Path == a.HMRURL() {
+		a.serveEvents(w, r, "update", a.hmrUpdate)
+		return
+	}
+	if a.LiveReload && r.URL.Path == a.EventsURL() {
+		a.serveEvents(w, r, "change", liveReloadChange)
 		return
 	}
-	content, err := a.ContentStore.Get(name[:nameLen-extLen])
+	if a.isOnDemand(r.URL.Path) {
+		a.serveOnDemand(w, r)
+		return
+	}
+	if name, ok := a.moduleName(r.URL.Path); ok {
+		a.serveModule(w, r, name)
+		return
+	}
+	key, ok := a.route(r.URL.Path)
+	if !ok {
+		a.serveError(w, r, 404, "invalid url", nil)
+		return
+	}
+	if fs, ok := a.ContentStore.(FileStore); ok {
+		a.serveFile(w, r, fs, key)
+		return
+	}
+	content, err := a.storedContent(key)
+	if err == nil && content == nil && a.VerifyContent {
+		var rebuilt bool
+		if rebuilt, err = a.rebuildKey(r.Context(), key); rebuilt && err == nil {
+			content, err = a.storedContent(key)
+		}
+	}
 	if err != nil {
-		w.WriteHeader(500)
-		w.Write([]byte("error retriving package from store\n"))
-		log.Printf("error retriving package from store: %s", err)
+		a.serveError(w, r, 500, "error retriving package from store", err)
+		return
 	}
 	if content == nil {
-		w.WriteHeader(404)
-		w.Write([]byte("not found\n"))
+		a.serveError(w, r, 404, "not found", nil)
 		return
 	}
-	w.Header().Add("Content-Type", "text/javascript")
-	w.WriteHeader(200)
-	w.Write(content)
+	a.setHeaders(w, r.URL.Path)
+	http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(content))
+}
+
+// Serves a file backed package, which allows for range requests and efficient
+// copies.
+func (a *App) serveFile(w http.ResponseWriter, r *http.Request, fs FileStore, key string) {
+	f, err := a.openStored(fs, key)
+	if err == nil && f == nil && a.VerifyContent {
+		var rebuilt bool
+		if rebuilt, err = a.rebuildKey(r.Context(), key); rebuilt && err == nil {
+			f, err = a.openStored(fs, key)
+		}
+	}
+	if err != nil {
+		a.serveError(w, r, 500, "error retriving package from store", err)
+		return
+	}
+	if f == nil {
+		a.serveError(w, r, 404, "not found", nil)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		a.serveError(w, r, 500, "error retriving package from store", err)
+		return
+	}
+	a.setHeaders(w, r.URL.Path)
+	http.ServeContent(w, r, key+ext, stat.ModTime(), f)
+}
+
+func (a *App) content(modules []string, exclude map[string]bool, b *build) ([]byte, []ModuleInfo, error) {
+	names, err := a.packageModules(modules, exclude, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := new(bytes.Buffer)
+	info, err := a.writeDefines(out, names, b, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	out.Write(b.bootstrap)
+	content, err := a.postProcess(modules, append(a.header(b), out.Bytes()...), b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, info, nil
 }
 
-func (a *App) content(modules []string) ([]byte, error) {
+// The sorted names of the modules and their dependencies, less those
+// excluded, for predictable output.
+func (a *App) packageModules(modules []string, exclude map[string]bool, b *build) ([]string, error) {
 	set := make(map[string]bool)
-	if err := a.buildDeps(modules, set); err != nil {
+	for name := range exclude {
+		set[name] = true
+	}
+	inlineOnly := make(map[string]bool)
+	if err := a.buildDepsFrom(b.context(), nil, modules, set, inlineOnly); err != nil {
 		return nil, err
 	}
-
-	// write a sorted list of modules for predictable output
 	var names []string
 	for name, _ := range set {
-		names = append(names, name)
+		if !exclude[name] && !inlineOnly[name] {
+			names = append(names, name)
+		}
 	}
 	sort.Strings(names)
-	out := new(bytes.Buffer)
+	return names, nil
+}
 
-	var tmp []byte
-	for _, name := range names {
-		m, err := a.Module(name)
+// Writes the define() calls for the named modules. If release is true, the
+// bytes buffered by the build are released after each module is written.
+func (a *App) writeDefines(w io.Writer, names []string, b *build, release bool) ([]ModuleInfo, error) {
+	if a.BuildParallelism > 1 {
+		return a.writeDefinesParallel(w, names, b, release)
+	}
+	info := make([]ModuleInfo, len(names))
+	for ix, name := range names {
+		define, moduleInfo, err := a.define(name, b)
 		if err != nil {
 			return nil, err
 		}
+		info[ix] = moduleInfo
+		if _, err := w.Write(define); err != nil {
+			return nil, err
+		}
+		if release {
+			b.release()
+		}
+	}
+	return info, nil
+}
+
+// Provides a define() call with the content as a string payload.
+func StringDefine(name string, content []byte) []byte {
+	out := new(bytes.Buffer)
+	tmp, _ := json.Marshal(name)
+	out.WriteString("define(")
+	out.Write(tmp)
+	out.WriteString(",")
+	tmp, _ = json.Marshal(string(bytes.TrimSpace(content)))
+	out.Write(tmp)
+	out.WriteString(");\n")
+	return out.Bytes()
+}
+
+// Provides a define() call with the content inline in a factory function.
+// This avoids escaping and preserves the source for debugging.
+func FunctionDefine(name string, content []byte) []byte {
+	out := new(bytes.Buffer)
+	tmp, _ := json.Marshal(name)
+	out.WriteString("define(")
+	out.Write(tmp)
+	out.WriteString(",function(require,exports,module){\n")
+	out.Write(bytes.TrimSpace(content))
+	out.WriteString("\n});\n")
+	return out.Bytes()
+}
+
+// Provides the define() call for a single module, with Transform applied.
+func (a *App) define(name string, b *build) ([]byte, ModuleInfo, error) {
+	ctx := b.context()
+	m, p, err := a.findContext(ctx, name)
+	if err != nil {
+		return nil, ModuleInfo{}, buildError(OpFind, name, "", nil, err)
+	}
+	origin := moduleOrigin(m, p)
+	fail := func(op BuildOp, err error) ([]byte, ModuleInfo, error) {
+		return nil, ModuleInfo{}, buildError(op, name, origin, nil, err)
+	}
+	if l, ok := m.(LocalizedModule); ok {
+		if m, err = a.localize(l, b.locale); err != nil {
+			return fail(OpRead, err)
+		}
+	}
+	var original []byte
+	if a.PreserveLicenses || a.Transform != nil {
+		if original, err = contentContext(ctx, m); err != nil {
+			return fail(OpRead, err)
+		}
+	}
+	if a.PreserveLicenses {
+		b.addLicenses(original)
+	}
+	var content []byte
+	cached := false
+	if a.BuildCache != nil && a.Transform != nil {
+		content, cached = a.BuildCache.transformed(name, original)
+	}
+	if !cached {
 		if a.Transform != nil {
-			if m, err = a.Transform.Transform(m); err != nil {
-				return nil, err
+			if m, err = transformContext(ctx, a.Transform, m); err != nil {
+				return fail(OpTransform, err)
 			}
 		}
-		content, err := m.Content()
-		if err != nil {
-			return nil, err
+		if content, err = contentContext(ctx, m); err != nil {
+			return fail(OpRead, err)
 		}
-
-		out.WriteString("define(")
-		if tmp, err = json.Marshal(m.Name()); err != nil {
-			return nil, err
+		if a.BuildCache != nil && a.Transform != nil {
+			a.BuildCache.setTransformed(name, original, content)
 		}
-		out.Write(tmp)
-		out.WriteString(",")
-		if tmp, err = json.Marshal(string(bytes.TrimSpace(content))); err != nil {
-			return nil, err
+	}
+	if err = b.grow(len(content)); err != nil {
+		return nil, ModuleInfo{}, err
+	}
+	content = a.rewriteRequire(name, content)
+	if content, err = a.rewriteScriptAssets(name, content); err != nil {
+		return fail(OpRewrite, err)
+	}
+	var deps []string
+	if a.OutputFormat == AMDFormat {
+		if deps, err = a.deps(name); err != nil {
+			return fail(OpParse, err)
 		}
-		out.Write(tmp)
-		out.WriteString(");\n")
 	}
-	return out.Bytes(), nil
+	define, err := a.OutputFormat.define(m.Name(), deps, content)
+	if err != nil {
+		return fail(OpFormat, err)
+	}
+	info := ModuleInfo{
+		Name:     name,
+		Provider: p,
+		Origin:   origin,
+		Size:     len(content),
+		RawSize:  len(content),
+		Gzipped:  gzipSize(content),
+	}
+	if original != nil {
+		info.RawSize = len(original)
+	}
+	return define, info, nil
+}
+
+// Provides the dependencies of the named module, resolved and aliased.
+func (a *App) deps(name string) ([]string, error) {
+	m, err := a.Module(name)
+	if err != nil {
+		return nil, err
+	}
+	d, err := a.resolvedRequire(context.Background(), name, m)
+	if err != nil {
+		return nil, err
+	}
+	for ix := range d {
+		d[ix] = a.Alias(d[ix])
+	}
+	return d, nil
+}
+
+// Provides the dependencies of the module, resolved relative to its name.
+func (a *App) resolvedRequire(ctx context.Context, name string, m Module) ([]string, error) {
+	d, err := a.require(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	for ix := range d {
+		d[ix] = ResolveName(name, d[ix])
+	}
+	return d, nil
 }
 
 func (a *App) buildDeps(require []string, set map[string]bool) error {
+	return a.buildDepsFrom(context.Background(), nil, require, set, nil)
+}
+
+// Adds the required modules and their dependencies to the set. The chain is
+// the list of modules leading to the required modules. A required module that
+// is not found results in a MissingRequireError naming its parent. The
+// InlineOnly modules found are also added to inlineOnly, if it is not nil.
+func (a *App) buildDepsFrom(ctx context.Context, chain []string, require []string, set, inlineOnly map[string]bool) error {
+	found := a.prefetch(ctx, require, set)
 	for _, name := range require {
+		name = a.Alias(name)
 		if set[name] {
 			continue
 		}
 		set[name] = true
-		m, err := a.Module(name)
+		current := append(chain[:len(chain):len(chain)], name)
+		f := found[name]
+		var m Module
+		var p Provider
+		var err error
+		if f != nil {
+			m, p, err = f.m, f.p, f.err
+		} else {
+			m, p, err = a.findContext(ctx, name)
+		}
 		if err != nil {
-			return err
+			if len(chain) > 0 && IsNotFound(err) {
+				err = &MissingRequireError{Module: chain[len(chain)-1], Require: name}
+			}
+			return buildError(OpFind, name, "", current, err)
+		}
+		if inlineOnly != nil && isInlineOnly(m) {
+			inlineOnly[name] = true
 		}
-		d, err := m.Require()
+		if _, ok := m.(ContextModule); ok && !isInlineOnly(m) {
+			// fetch the content with the context, modules like those from
+			// NewURLModule cache it for use by Require
+			if f != nil && f.fetched {
+				err = f.fetchErr
+			} else {
+				_, err = contentContext(ctx, m)
+			}
+			if err != nil {
+				return buildError(OpRead, name, moduleOrigin(m, p), current, err)
+			}
+		}
+		d, err := a.resolvedRequire(ctx, name, m)
 		if err != nil {
+			return buildError(OpParse, name, moduleOrigin(m, p), current, err)
+		}
+		if err := a.buildDepsFrom(ctx, current, d, set, inlineOnly); err != nil {
 			return err
 		}
-		a.buildDeps(d, set)
 	}
 	return nil
 }
 
+func (a *App) require(ctx context.Context, m Module) ([]string, error) {
+	if isInlineOnly(m) {
+		// the content may be specific to a build
+		return m.Require()
+	}
+	if a.BuildCache != nil {
+		return a.cachedRequire(ctx, m)
+	}
+	if _, ok := m.(*parserModule); ok || a.RequireParser == nil {
+		return requireContext(ctx, m)
+	}
+	content, err := contentContext(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return a.RequireParser.Parse(content)
+}
+
+// Provides the required modules using the BuildCache if the content has not
+// changed.
+func (a *App) cachedRequire(ctx context.Context, m Module) ([]string, error) {
+	content, err := contentContext(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if require, ok := a.BuildCache.require(m.Name(), content); ok {
+		return require, nil
+	}
+	var require []string
+	if _, ok := m.(*parserModule); ok || a.RequireParser == nil {
+		require, err = requireContext(ctx, m)
+	} else {
+		require, err = a.RequireParser.Parse(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.BuildCache.setRequire(m.Name(), content, require)
+	return require, nil
+}
+
 // Provides the Prelude, with Transform applied. The result is cached so you
 // don't have to.
 func (a *App) ScriptPrelude() ([]byte, error) {
-	if a.prelude == nil {
-		var err error
-		p := ScriptPrelude()
-		if a.Transform != nil {
-			if p, err = a.Transform.Transform(p); err != nil {
-				return nil, err
-			}
-		}
-		if a.prelude, err = p.Content(); err != nil {
+	a.mu.Lock()
+	prelude := a.prelude
+	a.mu.Unlock()
+	if prelude != nil {
+		return prelude, nil
+	}
+	var err error
+	p := ScriptPrelude()
+	if a.Transform != nil {
+		if p, err = a.Transform.Transform(p); err != nil {
 			return nil, err
 		}
 	}
-	return a.prelude, nil
+	if prelude, err = p.Content(); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	a.prelude = prelude
+	a.mu.Unlock()
+	return prelude, nil
 }
 
 type memoryStore struct {
-	data map[string][]byte
+	mu       sync.Mutex
+	data     map[string][]byte
+	accessed map[string]time.Time
 }
 
 // Provides a simple in-memory byte store.
 func NewMemoryStore() ByteStore {
-	return &memoryStore{data: make(map[string][]byte)}
+	return &memoryStore{
+		data:     make(map[string][]byte),
+		accessed: make(map[string]time.Time),
+	}
 }
 
 func (s *memoryStore) Store(key string, value []byte) error {
+	s.mu.Lock()
 	s.data[key] = value
+	s.accessed[key] = time.Now()
+	s.mu.Unlock()
 	return nil
 }
 
 func (s *memoryStore) Get(key string) ([]byte, error) {
-	return s.data[key], nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.data[key]
+	if ok {
+		s.accessed[key] = time.Now()
+	}
+	return value, nil
+}
+
+func (s *memoryStore) GC(before time.Time, keep map[string]bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for key, accessed := range s.accessed {
+		if !keep[key] && accessed.Before(before) {
+			delete(s.data, key)
+			delete(s.accessed, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) DeletePrefix(prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for key := range s.data {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.data, key)
+			delete(s.accessed, key)
+			deleted++
+		}
+	}
+	return deleted, nil
 }
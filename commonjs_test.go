@@ -2,7 +2,11 @@ package commonjs_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/daaku/go.commonjs"
 	"github.com/daaku/go.pkgrsrc/pkgrsrc"
 	"math"
@@ -11,6 +15,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 type providerWithError int
@@ -150,6 +155,36 @@ func TestJSONModuleError(t *testing.T) {
 	}
 }
 
+func TestJSONModuleWithOptions(t *testing.T) {
+	t.Parallel()
+	value := map[string]interface{}{"at": time.Date(2012, 1, 2, 0, 0, 0, 0, time.UTC)}
+	cases := []struct {
+		opts     commonjs.JSONOptions
+		expected string
+	}{
+		{commonjs.JSONOptions{}, "module.exports={\"at\":\"2012-01-02T00:00:00Z\"}\n"},
+		{commonjs.JSONOptions{Export: "exports.config", Indent: "  "}, "exports.config={\n  \"at\": \"2012-01-02T00:00:00Z\"\n}\n"},
+		{
+			commonjs.JSONOptions{
+				Marshal: func(v interface{}) ([]byte, error) {
+					at := v.(map[string]interface{})["at"].(time.Time)
+					return json.Marshal(at.Unix())
+				},
+			},
+			"module.exports=1325462400\n",
+		},
+	}
+	for _, c := range cases {
+		content, err := commonjs.NewJSONModuleWithOptions("foo", value, c.opts).Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != c.expected {
+			t.Fatalf("was expecting %q, got %q", c.expected, content)
+		}
+	}
+}
+
 func TestURLBackedModule(t *testing.T) {
 	t.Parallel()
 	js := []byte("require('foo')")
@@ -256,6 +291,52 @@ func TestModuleDepsMultiple(t *testing.T) {
 	}
 }
 
+func TestRequireParserModule(t *testing.T) {
+	t.Parallel()
+	p := commonjs.RequireParserFunc(func(content []byte) ([]string, error) {
+		return strings.Fields(string(content)), nil
+	})
+	m := commonjs.NewScriptModule("foo", []byte("bar baz"))
+	m = commonjs.NewRequireParserModule(m, p)
+	require, err := m.Require()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(require) != 2 || require[0] != "bar" || require[1] != "baz" {
+		t.Fatalf("did not find expected require, got %s", require)
+	}
+}
+
+func TestAppRequireParser(t *testing.T) {
+	t.Parallel()
+	const expectedContent = "define(\"bar\",\"bar\");\ndefine(\"foo\",\"[bar]\");\n"
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("foo", []byte("[bar]")),
+		},
+		RequireParser: commonjs.RequireParserFunc(
+			func(content []byte) ([]string, error) {
+				if string(content) == "[bar]" {
+					return []string{"bar"}, nil
+				}
+				return nil, nil
+			}),
+	}
+	actualURL, err := p.ModulesURL([]string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != expectedContent {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
 func TestDirProvider(t *testing.T) {
 	t.Parallel()
 	const name = "b/baz"
@@ -277,6 +358,55 @@ func TestDirProviderNotExist(t *testing.T) {
 	}
 }
 
+func TestPackageDirProvider(t *testing.T) {
+	t.Parallel()
+	p := commonjs.NewPackageDirProvider("_test")
+	cases := map[string]string{
+		"p":     `module.exports = require("./p/index")`,
+		"m":     `module.exports = require("./m/lib/main")`,
+		"b/baz": "require('bar')",
+	}
+	for name, expected := range cases {
+		m, err := p.Module(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes.TrimSpace(content)) != expected {
+			t.Fatalf("did not find expected content for %s, found %s", name, content)
+		}
+	}
+	if _, err := p.Module("b"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestAppPackageDirProvider(t *testing.T) {
+	t.Parallel()
+	const expectedContent = `define("p","module.exports = require(\"p/index\")");
+define("p/index","require('p/q')");
+define("p/q","q");
+`
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewPackageDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := p.ModulesURL([]string{"p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != expectedContent {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
 func TestFileSystemProvider(t *testing.T) {
 	t.Parallel()
 	const name = "b/baz"
@@ -308,6 +438,26 @@ func TestFileSystemProviderNotExistPackage(t *testing.T) {
 	}
 }
 
+func TestPackageFileSystemProvider(t *testing.T) {
+	t.Parallel()
+	p := commonjs.NewPackageFileSystemProvider(
+		pkgrsrc.New("github.com/daaku/go.commonjs/_test"))
+	m, err := p.Module("m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `module.exports = require("./m/lib/main")` {
+		t.Fatalf("did not find expected content, found %s", content)
+	}
+	if _, err := p.Module("xyz"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
 func TestWrapModule(t *testing.T) {
 	t.Parallel()
 	const name = "foo"
@@ -353,6 +503,284 @@ define("bar","bar");
 	}
 }
 
+func TestAppURLModuleOrder(t *testing.T) {
+	t.Parallel()
+	metrics := &missMetrics{}
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Metrics:      metrics,
+	}
+	first, err := p.ModulesURL([]string{"a/foo", "b/baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, modules := range [][]string{{"b/baz", "a/foo"}, {"a/foo", "b/baz", "a/foo"}} {
+		url, err := p.ModulesURL(modules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if url != first {
+			t.Fatalf("was expecting %s for %v, got %s", first, modules, url)
+		}
+	}
+	if metrics.misses != 1 {
+		t.Fatalf("was expecting a single build, got %d", metrics.misses)
+	}
+}
+
+func TestAppURLModuleCollision(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("a", []byte("a")),
+			commonjs.NewScriptModule("ab", []byte("ab")),
+			commonjs.NewScriptModule("bc", []byte("bc")),
+			commonjs.NewScriptModule("c", []byte("c")),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	first, err := p.ModulesURL([]string{"ab", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.ModulesURL([]string{"a", "bc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("was expecting different urls, got %s for both", first)
+	}
+}
+
+func TestAppVendor(t *testing.T) {
+	t.Parallel()
+	const expectedContent = `define("a/foo","require('bar')\nrequire('b/baz')");
+define("b/baz","require('bar')");
+`
+	const expectedVendorContent = "define(\"bar\",\"bar\");\n"
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Vendor:       []string{"bar"},
+	}
+	actualURL, err := p.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendorURL, err := p.VendorURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]string{
+		actualURL: expectedContent,
+		vendorURL: expectedVendorContent,
+	} {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: path}})
+		if w.Body.String() != expected {
+			println(w.Body.String())
+			t.Fatal("did not find expected content, instead found content above")
+		}
+	}
+}
+
+func TestAppVendorEmpty(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{}
+	vendorURL, err := p.VendorURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vendorURL != "" {
+		t.Fatal("was expecting an empty url")
+	}
+}
+
+func TestAppMaxBuildBytes(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:     "r",
+		Providers:     []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore:  commonjs.NewMemoryStore(),
+		MaxBuilds:     1,
+		MaxBuildBytes: 20,
+	}
+	if _, err := p.ModulesURL([]string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ModulesURL([]string{"a/foo"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+	stats := p.BuildStats()
+	if stats.InFlight != 0 || stats.Queued != 0 || stats.Bytes != 0 {
+		t.Fatalf("was expecting no builds in flight, got %+v", stats)
+	}
+	if stats.Rejected != 1 {
+		t.Fatalf("was expecting 1 rejected build, got %+v", stats)
+	}
+}
+
+func TestAppQueuedBuildHonorsContext(t *testing.T) {
+	t.Parallel()
+	started, release := make(chan struct{}), make(chan struct{})
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		MaxBuilds:    1,
+		Modules: []commonjs.Module{
+			commonjs.NewLazyJSONModule("slow", func(ctx context.Context) (interface{}, error) {
+				close(started)
+				<-release
+				return nil, nil
+			}, commonjs.JSONOptions{}),
+		},
+	}
+	go p.InlineDefines([]string{"slow"})
+	<-started
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.ModulesURLContext(ctx, []string{"bar"}); err != context.DeadlineExceeded {
+		t.Fatalf("was expecting the queued build to give up, got %v", err)
+	}
+	if stats := p.BuildStats(); stats.Queued != 0 {
+		t.Fatalf("was expecting no queued builds, got %+v", stats)
+	}
+}
+
+func TestAppRelativeRequire(t *testing.T) {
+	t.Parallel()
+	const expectedContent = `define("bar","bar");
+define("c/d/e","require(\"bar\")");
+define("c/f","require('bar') require(\"c/d/e\")");
+`
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("c/f", []byte(`require('../bar') require("./d/e")`)),
+			commonjs.NewScriptModule("c/d/e", []byte(`require("../../bar")`)),
+		},
+	}
+	actualURL, err := p.ModulesURL([]string{"c/f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != expectedContent {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestResolveName(t *testing.T) {
+	t.Parallel()
+	cases := []struct{ from, name, expected string }{
+		{"a/b", "./c", "a/c"},
+		{"a/b", "../c", "c"},
+		{"a/b/c", "../d/e", "a/d/e"},
+		{"a", "./b", "b"},
+		{"a/b", "c", "c"},
+	}
+	for _, c := range cases {
+		if actual := commonjs.ResolveName(c.from, c.name); actual != c.expected {
+			t.Fatalf("resolving %s from %s: expected %s got %s", c.name, c.from, c.expected, actual)
+		}
+	}
+}
+
+func TestAppBundleInfo(t *testing.T) {
+	t.Parallel()
+	dir := commonjs.NewDirProvider("_test")
+	module := commonjs.NewScriptModule("foo", []byte("require('bar')"))
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{dir},
+		Modules:      []commonjs.Module{module},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := p.ModulesURL([]string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := p.BundleInfo(actualURL)
+	if info == nil {
+		t.Fatal("did not find expected info")
+	}
+	if info.URL != actualURL || len(info.Modules) != 2 {
+		t.Fatalf("did not find expected info, found %+v", info)
+	}
+	if info.Modules[0].Name != "bar" || info.Modules[0].Provider != dir {
+		t.Fatalf("did not find expected provider for bar, found %+v", info.Modules[0])
+	}
+	if info.Modules[1].Name != "foo" || info.Modules[1].Provider != nil {
+		t.Fatalf("did not find expected provider for foo, found %+v", info.Modules[1])
+	}
+	if p.BundleInfo("/r/d613ea9.js") != nil {
+		t.Fatal("was expecting nil info for unknown url")
+	}
+}
+
+func TestAppAliases(t *testing.T) {
+	t.Parallel()
+	const expectedContent = `define("b/baz","require('bar')");
+define("bar","bar");
+define("foo","require('bar') require('b/baz')");
+`
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("foo", []byte("require('qux') require('x/baz')")),
+		},
+		Aliases: map[string]string{
+			"qux": "bar",
+			"x/*": "b/*",
+		},
+	}
+	actualURL, err := p.ModulesURL([]string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != expectedContent {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestAppAlias(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		Aliases: map[string]string{
+			"a":     "b",
+			"c/*":   "d/*",
+			"c/e/*": "f/*",
+		},
+	}
+	cases := map[string]string{
+		"a":     "b",
+		"a/b":   "a/b",
+		"c/x":   "d/x",
+		"c/e/x": "f/x",
+		"z":     "z",
+	}
+	for name, expected := range cases {
+		if actual := p.Alias(name); actual != expected {
+			t.Fatalf("alias for %s: expected %s got %s", name, expected, actual)
+		}
+	}
+}
+
 func TestAppURLLengthError(t *testing.T) {
 	t.Parallel()
 	p := &commonjs.App{
@@ -371,6 +799,143 @@ func TestAppURLLengthError(t *testing.T) {
 	}
 }
 
+func TestAppRoute(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Route: func(urlPath string) (string, bool) {
+			return commonjs.DefaultRoute(strings.TrimSuffix(urlPath, "/latest"))
+		},
+	}
+	actualURL, err := p.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL + "/latest"}})
+	if w.Body.String() != "define(\"bar\",\"bar\");\n" {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestDefaultRoute(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"/r/a102771.js":   "a102771",
+		"a102771.js":      "a102771",
+		"/r/a102771.css":  "",
+		"/r/a1027712.js":  "",
+		"/r/a102771.js/x": "",
+	}
+	for urlPath, expected := range cases {
+		key, ok := commonjs.DefaultRoute(urlPath)
+		if ok != (expected != "") || key != expected {
+			t.Fatalf("for %s expected %q got %q", urlPath, expected, key)
+		}
+	}
+}
+
+func TestHashLength(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		Hash:         sha1.New,
+		HashLength:   12,
+	}
+	actualURL, err := p.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("define(\"bar\",\"bar\");\n")
+	expectedURL := "/r/" + fmt.Sprintf("%x", sha1.Sum(content))[:12] + ".js"
+	if actualURL != expectedURL {
+		t.Fatalf("expected %s got %s", expectedURL, actualURL)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if bytes.Compare(w.Body.Bytes(), content) != 0 {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: "/r/a102771.js"}})
+	if w.Code != 404 {
+		t.Fatalf("was expecting a 404, got %d", w.Code)
+	}
+}
+
+func TestURLNamer(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		URLNamer: commonjs.URLNamerFunc(func(hash string, modules []string) string {
+			return strings.Join(modules, "-") + "-" + hash
+		}),
+	}
+	actualURL, err := p.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualURL != "/r/bar-a77da86.js" {
+		t.Fatalf("unexpected url %s", actualURL)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != "define(\"bar\",\"bar\");\n" {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:    "r",
+		BaseURL:      "https://cdn.example.com/",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := p.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualURL != "https://cdn.example.com/r/a77da86.js" {
+		t.Fatalf("unexpected url %s", actualURL)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: "/r/a77da86.js"}})
+	if w.Body.String() != "define(\"bar\",\"bar\");\n" {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestURLNamerWithoutHash(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		URLNamer: commonjs.URLNamerFunc(func(hash string, modules []string) string {
+			return "bar"
+		}),
+	}
+	if _, err := p.ModulesURL([]string{"bar"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
+
 func TestAppURLPackageMissingError(t *testing.T) {
 	t.Parallel()
 	p := &commonjs.App{
@@ -443,6 +1008,23 @@ func TestAppAppliesTransformToPrelude(t *testing.T) {
 	}
 }
 
+func TestAppScriptPreludeConcurrent(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{MountPath: "r"}
+	errs := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := app.ScriptPrelude()
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 func TestJSMin(t *testing.T) {
 	t.Parallel()
 	m, err := commonjs.JSMin.Transform(
@@ -459,3 +1041,21 @@ func TestJSMin(t *testing.T) {
 		t.Fatal("did not find expected content")
 	}
 }
+
+func TestStringDefine(t *testing.T) {
+	t.Parallel()
+	actual := string(commonjs.StringDefine("a", []byte(" require('b') \n")))
+	expected := "define(\"a\",\"require('b')\");\n"
+	if actual != expected {
+		t.Fatalf("expected %s got %s", expected, actual)
+	}
+}
+
+func TestFunctionDefine(t *testing.T) {
+	t.Parallel()
+	actual := string(commonjs.FunctionDefine("a", []byte("require('b') // c\n")))
+	expected := "define(\"a\",function(require,exports,module){\nrequire('b') // c\n});\n"
+	if actual != expected {
+		t.Fatalf("expected %s got %s", expected, actual)
+	}
+}
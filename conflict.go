@@ -0,0 +1,95 @@
+package commonjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Controls how the App handles a module name provided by more than one of
+// Modules and Providers. The first one in order always wins.
+type ConflictMode int
+
+const (
+	ConflictIgnore ConflictMode = iota // silently use the first module
+	ConflictWarn                       // log a warning once per name
+	ConflictFail                       // fail with a ConflictError
+)
+
+// Indicates a module name is provided by more than one source.
+type ConflictError struct {
+	Name    string
+	Origins []string // descriptions of the sources, in priority order
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf(
+		"module %s is provided by multiple sources: %s",
+		e.Name, strings.Join(e.Origins, ", "))
+}
+
+// Describes the source of a module for conflict reporting.
+func describeSource(ix int, p Provider) string {
+	if p == nil {
+		return "App.Modules"
+	}
+	if s, ok := p.(fmt.Stringer); ok {
+		return fmt.Sprintf("Providers[%d] %s", ix, s)
+	}
+	return fmt.Sprintf("Providers[%d] %T", ix, p)
+}
+
+// Checks the sources after the one that supplied the named module for
+// conflicts, according to the Conflicts mode. The result is cached for each
+// name until the packages are invalidated, and warnings are logged once.
+func (a *App) checkConflict(name string, found int) error {
+	if a.Conflicts == ConflictIgnore || a.Overrides[name] {
+		return nil
+	}
+	a.mu.Lock()
+	conflict, checked := a.conflicts[name]
+	a.mu.Unlock()
+	if !checked {
+		var err error
+		if conflict, err = a.findConflict(name, found); err != nil {
+			return err
+		}
+		a.mu.Lock()
+		if a.conflicts == nil {
+			a.conflicts = make(map[string]*ConflictError)
+		}
+		a.conflicts[name] = conflict
+		a.mu.Unlock()
+		if conflict != nil && a.Conflicts == ConflictWarn {
+			a.log(LogWarn, "%s", conflict)
+		}
+	}
+	if conflict != nil && a.Conflicts == ConflictFail {
+		return conflict
+	}
+	return nil
+}
+
+// Probes the sources after the one that supplied the named module, returning
+// a ConflictError if any of them also provide it.
+func (a *App) findConflict(name string, found int) (*ConflictError, error) {
+	var origins []string
+	if found < 0 {
+		origins = append(origins, describeSource(0, nil))
+	} else {
+		origins = append(origins, describeSource(found, a.Providers[found]))
+	}
+	for ix := found + 1; ix < len(a.Providers); ix++ {
+		_, err := a.Providers[ix].Module(name)
+		if err == nil {
+			origins = append(origins, describeSource(ix, a.Providers[ix]))
+			continue
+		}
+		if !IsNotFound(err) {
+			return nil, err
+		}
+	}
+	if len(origins) == 1 {
+		return nil, nil
+	}
+	return &ConflictError{Name: name, Origins: origins}, nil
+}
@@ -0,0 +1,70 @@
+package commonjs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestConflicts(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("bar", []byte("shadow")),
+		},
+		Providers: []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		Conflicts: commonjs.ConflictFail,
+	}
+	_, err := app.Module("bar")
+	var conflict *commonjs.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("was expecting a conflict error, got %v", err)
+	}
+	if conflict.Name != "bar" || len(conflict.Origins) != 2 || conflict.Origins[0] != "App.Modules" {
+		t.Fatalf("unexpected conflict %v", conflict)
+	}
+	if _, err := app.Module("a/foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Overrides = map[string]bool{"bar": true}
+	m, err := app.Module("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := m.Content(); string(content) != "shadow" {
+		t.Fatalf("was expecting the shadowing module, got %s", content)
+	}
+}
+
+func TestConflictsWarn(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers: []commonjs.Provider{
+			commonjs.NewDirProvider("_test"),
+			commonjs.NewDirProvider("_test"),
+		},
+		Conflicts: commonjs.ConflictWarn,
+	}
+	if _, err := app.Module("bar"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConflictsChecked(t *testing.T) {
+	t.Parallel()
+	counter := &countingProvider{Provider: commonjs.NewDirProvider("_test")}
+	app := &commonjs.App{
+		Providers: []commonjs.Provider{commonjs.NewDirProvider("_test"), counter},
+		Conflicts: commonjs.ConflictWarn,
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := app.Module("bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counter.count != 1 {
+		t.Fatalf("was expecting the conflict to be checked once, got %d", counter.count)
+	}
+}
@@ -0,0 +1,180 @@
+package commonjs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Reads the content for the key from the ContentStore. With VerifyContent,
+// content not matching the hash in the key, like a truncated write, is
+// treated as missing. Content is only verified once for each key.
+func (a *App) storedContent(key string) ([]byte, error) {
+	content, err := a.ContentStore.Get(key)
+	if err != nil || content == nil || a.verified(key) || a.validContent(key, content) {
+		return content, err
+	}
+	return nil, nil
+}
+
+// Checks if the content for the key was already verified.
+func (a *App) verified(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.verifiedKeys[key]
+}
+
+// Records the BuildFingerprint the content for the key was built with, which
+// allows verifying it after the fingerprint changes.
+func (a *App) storedKey(key, fingerprint string) {
+	a.mu.Lock()
+	if a.keyFingerprints == nil {
+		a.keyFingerprints = make(map[string]string)
+	}
+	a.keyFingerprints[key] = fingerprint
+	delete(a.verifiedKeys, key)
+	a.mu.Unlock()
+}
+
+// Checks the content matches the hash in the key if VerifyContent is set.
+// Only content stored by the App, or listed in a loaded manifest, is checked.
+// Other keys, like those from earlier builds or a custom Route, cannot be
+// verified.
+func (a *App) validContent(key string, content []byte) bool {
+	if !a.VerifyContent {
+		return true
+	}
+	a.mu.Lock()
+	fingerprint, ok := a.keyFingerprints[key]
+	a.mu.Unlock()
+	if !ok {
+		return true
+	}
+	if a.hashWith(fingerprint, content) == key {
+		a.mu.Lock()
+		if a.verifiedKeys == nil {
+			a.verifiedKeys = make(map[string]bool)
+		}
+		a.verifiedKeys[key] = true
+		a.mu.Unlock()
+		return true
+	}
+	a.log(LogWarn, "content for %s in the store does not match the hash", key)
+	return false
+}
+
+// The values to exclude when building the package for the spec, matching
+// the methods returning package URLs.
+func (a *App) specExclude(spec packageSpec) (map[string]bool, error) {
+	if spec.vendor || spec.prelude {
+		return nil, nil
+	}
+	exclude, err := a.vendorSet()
+	if err != nil || len(spec.inline) == 0 {
+		return exclude, err
+	}
+	set, err := a.inlineSet(spec.inline)
+	if err != nil {
+		return nil, err
+	}
+	for name := range exclude {
+		set[name] = true
+	}
+	return set, nil
+}
+
+// Rebuilds the cached packages, standalone bundles, stylesheets, assets and
+// prelude stored under the key, which was found missing or corrupted,
+// returning true if any were rebuilt. Scripts from ScriptURL cannot be rebuilt,
+// and are stored again when next requested.
+func (a *App) rebuildKey(ctx context.Context, key string) (bool, error) {
+	matches := func(url string) bool {
+		k, ok := a.route(url)
+		return ok && url != "" && k == key
+	}
+	var rebuilds []func() error
+	a.mu.Lock()
+	delete(a.verifiedKeys, key)
+	for cacheKey, entry := range a.packageURLs {
+		if matches(entry.url) {
+			spec := entry.packageSpec
+			delete(a.packageURLs, cacheKey)
+			rebuilds = append(rebuilds, func() error {
+				a.log(LogInfo, "rebuilding package for %v", spec.modules)
+				exclude, err := a.specExclude(spec)
+				if err != nil {
+					return err
+				}
+				_, err = a.packageURL(ctx, spec, exclude)
+				return err
+			})
+		}
+	}
+	for _, c := range []struct {
+		urls    map[string]string
+		rebuild func(cacheKey string) error
+	}{
+		{a.standaloneURLs, func(cacheKey string) error {
+			_, err := a.StandaloneURL(strings.Split(cacheKey, "\x00"))
+			return err
+		}},
+		{a.styleURLs, func(cacheKey string) error {
+			_, err := a.StylesURL(strings.Split(cacheKey, "\x00"))
+			return err
+		}},
+		{a.assetURLs, func(cacheKey string) error {
+			_, err := a.AssetURL(cacheKey)
+			return err
+		}},
+		{a.scriptURLs, nil},
+	} {
+		for cacheKey, url := range c.urls {
+			if matches(url) {
+				delete(c.urls, cacheKey)
+				if c.rebuild != nil {
+					rebuild, cacheKey, url := c.rebuild, cacheKey, url
+					rebuilds = append(rebuilds, func() error {
+						a.log(LogInfo, "rebuilding %s", url)
+						return rebuild(cacheKey)
+					})
+				}
+			}
+		}
+	}
+	if matches(a.preludeURL) {
+		a.preludeURL = ""
+		rebuilds = append(rebuilds, func() error {
+			a.log(LogInfo, "rebuilding prelude")
+			_, err := a.PreludeURL()
+			return err
+		})
+	}
+	a.mu.Unlock()
+
+	for _, rebuild := range rebuilds {
+		if err := rebuild(); err != nil {
+			return false, err
+		}
+	}
+	return len(rebuilds) > 0, nil
+}
+
+// Opens the file for the key like storedContent, reading it to verify the
+// content if VerifyContent is set and it was not already verified.
+func (a *App) openStored(fs FileStore, key string) (*os.File, error) {
+	f, err := fs.Open(key)
+	if err != nil || f == nil || !a.VerifyContent || a.verified(key) {
+		return f, err
+	}
+	content, err := ioutil.ReadAll(f)
+	if err == nil && a.validContent(key, content) {
+		_, err = f.Seek(0, io.SeekStart)
+		if err == nil {
+			return f, nil
+		}
+	}
+	f.Close()
+	return nil, err
+}
@@ -0,0 +1,135 @@
+package commonjs_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func testVerifyContent(t *testing.T, store commonjs.ByteStore) {
+	app := &commonjs.App{
+		MountPath:     "r",
+		ContentStore:  store,
+		VerifyContent: true,
+		Modules:       []commonjs.Module{commonjs.NewScriptModule("mname", []byte("js"))},
+	}
+	u, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimSuffix(path.Base(u), ".js")
+	if err := store.Store(key, []byte("define(\"mna")); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: u}, Header: http.Header{}})
+	if w.Code != 200 || w.Body.String() != "define(\"mname\",\"js\");\n" {
+		t.Fatalf("was expecting the rebuilt package, got %d %q", w.Code, w.Body.String())
+	}
+	if content, _ := store.Get(key); string(content) != "define(\"mname\",\"js\");\n" {
+		t.Fatalf("was expecting the store to be repaired, got %q", content)
+	}
+}
+
+func TestVerifyContentMemory(t *testing.T) {
+	t.Parallel()
+	testVerifyContent(t, commonjs.NewMemoryStore())
+}
+
+func TestVerifyContentDisk(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "commonjs-verify-content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testVerifyContent(t, commonjs.NewDiskStore(dir))
+}
+
+func TestVerifyContentUnknown(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	app := &commonjs.App{MountPath: "r", ContentStore: store, VerifyContent: true}
+	if err := store.Store("56cc634", []byte("corrupt")); err != nil {
+		t.Fatal(err)
+	}
+	// content the App did not store may be from an earlier build, and cannot
+	// be verified
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: "/r/56cc634.js"}, Header: http.Header{}})
+	if w.Code != 200 {
+		t.Fatalf("was expecting unverifiable content to be served, got %d", w.Code)
+	}
+}
+
+func TestVerifyContentBuildVersion(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	app := &commonjs.App{
+		MountPath:        "r",
+		ContentStore:     store,
+		VerifyContent:    true,
+		FingerprintBuild: true,
+		BuildVersion:     "1",
+		Modules:          []commonjs.Module{commonjs.NewScriptModule("mname", []byte("js"))},
+	}
+	old, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.BuildVersion = "2"
+	current, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old == current {
+		t.Fatal("was expecting a new url for the new build version")
+	}
+	fresh := &commonjs.App{
+		MountPath:        "r",
+		ContentStore:     store,
+		VerifyContent:    true,
+		FingerprintBuild: true,
+		BuildVersion:     "2",
+	}
+	for _, a := range []*commonjs.App{app, fresh} {
+		for _, u := range []string{old, current} {
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: u}, Header: http.Header{}})
+			if w.Code != 200 || w.Body.String() != "define(\"mname\",\"js\");\n" {
+				t.Fatalf("was expecting %s to be served, got %d %q", u, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestVerifyContentRebuildsStyles(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	app := &commonjs.App{
+		MountPath:     "r",
+		Providers:     []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore:  store,
+		VerifyContent: true,
+	}
+	u, err := app.StylesURL([]string{"css/main.css"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimSuffix(path.Base(u), path.Ext(u))
+	if err := store.Store(key, []byte("* {")); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: u}, Header: http.Header{}})
+	if w.Code != 200 || !strings.Contains(w.Body.String(), ".main { color: red; }") {
+		t.Fatalf("was expecting the rebuilt stylesheet, got %d %q", w.Code, w.Body.String())
+	}
+}
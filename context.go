@@ -0,0 +1,87 @@
+package commonjs
+
+import "context"
+
+// A Module may implement ContextModule to support cancellation, deadlines and
+// tracing when fetching its content, for example from a remote server.
+type ContextModule interface {
+	Module
+	ContentContext(ctx context.Context) ([]byte, error)
+}
+
+// A Module may implement ContextRequirer to support cancellation, deadlines
+// and tracing when finding its dependencies, for example where its content is
+// fetched from a remote server.
+type ContextRequirer interface {
+	Module
+	RequireContext(ctx context.Context) ([]string, error)
+}
+
+// A Provider may implement ContextProvider to support cancellation, deadlines
+// and tracing when finding modules.
+type ContextProvider interface {
+	Provider
+	ModuleContext(ctx context.Context, name string) (Module, error)
+}
+
+// A Transform may implement ContextTransform to support cancellation,
+// deadlines and tracing, for example when using a remote service.
+type ContextTransform interface {
+	Transform
+	TransformContext(ctx context.Context, m Module) (Module, error)
+}
+
+// The content of the module using the context if supported.
+func contentContext(ctx context.Context, m Module) ([]byte, error) {
+	if cm, ok := m.(ContextModule); ok {
+		return cm.ContentContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Content()
+}
+
+// The dependencies of the module using the context if supported.
+func requireContext(ctx context.Context, m Module) ([]string, error) {
+	if cr, ok := m.(ContextRequirer); ok {
+		return cr.RequireContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Require()
+}
+
+// The named module from the provider using the context if supported.
+func moduleContext(ctx context.Context, p Provider, name string) (Module, error) {
+	if cp, ok := p.(ContextProvider); ok {
+		return cp.ModuleContext(ctx, name)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.Module(name)
+}
+
+// Applies the Transform using the context if supported.
+func transformContext(ctx context.Context, t Transform, m Module) (Module, error) {
+	if ct, ok := t.(ContextTransform); ok {
+		return ct.TransformContext(ctx, m)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.Transform(m)
+}
+
+// Returns a URL for a given set of modules like ModulesURL, using the context
+// when building the package. This allows for builds fetching remote modules to
+// be cancelled, and for deadlines and tracing to apply.
+func (a *App) ModulesURLContext(ctx context.Context, modules []string) (string, error) {
+	exclude, err := a.vendorSet()
+	if err != nil {
+		return "", err
+	}
+	return a.packageURL(ctx, packageSpec{modules: modules}, exclude)
+}
@@ -0,0 +1,71 @@
+package commonjs_test
+
+import (
+	"context"
+	"errors"
+	"github.com/daaku/go.commonjs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type contextProvider struct {
+	commonjs.Provider
+	ctx context.Context
+}
+
+func (p *contextProvider) ModuleContext(ctx context.Context, name string) (commonjs.Module, error) {
+	p.ctx = ctx
+	return p.Provider.Module(name)
+}
+
+func TestModulesURLContext(t *testing.T) {
+	t.Parallel()
+	type key struct{}
+	p := &contextProvider{Provider: commonjs.NewDirProvider("_test")}
+	app := &commonjs.App{
+		ContentStore: commonjs.NewMemoryStore(),
+		Providers:    []commonjs.Provider{p},
+	}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	if _, err := app.ModulesURLContext(ctx, []string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if p.ctx == nil || p.ctx.Value(key{}) != "value" {
+		t.Fatal("was expecting the context to be passed to the provider")
+	}
+}
+
+func TestModulesURLContextCancelled(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers: []commonjs.Provider{commonjs.NewDirProvider("_test")},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := app.ModulesURLContext(ctx, []string{"bar"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("was expecting a cancelled error, got %v", err)
+	}
+}
+
+func TestURLModuleContextCancelled(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("exports.foo = 1"))
+	}))
+	defer s.Close()
+	app := &commonjs.App{
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      []commonjs.Module{commonjs.NewURLModule("foo", s.URL+"/foo.js")},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := app.ModulesURLContext(ctx, []string{"foo"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("was expecting a cancelled error, got %v", err)
+	}
+	if _, err := app.ModulesURLContext(context.Background(), []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+}
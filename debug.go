@@ -0,0 +1,164 @@
+package commonjs
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The path under the MountPath serving the debug endpoints when DebugAuth is
+// set:
+//
+//	/r/_debug/modules         known modules and their origins
+//	/r/_debug/packages        built packages with their sizes
+//	/r/_debug/graph?m=a,b     dependencies of the given modules
+//	/r/_debug/graph.json?m=a  dependency graph with sizes and require sites, all modules without m
+//	/r/_debug/graph.dot?m=a   the same graph in the Graphviz DOT language
+//	/r/_debug/graph.html?m=a  visualization of the graph
+const DebugPath = "_debug"
+
+type debugModule struct {
+	Name   string `json:"name"`
+	Origin string `json:"origin"`
+}
+
+type debugPackage struct {
+	URL     string   `json:"url"`
+	Modules []string `json:"modules"`
+	Vendor  bool     `json:"vendor,omitempty"`
+	Locale  string   `json:"locale,omitempty"`
+	Size    int      `json:"size"`
+}
+
+// Serves the debug endpoints if the request is for one of them, returning
+// true if it was handled.
+func (a *App) serveDebug(w http.ResponseWriter, r *http.Request) bool {
+	if a.DebugAuth == nil {
+		return false
+	}
+	prefix := path.Join("/", a.MountPath, DebugPath) + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		return false
+	}
+	if !a.DebugAuth(r) {
+		a.serveError(w, r, 403, "forbidden", nil)
+		return true
+	}
+
+	var v interface{}
+	var err error
+	switch endpoint := r.URL.Path[len(prefix):]; endpoint {
+	case "modules":
+		v, err = a.debugModules()
+	case "packages":
+		v = a.debugPackages()
+	case "graph":
+		v, err = a.Graph(queryModules(r))
+	case "graph.json":
+		v, err = a.GraphData(queryModules(r))
+	case "graph.dot", "graph.html":
+		a.serveGraph(w, r, endpoint)
+		return true
+	default:
+		a.serveError(w, r, 404, "not found", nil)
+		return true
+	}
+	if err != nil {
+		a.serveError(w, r, 500, "error serving debug endpoint", err)
+		return true
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		a.serveError(w, r, 500, "error serving debug endpoint", err)
+		return true
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(out)
+	return true
+}
+
+// Serves the graph in the DOT language or the page visualizing it.
+func (a *App) serveGraph(w http.ResponseWriter, r *http.Request, endpoint string) {
+	contentType, out := "text/html; charset=utf-8", graphHTML
+	if endpoint == "graph.dot" {
+		var err error
+		if out, err = a.GraphDOT(queryModules(r)); err != nil {
+			a.serveError(w, r, 500, "error serving debug endpoint", err)
+			return
+		}
+		contentType = "text/vnd.graphviz"
+	}
+	w.Header().Add("Content-Type", contentType)
+	w.WriteHeader(200)
+	w.Write(out)
+}
+
+func (a *App) debugModules() ([]debugModule, error) {
+	names, err := a.ModuleNames()
+	if err != nil {
+		return nil, err
+	}
+	modules := make([]debugModule, len(names))
+	for ix, name := range names {
+		origin, err := a.Origin(name)
+		if err != nil {
+			return nil, err
+		}
+		modules[ix] = debugModule{Name: name, Origin: origin}
+	}
+	return modules, nil
+}
+
+func (a *App) debugPackages() []debugPackage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	packages := []debugPackage{}
+	for _, entry := range a.packageURLs {
+		packages = append(packages, debugPackage{
+			URL:     entry.url,
+			Modules: entry.modules,
+			Vendor:  entry.vendor,
+			Locale:  entry.locale,
+			Size:    entry.size,
+		})
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].URL < packages[j].URL
+	})
+	return packages
+}
+
+// Returns the dependency graph for the given modules, mapping each module
+// including the transitive dependencies to the modules it requires.
+func (a *App) Graph(modules []string) (map[string][]string, error) {
+	set := make(map[string]bool)
+	if err := a.buildDeps(modules, set); err != nil {
+		return nil, err
+	}
+	graph := make(map[string][]string, len(set))
+	for name := range set {
+		deps, err := a.deps(name)
+		if err != nil {
+			return nil, err
+		}
+		if deps == nil {
+			deps = []string{}
+		}
+		graph[name] = deps
+	}
+	return graph, nil
+}
+
+// The comma separated modules in the "m" query parameter.
+func queryModules(r *http.Request) []string {
+	var modules []string
+	for _, name := range strings.Split(r.URL.Query().Get("m"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			modules = append(modules, name)
+		}
+	}
+	return modules
+}
@@ -0,0 +1,82 @@
+package commonjs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("page", []byte("require('widget')")),
+			commonjs.NewScriptModule("widget", []byte("")),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+		DebugAuth: func(r *http.Request) bool {
+			return r.Header.Get("X-Debug") == "yes"
+		},
+	}
+	if _, err := app.ModulesURL([]string{"page"}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(url string, allowed bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", url, nil)
+		if allowed {
+			r.Header.Set("X-Debug", "yes")
+		}
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := get("/r/_debug/modules", false); w.Code != 403 {
+		t.Fatalf("was expecting a 403, got %d", w.Code)
+	}
+	if w := get("/r/_debug/nope", true); w.Code != 404 {
+		t.Fatalf("was expecting a 404, got %d", w.Code)
+	}
+
+	var modules []map[string]string
+	if err := json.Unmarshal(get("/r/_debug/modules", true).Body.Bytes(), &modules); err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 2 || modules[0]["name"] != "page" || modules[0]["origin"] != "App.Modules" {
+		t.Fatalf("unexpected modules %v", modules)
+	}
+
+	var packages []map[string]interface{}
+	if err := json.Unmarshal(get("/r/_debug/packages", true).Body.Bytes(), &packages); err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 || packages[0]["size"].(float64) == 0 {
+		t.Fatalf("unexpected packages %v", packages)
+	}
+
+	var graph map[string][]string
+	if err := json.Unmarshal(get("/r/_debug/graph?m=page", true).Body.Bytes(), &graph); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"page": {"widget"}, "widget": {}}
+	if !reflect.DeepEqual(graph, expected) {
+		t.Fatalf("expected %v got %v", expected, graph)
+	}
+}
+
+func TestDebugDisabled(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{MountPath: "r", ContentStore: commonjs.NewMemoryStore()}
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/r/_debug/modules", nil)
+	app.ServeHTTP(w, r)
+	if w.Code != 404 {
+		t.Fatalf("was expecting a 404, got %d", w.Code)
+	}
+}
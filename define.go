@@ -0,0 +1,170 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type defineTransform struct {
+	re     *regexp.Regexp
+	values map[string]string
+}
+
+// Provides a transform replacing identifiers like process.env.NODE_ENV or
+// __DEV__ with the given JavaScript literals, like `"production"` or `false`.
+// Applied before minification, this allows for development only branches to
+// be removed. Identifiers are only replaced when not part of a longer
+// identifier or property access.
+func NewDefineTransform(values map[string]string) Transform {
+	var keys []string
+	for key := range values {
+		keys = append(keys, regexp.QuoteMeta(key))
+	}
+	// longest first so that a.b is preferred over a
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return &defineTransform{
+		re:     regexp.MustCompile(`(^|[^\w$.])(` + strings.Join(keys, "|") + `)\b`),
+		values: values,
+	}
+}
+
+func (d *defineTransform) Transform(m Module) (Module, error) {
+	if len(d.values) == 0 || strings.TrimPrefix(m.Ext(), ".") != jsExt {
+		return m, nil
+	}
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	matches := d.re.FindAllSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return m, nil
+	}
+	out := new(bytes.Buffer)
+	last := 0
+	for _, match := range matches {
+		end := match[5]
+		if end < len(content) && content[end] == '$' {
+			continue // part of a longer identifier
+		}
+		out.Write(content[last:match[4]])
+		out.WriteString(d.values[string(content[match[4]:end])])
+		last = end
+	}
+	out.Write(content[last:])
+	return NewScriptModule(m.Name(), out.Bytes()), nil
+}
+
+type defineModule struct {
+	name    string
+	values  map[string]interface{}
+	mu      sync.Mutex
+	content []byte // once the values have been evaluated successfully
+}
+
+var reIdentifier = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+// Define a module exporting each of the values as JSON, like
+// `exports.version = "1.2"`. Values may be functions returning the value, or
+// the value and an error, which are called at build time to allow for values
+// like the git revision or build timestamp. The functions are called once, the
+// first time the module is built, and the content is reused after that.
+func NewDefineModule(name string, values map[string]interface{}) Module {
+	return &defineModule{name: name, values: values}
+}
+
+func (m *defineModule) Name() string {
+	return m.name
+}
+
+func (m *defineModule) Content() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.content != nil {
+		return m.content, nil
+	}
+	var keys []string
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := new(bytes.Buffer)
+	for _, key := range keys {
+		v := m.values[key]
+		switch f := v.(type) {
+		case func() interface{}:
+			v = f()
+		case func() (interface{}, error):
+			var err error
+			if v, err = f(); err != nil {
+				return nil, fmt.Errorf("evaluating %s in module %s: %w", key, m.name, err)
+			}
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if reIdentifier.MatchString(key) {
+			out.WriteString("exports." + key)
+		} else {
+			quoted, _ := json.Marshal(key)
+			out.WriteString("exports[" + string(quoted) + "]")
+		}
+		out.WriteString("=")
+		out.Write(value)
+		out.WriteString(";\n")
+	}
+	m.content = out.Bytes()
+	return m.content, nil
+}
+
+func (m *defineModule) Require() ([]string, error) {
+	return nil, nil
+}
+
+func (m *defineModule) Ext() string {
+	return jsExt
+}
+
+type transforms []Transform
+
+// Provides a Transform applying the given transforms in order. This is useful
+// to replace identifiers using NewDefineTransform before minification.
+func NewTransforms(t ...Transform) Transform {
+	return transforms(t)
+}
+
+// Identifies the transforms in order.
+func (ts transforms) Fingerprint() string {
+	ids := make([]string, len(ts))
+	for ix, t := range ts {
+		ids[ix] = transformFingerprint(t)
+	}
+	return "[" + strings.Join(ids, ",") + "]"
+}
+
+func (ts transforms) Transform(m Module) (Module, error) {
+	var err error
+	for _, t := range ts {
+		if m, err = t.Transform(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (ts transforms) TransformContext(ctx context.Context, m Module) (Module, error) {
+	var err error
+	for _, t := range ts {
+		if m, err = transformContext(ctx, t, m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,105 @@
+package commonjs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestDefineTransform(t *testing.T) {
+	t.Parallel()
+	transform := commonjs.NewDefineTransform(map[string]string{
+		"process.env.NODE_ENV": `"production"`,
+		"__DEV__":              "false",
+	})
+	cases := map[string]string{
+		`if (process.env.NODE_ENV !== "production") x()`: `if ("production" !== "production") x()`,
+		`__DEV__ && log()`: `false && log()`,
+		`a.__DEV__ + __DEV__x + __DEV__$ + my__DEV__`: `a.__DEV__ + __DEV__x + __DEV__$ + my__DEV__`,
+		`process.env.NODE_ENV.length`:                 `"production".length`,
+		`(__DEV__,__DEV__)`:                           `(false,false)`,
+	}
+	for input, expected := range cases {
+		m, err := transform.Transform(commonjs.NewScriptModule("a", []byte(input)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != expected {
+			t.Fatalf("for %s expected %s got %s", input, expected, actual)
+		}
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	t.Parallel()
+	transform := commonjs.NewTransforms(
+		commonjs.NewDefineTransform(map[string]string{"A": "B"}),
+		commonjs.NewDefineTransform(map[string]string{"B": "C"}),
+	)
+	m, err := transform.Transform(commonjs.NewScriptModule("a", []byte("A")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != "C" {
+		t.Fatalf("expected C got %s", actual)
+	}
+}
+
+func TestDefineModule(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	m := commonjs.NewDefineModule("config", map[string]interface{}{
+		"debug":    false,
+		"api-host": "example.com",
+		"revision": func() interface{} {
+			calls++
+			return "abc123"
+		},
+		"built": func() (interface{}, error) {
+			return 42, nil
+		},
+	})
+	if calls != 0 {
+		t.Fatal("was expecting lazy values to not be evaluated up front")
+	}
+	actual, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `exports["api-host"]="example.com";
+exports.built=42;
+exports.debug=false;
+exports.revision="abc123";
+`
+	if string(actual) != expected {
+		println(string(actual))
+		t.Fatal("did not find expected content, instead found content above")
+	}
+	if _, err := m.Content(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("was expecting lazy values to be evaluated once, got %d", calls)
+	}
+}
+
+func TestDefineModuleError(t *testing.T) {
+	t.Parallel()
+	m := commonjs.NewDefineModule("config", map[string]interface{}{
+		"revision": func() (interface{}, error) {
+			return nil, errors.New("no git")
+		},
+	})
+	if _, err := m.Content(); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
@@ -0,0 +1,186 @@
+package commonjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The differences between the packages in two manifests.
+type ManifestDiff struct {
+	Packages []PackageDiff // added, removed and changed packages, sorted by their modules
+}
+
+// The differences between two builds of a package. Packages are matched by
+// what they were built for, like the modules and the locale.
+type PackageDiff struct {
+	Modules []string // modules the package was built for
+	Vendor  bool
+	Locale  string
+	OldURL  string // empty if the package was added
+	NewURL  string // empty if the package was removed
+	OldSize int
+	NewSize int
+	Added   []ModuleChange // modules added to the package
+	Removed []ModuleChange // modules removed from the package
+	Changed []ModuleChange // modules whose size changed
+}
+
+// The change in size of a module in a package, with a 0 OldSize for added
+// modules and a 0 NewSize for removed modules.
+type ModuleChange struct {
+	Name    string
+	OldSize int
+	NewSize int
+}
+
+// The change in size of the package in bytes.
+func (p PackageDiff) Delta() int {
+	return p.NewSize - p.OldSize
+}
+
+// Describes the package, like "a, b (vendor, fr)".
+func (p PackageDiff) String() string {
+	var attrs []string
+	if p.Vendor {
+		attrs = append(attrs, "vendor")
+	}
+	if p.Locale != "" {
+		attrs = append(attrs, p.Locale)
+	}
+	s := strings.Join(p.Modules, ", ")
+	if len(attrs) > 0 {
+		s += " (" + strings.Join(attrs, ", ") + ")"
+	}
+	return s
+}
+
+// Compares two manifests written by WriteManifest, typically from the base and
+// the head of a change, to catch unexpected growth. Module level differences
+// are only available for manifests including module sizes.
+func DiffManifests(before, after io.Reader) (*ManifestDiff, error) {
+	var oldManifest, newManifest manifest
+	if err := json.NewDecoder(before).Decode(&oldManifest); err != nil {
+		return nil, fmt.Errorf("reading old manifest: %w", err)
+	}
+	if err := json.NewDecoder(after).Decode(&newManifest); err != nil {
+		return nil, fmt.Errorf("reading new manifest: %w", err)
+	}
+	oldPackages := make(map[string]manifestPackage, len(oldManifest.Packages))
+	for _, p := range oldManifest.Packages {
+		oldPackages[p.spec().key()] = p
+	}
+	d := &ManifestDiff{}
+	for _, p := range newManifest.Packages {
+		key := p.spec().key()
+		o, ok := oldPackages[key]
+		delete(oldPackages, key)
+		if ok && o.URL == p.URL {
+			continue
+		}
+		pd := PackageDiff{
+			Modules: p.Modules,
+			Vendor:  p.Vendor,
+			Locale:  p.Locale,
+			NewURL:  p.URL,
+			NewSize: p.Size,
+		}
+		if ok {
+			pd.OldURL, pd.OldSize = o.URL, o.Size
+		}
+		diffModules(&pd, o.ModuleSizes, p.ModuleSizes)
+		d.Packages = append(d.Packages, pd)
+	}
+	for _, o := range oldPackages {
+		pd := PackageDiff{
+			Modules: o.Modules,
+			Vendor:  o.Vendor,
+			Locale:  o.Locale,
+			OldURL:  o.URL,
+			OldSize: o.Size,
+		}
+		diffModules(&pd, o.ModuleSizes, nil)
+		d.Packages = append(d.Packages, pd)
+	}
+	sort.Slice(d.Packages, func(i, j int) bool {
+		return d.Packages[i].String() < d.Packages[j].String()
+	})
+	return d, nil
+}
+
+// Fills in the module level differences.
+func diffModules(pd *PackageDiff, before, after map[string]int) {
+	for name, size := range after {
+		oldSize, ok := before[name]
+		change := ModuleChange{Name: name, OldSize: oldSize, NewSize: size}
+		if !ok {
+			pd.Added = append(pd.Added, change)
+		} else if oldSize != size {
+			pd.Changed = append(pd.Changed, change)
+		}
+	}
+	for name, size := range before {
+		if _, ok := after[name]; !ok {
+			pd.Removed = append(pd.Removed, ModuleChange{Name: name, OldSize: size})
+		}
+	}
+	sortChanges(pd.Added)
+	sortChanges(pd.Removed)
+	sortChanges(pd.Changed)
+}
+
+func sortChanges(changes []ModuleChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// Returns the packages which grew by more than the given number of bytes.
+func (d *ManifestDiff) Grown(bytes int) []PackageDiff {
+	var grown []PackageDiff
+	for _, p := range d.Packages {
+		if p.Delta() > bytes {
+			grown = append(grown, p)
+		}
+	}
+	return grown
+}
+
+// Writes a human readable report of the differences, like:
+//
+//	page: 1200 -> 1500 bytes (+300)
+//	  + widget 280
+//	  ~ page 100 -> 120 (+20)
+func (d *ManifestDiff) WriteReport(w io.Writer) error {
+	for _, p := range d.Packages {
+		status := ""
+		if p.OldURL == "" {
+			status = " added"
+		} else if p.NewURL == "" {
+			status = " removed"
+		}
+		if _, err := fmt.Fprintf(w, "%s:%s %d -> %d bytes (%+d)\n",
+			p, status, p.OldSize, p.NewSize, p.Delta()); err != nil {
+			return err
+		}
+		for _, c := range p.Added {
+			if _, err := fmt.Fprintf(w, "  + %s %d\n", c.Name, c.NewSize); err != nil {
+				return err
+			}
+		}
+		for _, c := range p.Removed {
+			if _, err := fmt.Fprintf(w, "  - %s %d\n", c.Name, c.OldSize); err != nil {
+				return err
+			}
+		}
+		for _, c := range p.Changed {
+			if _, err := fmt.Fprintf(w, "  ~ %s %d -> %d (%+d)\n",
+				c.Name, c.OldSize, c.NewSize, c.NewSize-c.OldSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func diffManifest(t *testing.T, modules map[string]string, entrypoints ...[]string) []byte {
+	var ms []commonjs.Module
+	for name, content := range modules {
+		ms = append(ms, commonjs.NewScriptModule(name, []byte(content)))
+	}
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      ms,
+	}
+	for _, modules := range entrypoints {
+		if _, err := app.ModulesURL(modules); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := app.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDiffManifests(t *testing.T) {
+	t.Parallel()
+	before := diffManifest(t, map[string]string{
+		"page":   "require('widget');require('old')",
+		"widget": "js",
+		"old":    "old",
+		"admin":  "js",
+		"other":  "js",
+	}, []string{"page"}, []string{"admin"}, []string{"other"})
+	after := diffManifest(t, map[string]string{
+		"page":   "require('widget');require('new')",
+		"widget": "more js",
+		"new":    "a large dependency, much larger than the others",
+		"admin":  "js",
+		"other":  "js",
+		"fresh":  "js",
+	}, []string{"page"}, []string{"admin"}, []string{"fresh"})
+
+	diff, err := commonjs.DiffManifests(bytes.NewReader(before), bytes.NewReader(after))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Packages) != 3 {
+		t.Fatalf("unexpected packages %+v", diff.Packages)
+	}
+	fresh, other, page := diff.Packages[0], diff.Packages[1], diff.Packages[2]
+	if fresh.String() != "fresh" || fresh.OldURL != "" || len(fresh.Added) != 1 {
+		t.Fatalf("unexpected added package %+v", fresh)
+	}
+	if other.String() != "other" || other.NewURL != "" || len(other.Removed) != 1 {
+		t.Fatalf("unexpected removed package %+v", other)
+	}
+	if !reflect.DeepEqual(page.Added, []commonjs.ModuleChange{{Name: "new", NewSize: 47}}) ||
+		!reflect.DeepEqual(page.Removed, []commonjs.ModuleChange{{Name: "old", OldSize: 3}}) ||
+		!reflect.DeepEqual(page.Changed, []commonjs.ModuleChange{{Name: "widget", OldSize: 2, NewSize: 7}}) {
+		t.Fatalf("unexpected module changes %+v", page)
+	}
+	if page.Delta() <= 0 {
+		t.Fatalf("was expecting the page package to grow, got %d", page.Delta())
+	}
+	if grown := diff.Grown(fresh.Delta()); len(grown) != 1 || grown[0].String() != "page" {
+		t.Fatalf("unexpected grown packages %+v", grown)
+	}
+
+	var report bytes.Buffer
+	if err := diff.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"fresh: added 0 -> ", "other: removed ", "  + new 47\n", "  - old 3\n", "  ~ widget 2 -> 7 (+5)\n"} {
+		if !bytes.Contains(report.Bytes(), []byte(e)) {
+			println(report.String())
+			t.Fatalf("did not find %q", e)
+		}
+	}
+}
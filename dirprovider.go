@@ -0,0 +1,259 @@
+package commonjs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Controls how a directory Provider handles symbolic links.
+type SymlinkPolicy int
+
+const (
+	SymlinkDefault SymlinkPolicy = iota // follow links to find modules, but do not list linked directories, like NewDirProvider
+	SymlinkFollow                       // follow all links, also listing linked directories
+	SymlinkInside                       // follow links resolving inside the directory
+	SymlinkIgnore                       // ignore files reached through links
+)
+
+// Options for NewDirProviderWithOptions.
+type DirOptions struct {
+	Roots    []string                // optional additional directories, searched in order after the first
+	Ignore   []string                // optional patterns of files and directories to skip, like "node_modules" or "*.test.js"
+	Symlinks SymlinkPolicy           // optional handling of symbolic links, defaults to SymlinkDefault
+	MaxDepth int                     // optional maximum number of path elements in a file path, 1 allows only the top directory
+	Packages bool                    // resolve directories like NewPackageDirProvider
+	Assets   map[string]AssetWrapper // optional wrappers like NewAssetDirProvider
+
+	// Optional extensions tried in order for names without one, defaults to
+	// ".js". Files found with another extension are wrapped using the
+	// wrapper for the extension in Assets or DefaultAssets if there is one,
+	// which allows for registering wrappers compiling other languages.
+	Extensions []string
+
+	// Name the modules under each directory with the directory name as a
+	// prefix, for example the file "src/widgets/menu.js" as "widgets/menu"
+	// when given the directory "src/widgets". This allows for merging roots
+	// without renaming modules.
+	PrefixRoots bool
+}
+
+// Provide modules from one or more directory trees. Ignore patterns are matched
+// like NewGlobModules, against the slash separated path relative to the
+// directory if the pattern contains a slash, or against each file and
+// directory name otherwise. ModuleNames lists the modules in all the trees.
+func NewDirProviderWithOptions(dirname string, opts DirOptions) Provider {
+	roots := append([]string{dirname}, opts.Roots...)
+	providers := make(multiDirProvider, len(roots))
+	for ix, root := range roots {
+		var p Provider = &dirProvider{
+			path:     root,
+			packages: opts.Packages,
+			assets:   opts.Assets,
+			ignore:   opts.Ignore,
+			symlinks: opts.Symlinks,
+			maxDepth: opts.MaxDepth,
+			exts:     opts.Extensions,
+		}
+		if opts.PrefixRoots {
+			p = NewPrefixProvider(filepath.Base(filepath.Clean(root)), p)
+		}
+		providers[ix] = p
+	}
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return providers
+}
+
+// The extensions tried in order for names without one.
+func (d *dirProvider) extensions() []string {
+	if len(d.exts) == 0 {
+		return []string{ext}
+	}
+	return d.exts
+}
+
+// The extension of the file name if it is one of the extensions.
+func (d *dirProvider) hasExtension(filename string) (string, bool) {
+	fileExt := filepath.Ext(filename)
+	for _, e := range d.extensions() {
+		if fileExt == e {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// Checks if the slash separated file path relative to the directory is
+// excluded by the ignore patterns or the maximum depth.
+func (d *dirProvider) excluded(rel string) bool {
+	elems := strings.Split(rel, "/")
+	if d.maxDepth > 0 && len(elems) > d.maxDepth {
+		return true
+	}
+	for _, pattern := range d.ignore {
+		for ix := range elems {
+			target := elems[ix]
+			if strings.Contains(pattern, "/") {
+				target = strings.Join(elems[:ix+1], "/")
+			}
+			if matched, _ := path.Match(pattern, target); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checks if the symlink policy allows the slash separated file path relative
+// to the directory. Files that do not exist are allowed, leaving the caller to
+// report them as not found.
+func (d *dirProvider) linkAllowed(rel string) (bool, error) {
+	if d.symlinks == SymlinkDefault || d.symlinks == SymlinkFollow {
+		return true, nil
+	}
+	root, err := filepath.EvalSymlinks(d.path)
+	if err != nil {
+		return false, err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(d.path, filepath.FromSlash(rel)))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if d.symlinks == SymlinkIgnore {
+		return resolved == filepath.Join(root, filepath.FromSlash(rel)), nil
+	}
+	inside, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return false, nil
+	}
+	return inside != ".." && !strings.HasPrefix(inside, ".."+string(filepath.Separator)), nil
+}
+
+// Checks if the file path is excluded or disallowed by the symlink policy.
+func (d *dirProvider) skip(rel string) (bool, error) {
+	if d.excluded(rel) {
+		return true, nil
+	}
+	allowed, err := d.linkAllowed(rel)
+	return !allowed, err
+}
+
+// Lists the names of the modules in the directory tree, once each even if
+// there are files with multiple extensions.
+func (d *dirProvider) ModuleNames() ([]string, error) {
+	root, err := filepath.EvalSymlinks(d.path)
+	if err != nil {
+		return nil, err
+	}
+	names, err := d.walk(d.path, "", map[string]bool{root: true}, nil)
+	if err != nil || len(d.exts) < 2 {
+		return names, err
+	}
+	seen := make(map[string]bool, len(names))
+	unique := names[:0]
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique, nil
+}
+
+// Walks the directory tree adding the names of the modules, following allowed
+// symbolic links to directories unless they link back to a directory being
+// walked. Linked directories are only walked with SymlinkFollow or
+// SymlinkInside.
+func (d *dirProvider) walk(dir, rel string, walking map[string]bool, names []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		childRel := path.Join(rel, e.Name())
+		filename := filepath.Join(dir, e.Name())
+		isDir := e.IsDir()
+		if e.Type()&os.ModeSymlink != 0 {
+			stat, err := os.Stat(filename)
+			if err != nil {
+				continue // dangling link
+			}
+			isDir = stat.IsDir()
+			if isDir && d.symlinks == SymlinkDefault {
+				continue
+			}
+		}
+		if isDir {
+			// the files inside have one more path element
+			if d.maxDepth > 0 && strings.Count(childRel, "/")+2 > d.maxDepth {
+				continue
+			}
+		}
+		fileExt, ok := d.hasExtension(filename)
+		if !isDir && !ok {
+			continue
+		}
+		skip, err := d.skip(childRel)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		if !isDir {
+			names = append(names, strings.TrimSuffix(childRel, fileExt))
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(filename)
+		if err != nil {
+			return nil, err
+		}
+		if walking[resolved] {
+			continue
+		}
+		walking[resolved] = true
+		names, err = d.walk(filename, childRel, walking, names)
+		delete(walking, resolved)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// Provides modules from multiple directories, the first one wins.
+type multiDirProvider []Provider
+
+func (p multiDirProvider) Module(name string) (Module, error) {
+	for _, d := range p {
+		m, err := d.Module(name)
+		if err == nil || !IsNotFound(err) {
+			return m, err
+		}
+	}
+	return nil, errModuleNotFound(name)
+}
+
+func (p multiDirProvider) ModuleNames() ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, d := range p {
+		dirNames, err := d.(Lister).ModuleNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range dirNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,199 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+// Writes the files, creating directories as needed.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func moduleNames(t *testing.T, p commonjs.Provider) []string {
+	names, err := p.(commonjs.Lister).ModuleNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDirProviderWithOptions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main/a.js":                "a",
+		"main/a.test.js":           "test",
+		"main/node_modules/x/x.js": "x",
+		"main/b/c.js":              "c",
+		"main/b/d/e.js":            "e",
+		"extra/a.js":               "shadowed",
+		"extra/f.js":               "f",
+		"outside/secret.js":        "secret",
+	})
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(dir, "main", "link")); err != nil {
+		t.Fatal(err)
+	}
+	p := commonjs.NewDirProviderWithOptions(filepath.Join(dir, "main"), commonjs.DirOptions{
+		Roots:    []string{filepath.Join(dir, "extra")},
+		Ignore:   []string{"node_modules", "*.test.js"},
+		Symlinks: commonjs.SymlinkInside,
+		MaxDepth: 2,
+	})
+	expected := []string{"a", "b/c", "f"}
+	if names := moduleNames(t, p); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+	for _, name := range []string{"a.test", "node_modules/x/x", "b/d/e", "link/secret"} {
+		if _, err := p.Module(name); !commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting %s to not be found, got %v", name, err)
+		}
+	}
+	m, err := p.Module("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := m.Content(); string(content) != "a" {
+		t.Fatalf("was expecting the module from the first root, got %q", content)
+	}
+	if _, err := p.Module("f"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirProviderSymlinks(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"root/a/b.js":       "b",
+		"outside/secret.js": "secret",
+	})
+	root := filepath.Join(dir, "root")
+	if err := os.Symlink(filepath.Join(root, "a"), filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(root, "outside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		policy   commonjs.SymlinkPolicy
+		expected []string
+	}{
+		{commonjs.SymlinkDefault, []string{"a/b"}},
+		{commonjs.SymlinkFollow, []string{"a/b", "inside/b", "outside/secret"}},
+		{commonjs.SymlinkInside, []string{"a/b", "inside/b"}},
+		{commonjs.SymlinkIgnore, []string{"a/b"}},
+	}
+	for _, c := range cases {
+		p := commonjs.NewDirProviderWithOptions(root, commonjs.DirOptions{Symlinks: c.policy})
+		if names := moduleNames(t, p); !reflect.DeepEqual(names, c.expected) {
+			t.Fatalf("was expecting %v for policy %d, got %v", c.expected, c.policy, names)
+		}
+	}
+}
+
+type upperModule struct {
+	commonjs.Module
+}
+
+func (m upperModule) Content() ([]byte, error) {
+	content, err := m.Module.Content()
+	return bytes.ToUpper(content), err
+}
+
+func TestDirProviderExtensions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.js":        "a",
+		"a.json":      `"shadowed"`,
+		"config.json": `{"b":1}`,
+		"x.coffee":    "x",
+		"y.txt":       "y",
+	})
+	assets := commonjs.DefaultAssets()
+	assets[".coffee"] = func(m commonjs.Module) commonjs.Module {
+		return upperModule{m}
+	}
+	p := commonjs.NewDirProviderWithOptions(dir, commonjs.DirOptions{
+		Extensions: []string{".js", ".json", ".coffee"},
+		Assets:     assets,
+	})
+	expected := []string{"a", "config", "x"}
+	if names := moduleNames(t, p); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+	for name, expected := range map[string]string{
+		"a":           "a",
+		"config":      `module.exports={"b":1};`,
+		"config.json": `module.exports={"b":1};`,
+		"x":           "X",
+	} {
+		m, err := p.Module(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Fatalf("was expecting %q for %s, got %q", expected, name, content)
+		}
+	}
+	if _, err := p.Module("y"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting y to not be found, got %v", err)
+	}
+}
+
+func TestDirProviderPrefixRoots(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"src/widgets/menu.js": "require('./item')",
+		"src/widgets/item.js": "item",
+		"lib/util/strings.js": "strings",
+	})
+	p := commonjs.NewDirProviderWithOptions(filepath.Join(dir, "src", "widgets"), commonjs.DirOptions{
+		Roots:       []string{filepath.Join(dir, "lib", "util") + "/"},
+		PrefixRoots: true,
+	})
+	expected := []string{"util/strings", "widgets/item", "widgets/menu"}
+	if names := moduleNames(t, p); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{p},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	graph, err := app.Graph([]string{"widgets/menu", "util/strings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps := graph["widgets/menu"]; !reflect.DeepEqual(deps, []string{"widgets/item"}) {
+		t.Fatalf("was expecting menu to require widgets/item, got %v", deps)
+	}
+	if _, err := p.Module("menu"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting the unprefixed name to not be found, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package commonjs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// The name of the manifest written by Export.
+const ExportManifest = "manifest.json"
+
+// Builds the packages for the given entry points along with the vendor
+// package, and writes them to the directory using their hashed file names.
+// A manifest mapping the entry points to package URLs is also written, in the
+// format understood by LoadManifest. This allows for packages to be hosted
+// statically, for example on a CDN.
+func (a *App) Export(dirname string, entrypoints [][]string) error {
+	if err := a.Precompile(entrypoints); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirname, 0755); err != nil {
+		return err
+	}
+
+	var packages []manifestPackage
+	if len(a.Vendor) > 0 {
+		url, err := a.VendorURL()
+		if err != nil {
+			return err
+		}
+		packages = append(packages,
+			manifestPackage{Modules: a.Vendor, Vendor: true, URL: url})
+	}
+	for _, modules := range entrypoints {
+		url, err := a.ModulesURL(modules)
+		if err != nil {
+			return err
+		}
+		packages = append(packages, manifestPackage{Modules: modules, URL: url})
+	}
+
+	for ix, p := range packages {
+		key, ok := a.route(p.URL)
+		if !ok {
+			return fmt.Errorf("invalid package url %s", p.URL)
+		}
+		content, err := a.storedContent(key)
+		if err != nil {
+			return err
+		}
+		if content == nil {
+			return fmt.Errorf("package %s missing from store", p.URL)
+		}
+		packages[ix].Integrity = Integrity(content)
+		packages[ix].Size = len(content)
+		filename := filepath.Join(dirname, path.Base(p.URL))
+		if err := ioutil.WriteFile(filename, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writeManifest(buf, a.BuildFingerprint(), packages); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dirname, ExportManifest), buf.Bytes(), 0644)
+}
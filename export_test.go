@@ -0,0 +1,63 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "commonjs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Vendor:       []string{"bar"},
+	}
+	if err := a.Export(dir, [][]string{{"a/foo"}}); err != nil {
+		t.Fatal(err)
+	}
+	actualURL, err := a.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendorURL, err := a.VendorURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, path.Base(vendorURL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "define(\"bar\",\"bar\");\n" {
+		t.Fatalf("did not find expected content, found %s", content)
+	}
+
+	f, err := os.Open(filepath.Join(dir, commonjs.ExportManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := &commonjs.App{MountPath: "r", ContentStore: commonjs.NewMemoryStore()}
+	if err := b.LoadManifest(f); err != nil {
+		t.Fatal(err)
+	}
+	loadedURL, err := b.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loadedURL != actualURL {
+		t.Fatalf("expected %s got %s", actualURL, loadedURL)
+	}
+	if _, err := os.Stat(filepath.Join(dir, path.Base(actualURL))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,149 @@
+package commonjs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// A Module may implement PreludeExtender to declare the prelude extensions it
+// needs. The extensions are looked up in App.PreludeExtensions.
+type PreludeExtender interface {
+	PreludeExtensions() []string
+}
+
+type extenderModule struct {
+	Module
+	extensions []string
+}
+
+// Wraps another module and declares the prelude extensions it needs.
+func NewPreludeExtenderModule(m Module, extensions ...string) Module {
+	return &extenderModule{
+		Module:     m,
+		extensions: extensions,
+	}
+}
+
+func (m *extenderModule) PreludeExtensions() []string {
+	return m.extensions
+}
+
+// Provides the Prelude along with the PreludeExtensions needed by the given
+// modules and their dependencies, with Transform applied. This allows for
+// loader features to only be included on pages that need them. The HMR runtime
+// and live reload script are included when HotReload or LiveReload are
+// enabled.
+func (a *App) BundlePrelude(modules []string) ([]byte, error) {
+	prelude, err := a.bundlePrelude(modules)
+	if err != nil || !(a.HotReload || a.LiveReload) {
+		return prelude, err
+	}
+	prelude = append([]byte(nil), prelude...)
+	if a.HotReload {
+		prelude = append(prelude, a.hmrRuntime()...)
+	}
+	if a.LiveReload {
+		prelude = append(prelude, a.liveReloadScript()...)
+	}
+	return prelude, nil
+}
+
+func (a *App) bundlePrelude(modules []string) ([]byte, error) {
+	prelude, err := a.ScriptPrelude()
+	if err != nil {
+		return nil, err
+	}
+	if len(a.PreludeExtensions) == 0 {
+		return prelude, nil
+	}
+	names, err := a.neededExtensions(modules)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return prelude, nil
+	}
+
+	buf := bytes.NewBuffer(append([]byte(nil), prelude...))
+	for _, name := range names {
+		content, err := a.preludeExtension(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(content)
+	}
+	return buf.Bytes(), nil
+}
+
+// Returns the sorted names of the prelude extensions needed by the modules and
+// their dependencies. The result is cached until the packages are invalidated.
+func (a *App) neededExtensions(modules []string) ([]string, error) {
+	key := packageSpec{modules: modules}.key()
+	a.mu.Lock()
+	names, ok := a.extensionNames[key]
+	a.mu.Unlock()
+	if ok {
+		return names, nil
+	}
+
+	set := make(map[string]bool)
+	if err := a.buildDeps(modules, set); err != nil {
+		return nil, err
+	}
+	needed := make(map[string]bool)
+	for name := range set {
+		m, err := a.Module(name)
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := m.(PreludeExtender); ok {
+			for _, extension := range e.PreludeExtensions() {
+				needed[extension] = true
+			}
+		}
+	}
+	for name := range needed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a.mu.Lock()
+	if a.extensionNames == nil {
+		a.extensionNames = make(map[string][]string)
+	}
+	a.extensionNames[key] = names
+	a.mu.Unlock()
+	return names, nil
+}
+
+// Provides the named prelude extension, with Transform applied. The result is
+// cached.
+func (a *App) preludeExtension(name string) ([]byte, error) {
+	a.mu.Lock()
+	content, ok := a.extensions[name]
+	a.mu.Unlock()
+	if ok {
+		return content, nil
+	}
+	m, ok := a.PreludeExtensions[name]
+	if !ok {
+		return nil, fmt.Errorf("prelude extension %s was not found", name)
+	}
+	var err error
+	if a.Transform != nil {
+		if m, err = a.Transform.Transform(m); err != nil {
+			return nil, err
+		}
+	}
+	if content, err = m.Content(); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	if a.extensions == nil {
+		a.extensions = make(map[string][]byte)
+	}
+	a.extensions[name] = content
+	a.mu.Unlock()
+	return content, nil
+}
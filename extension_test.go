@@ -0,0 +1,86 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"github.com/daaku/go.commonjs"
+	"testing"
+)
+
+func TestBundlePrelude(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("plain", []byte("")),
+			commonjs.NewPreludeExtenderModule(
+				commonjs.NewScriptModule("styled", []byte("require('plain')")),
+				"css"),
+		},
+		PreludeExtensions: map[string]commonjs.Module{
+			"css": commonjs.NewScriptModule("css", []byte("/*css*/")),
+		},
+	}
+	prelude, err := a.ScriptPrelude()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := a.BundlePrelude([]string{"plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, prelude) {
+		t.Fatal("was expecting the plain prelude")
+	}
+	styled, err := a.BundlePrelude([]string{"styled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(styled, prelude) || !bytes.HasSuffix(styled, []byte("/*css*/")) {
+		t.Fatalf("was expecting the extended prelude, got %s", styled)
+	}
+	if bytes.HasSuffix(prelude, []byte("/*css*/")) {
+		t.Fatal("the cached prelude was modified")
+	}
+}
+
+func TestBundlePreludeMissingExtension(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewPreludeExtenderModule(
+				commonjs.NewScriptModule("foo", []byte("")), "worker"),
+		},
+		PreludeExtensions: map[string]commonjs.Module{},
+	}
+	a.PreludeExtensions["css"] = commonjs.NewScriptModule("css", nil)
+	if _, err := a.BundlePrelude([]string{"foo"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
+
+func TestBundlePreludeExtensionsCached(t *testing.T) {
+	t.Parallel()
+	counter := &countingProvider{Provider: commonjs.NewDirProvider("_test")}
+	a := &commonjs.App{
+		Providers: []commonjs.Provider{counter},
+		PreludeExtensions: map[string]commonjs.Module{
+			"css": commonjs.NewScriptModule("css", []byte("/*css*/")),
+		},
+	}
+	if _, err := a.BundlePrelude([]string{"a/foo"}); err != nil {
+		t.Fatal(err)
+	}
+	count := counter.count
+	if _, err := a.BundlePrelude([]string{"a/foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count != count {
+		t.Fatalf("was expecting cached extensions, modules were found %d more times", counter.count-count)
+	}
+	a.InvalidatePackages()
+	if _, err := a.BundlePrelude([]string{"a/foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count == count {
+		t.Fatal("was expecting the extensions to be found again after invalidating")
+	}
+}
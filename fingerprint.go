@@ -0,0 +1,51 @@
+package commonjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Transform may implement Fingerprinter to identify its configuration in
+// the BuildFingerprint, for example with the version of a minifier. Other
+// Transforms are identified by their type.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// Returns the identity of the build configuration when FingerprintBuild is
+// set, made of the OutputFormat, the prelude version, the Transform and
+// PostProcess identities and the BuildVersion. It is included in the package
+// hashes and the URL cache keys, so changing the configuration changes the
+// URLs even if the content does not, and manifests written with another
+// configuration are not used. Returns an empty string otherwise.
+func (a *App) BuildFingerprint() string {
+	if !a.FingerprintBuild {
+		return ""
+	}
+	post := make([]string, len(a.PostProcess))
+	for ix, t := range a.PostProcess {
+		post[ix] = transformFingerprint(t)
+	}
+	return fmt.Sprintf("format=%d;prelude=%d;transform=%s;postprocess=%s;version=%s",
+		a.OutputFormat, PreludeVersion, transformFingerprint(a.Transform),
+		strings.Join(post, ","), a.BuildVersion)
+}
+
+// The identity of the Transform, if any.
+func transformFingerprint(t Transform) string {
+	if t == nil {
+		return ""
+	}
+	if f, ok := t.(Fingerprinter); ok {
+		return f.Fingerprint()
+	}
+	return fmt.Sprintf("%T", t)
+}
+
+// The URL cache key for the package spec built with the fingerprint.
+func fingerprintKey(fingerprint string, spec packageSpec) string {
+	if fingerprint == "" {
+		return spec.key()
+	}
+	return "\x00fingerprint:" + fingerprint + "\x00" + spec.key()
+}
@@ -0,0 +1,96 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type versionedTransform string
+
+func (v versionedTransform) Transform(m commonjs.Module) (commonjs.Module, error) {
+	return m, nil
+}
+
+func (v versionedTransform) Fingerprint() string {
+	return "versioned-" + string(v)
+}
+
+func newFingerprintApp() *commonjs.App {
+	return &commonjs.App{
+		MountPath:        "r",
+		ContentStore:     commonjs.NewMemoryStore(),
+		Modules:          []commonjs.Module{commonjs.NewScriptModule("mname", []byte("js"))},
+		FingerprintBuild: true,
+		Transform:        versionedTransform("1"),
+		VerifyContent:    true,
+	}
+}
+
+func TestBuildFingerprint(t *testing.T) {
+	t.Parallel()
+	app := newFingerprintApp()
+	fingerprint := app.BuildFingerprint()
+	if !strings.Contains(fingerprint, "transform=versioned-1") {
+		t.Fatalf("did not find the transform in %s", fingerprint)
+	}
+	first, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == "/r/56cc634.js" {
+		t.Fatal("was expecting the fingerprint to change the hash")
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: first}, Header: http.Header{}})
+	if w.Code != 200 {
+		t.Fatalf("was expecting the fingerprinted package to verify, got %d", w.Code)
+	}
+
+	app.Transform = versionedTransform("2")
+	second, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Fatal("was expecting a new url for the new transform with the same content")
+	}
+	app.FingerprintBuild = false
+	if u, _ := app.ModulesURL([]string{"mname"}); u != "/r/56cc634.js" {
+		t.Fatalf("was expecting the content hash without fingerprinting, got %s", u)
+	}
+}
+
+func TestBuildFingerprintManifest(t *testing.T) {
+	t.Parallel()
+	before := newFingerprintApp()
+	if _, err := before.ModulesURL([]string{"mname"}); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := before.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := newFingerprintApp()
+	if err := same.LoadManifest(bytes.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := same.Manifest(); !bytes.Equal(loaded, manifest) {
+		t.Fatalf("was expecting the manifest to be loaded, got %s", loaded)
+	}
+
+	after := newFingerprintApp()
+	after.BuildVersion = "2"
+	if err := after.LoadManifest(bytes.NewReader(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := after.Manifest(); bytes.Contains(loaded, []byte(`"url"`)) {
+		t.Fatalf("was expecting the manifest for another fingerprint to be ignored, got %s", loaded)
+	}
+}
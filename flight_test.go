@@ -0,0 +1,151 @@
+package commonjs_test
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type countingModule struct {
+	commonjs.Module
+	reads *int32
+	ready chan struct{}
+}
+
+func (m *countingModule) Content() ([]byte, error) {
+	atomic.AddInt32(m.reads, 1)
+	<-m.ready
+	return m.Module.Content()
+}
+
+type missMetrics struct {
+	misses int32
+}
+
+func (m *missMetrics) PackageBuilt([]string, time.Duration, int) {}
+func (m *missMetrics) CacheHit([]string)                         {}
+func (m *missMetrics) CacheMiss([]string)                        { atomic.AddInt32(&m.misses, 1) }
+func (m *missMetrics) Served(string, int, int)                   {}
+func (m *missMetrics) NotFound(string)                           {}
+
+func TestConcurrentBuildsCoalesce(t *testing.T) {
+	t.Parallel()
+	var reads int32
+	ready := make(chan struct{})
+	metrics := &missMetrics{}
+	app := &commonjs.App{
+		Metrics:      metrics,
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			&countingModule{
+				Module: commonjs.NewScriptModule("slow", []byte("exports.slow = 1")),
+				reads:  &reads,
+				ready:  ready,
+			},
+		},
+		RequireParser: commonjs.RequireParserFunc(func([]byte) ([]string, error) {
+			return nil, nil
+		}),
+	}
+	const n = 10
+	urls := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			url, err := app.ModulesURL([]string{"slow"})
+			if err != nil {
+				t.Error(err)
+			}
+			urls[i] = url
+		}(i)
+	}
+	// wait for the first build to start reading before letting it finish
+	for atomic.LoadInt32(&reads) == 0 {
+		runtime.Gosched()
+	}
+	close(ready)
+	wg.Wait()
+	if n := atomic.LoadInt32(&metrics.misses); n != 1 {
+		t.Fatalf("was expecting a single build, got %d", n)
+	}
+	for _, url := range urls {
+		if url != urls[0] {
+			t.Fatalf("was expecting the same url, got %v", urls)
+		}
+	}
+}
+
+type panicModule struct {
+	commonjs.Module
+}
+
+func (m *panicModule) Content() ([]byte, error) {
+	panic("boom")
+}
+
+func TestPanickingBuildReleasesFlight(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			&panicModule{Module: commonjs.NewScriptModule("bad", []byte("js"))},
+		},
+		RequireParser: commonjs.RequireParserFunc(func([]byte) ([]string, error) {
+			return nil, nil
+		}),
+	}
+	for i := 0; i < 2; i++ {
+		done := make(chan interface{})
+		go func() {
+			defer func() { done <- recover() }()
+			app.ModulesURL([]string{"bad"})
+		}()
+		select {
+		case r := <-done:
+			if r == nil {
+				t.Fatal("was expecting the panic to propagate")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("build blocked on a previously panicked build")
+		}
+	}
+}
+
+func TestWaiterHonorsContext(t *testing.T) {
+	t.Parallel()
+	var reads int32
+	ready := make(chan struct{})
+	defer close(ready)
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			&countingModule{
+				Module: commonjs.NewScriptModule("slow", []byte("exports.slow = 1")),
+				reads:  &reads,
+				ready:  ready,
+			},
+		},
+		RequireParser: commonjs.RequireParserFunc(func([]byte) ([]string, error) {
+			return nil, nil
+		}),
+	}
+	go app.ModulesURL([]string{"slow"})
+	for atomic.LoadInt32(&reads) == 0 {
+		runtime.Gosched()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := app.ModulesURLContext(ctx, []string{"slow"}); err != context.DeadlineExceeded {
+		t.Fatalf("was expecting the waiter to time out, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package commonjs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The format modules are written in by the App.
+type OutputFormat int
+
+const (
+	StringFormat   OutputFormat = iota // define() calls with string payloads
+	FunctionFormat                     // define() calls with factory functions
+	AMDFormat                          // named AMD define() calls for loaders like RequireJS
+)
+
+// Provides the define() call for the module in the given format. The
+// dependencies are only used by the AMDFormat.
+func (f OutputFormat) define(name string, deps []string, content []byte) ([]byte, error) {
+	switch f {
+	case StringFormat:
+		return StringDefine(name, content), nil
+	case FunctionFormat:
+		return FunctionDefine(name, content), nil
+	case AMDFormat:
+		return AMDDefine(name, deps, content), nil
+	}
+	return nil, fmt.Errorf("unknown output format %d", f)
+}
+
+// Provides a named AMD define() call using the CommonJS wrapper, compatible
+// with loaders like RequireJS and Almond.
+func AMDDefine(name string, deps []string, content []byte) []byte {
+	out := new(bytes.Buffer)
+	tmp, _ := json.Marshal(name)
+	out.WriteString("define(")
+	out.Write(tmp)
+	tmp, _ = json.Marshal(append([]string{"require", "exports", "module"}, deps...))
+	out.WriteString(",")
+	out.Write(tmp)
+	out.WriteString(",function(require,exports,module){\n")
+	out.Write(bytes.TrimSpace(content))
+	out.WriteString("\n});\n")
+	return out.Bytes()
+}
@@ -0,0 +1,66 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestFunctionFormat(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		OutputFormat: commonjs.FunctionFormat,
+	}
+	actualURL, err := app.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	expected := "define(\"bar\",function(require,exports,module){\nbar\n});\n"
+	if w.Body.String() != expected {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		OutputFormat: commonjs.OutputFormat(42),
+	}
+	if _, err := app.ModulesURL([]string{"bar"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
+
+func TestAMDFormat(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		OutputFormat: commonjs.AMDFormat,
+	}
+	actualURL, err := app.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	expected := "define(\"a/foo\",[\"require\",\"exports\",\"module\",\"bar\",\"b/baz\"],function(require,exports,module){\nrequire('bar')\nrequire('b/baz')\n});\n"
+	if !strings.HasPrefix(w.Body.String(), expected) {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
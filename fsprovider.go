@@ -0,0 +1,40 @@
+package commonjs
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Provides modules from an io/fs.FS, such as an embed.FS. This allows for
+// modules to be compiled into the binary using go:embed.
+func NewFSProvider(fsys fs.FS) Provider {
+	return &fsProvider{read: fsReader(fsys), fsys: fsys}
+}
+
+// Provides modules from an io/fs.FS, additionally resolving names referring to
+// a directory to the main module listed in the package.json in that
+// directory, or to the index.js in that directory.
+func NewPackageFSProvider(fsys fs.FS) Provider {
+	return &fsProvider{read: fsReader(fsys), fsys: fsys, packages: true}
+}
+
+// Provides modules from an io/fs.FS, using the given wrappers for names with a
+// matching extension instead of the defaults from DefaultAssets.
+func NewAssetFSProvider(fsys fs.FS, assets map[string]AssetWrapper) Provider {
+	return &fsProvider{read: fsReader(fsys), fsys: fsys, assets: assets}
+}
+
+// Returns a function to read the named file from the io/fs.FS, returning
+// errModuleNotFound if it does not exist.
+func fsReader(fsys fs.FS) func(string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		if !fs.ValidPath(name) {
+			return nil, errModuleNotFound(name)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errModuleNotFound(name)
+		}
+		return content, err
+	}
+}
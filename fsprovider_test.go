@@ -0,0 +1,48 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSProvider(t *testing.T) {
+	t.Parallel()
+	const name = "b/baz"
+	p := commonjs.NewFSProvider(os.DirFS("_test"))
+	m, err := p.Module(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name() != name {
+		t.Fatal("did not find expected name")
+	}
+	for _, name := range []string{"xyz", "../bar", "/bar"} {
+		if _, err := p.Module(name); !commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting a not found error for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestPackageFSProvider(t *testing.T) {
+	t.Parallel()
+	p := commonjs.NewPackageFSProvider(fstest.MapFS{
+		"pkg/package.json": &fstest.MapFile{Data: []byte(`{"main":"lib"}`)},
+		"pkg/lib/index.js": &fstest.MapFile{Data: []byte("lib")},
+	})
+	m, err := p.Module("pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `module.exports = require("./pkg/lib")` {
+		t.Fatalf("did not find expected content, found %s", content)
+	}
+	if _, err := p.Module("pkg/lib"); err != nil {
+		t.Fatal(err)
+	}
+}
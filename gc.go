@@ -0,0 +1,72 @@
+package commonjs
+
+import (
+	"errors"
+	"time"
+)
+
+// A ByteStore which records when values were last stored or read, allowing
+// stale values to be removed. The stores from NewMemoryStore and NewDiskStore
+// implement it.
+type StoreCollector interface {
+	// Delete the values last accessed before the time, except for the kept
+	// keys, returning the number of deleted values.
+	GC(before time.Time, keep map[string]bool) (int, error)
+}
+
+var (
+	errGCNotSupported = errors.New("ContentStore does not support garbage collection")
+	errGCSharedStore  = errors.New("ContentStore is shared through a Mux, use Mux.GCStore")
+)
+
+// Removes the packages not accessed within the given duration from the
+// ContentStore, which must implement StoreCollector. Packages whose URLs are
+// cached by the App are kept, since they may be requested without being
+// rebuilt. Returns the number of removed values. Apps sharing the store of a
+// Mux must use Mux.GCStore instead.
+func (a *App) GCStore(olderThan time.Duration) (int, error) {
+	a.mu.Lock()
+	shared := a.sharedStore
+	a.mu.Unlock()
+	if shared {
+		return 0, errGCSharedStore
+	}
+	deleted, err := gcStore(a.ContentStore, olderThan, a.cachedKeys())
+	if err != nil {
+		return deleted, err
+	}
+	a.log(LogInfo, "removed %d stale values from the store", deleted)
+	return deleted, nil
+}
+
+// Removes the values not accessed within the given duration from the store,
+// except for the kept keys.
+func gcStore(store ByteStore, olderThan time.Duration, keep map[string]bool) (int, error) {
+	c, ok := store.(StoreCollector)
+	if !ok {
+		return 0, errGCNotSupported
+	}
+	return c.GC(time.Now().Add(-olderThan), keep)
+}
+
+// The store keys of the URLs cached by the App.
+func (a *App) cachedKeys() map[string]bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys := make(map[string]bool)
+	add := func(url string) {
+		if key, ok := a.route(url); ok {
+			keys[key] = true
+		}
+	}
+	for _, entry := range a.packageURLs {
+		add(entry.url)
+	}
+	add(a.preludeURL)
+	for _, urls := range []map[string]string{a.standaloneURLs, a.styleURLs, a.assetURLs, a.scriptURLs} {
+		for _, url := range urls {
+			add(url)
+		}
+	}
+	return keys
+}
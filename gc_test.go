@@ -0,0 +1,118 @@
+package commonjs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func testGCStore(t *testing.T, store commonjs.ByteStore) {
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: store,
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("mname", []byte("js"))},
+	}
+	if _, err := app.ModulesURL([]string{"mname"}); err != nil {
+		t.Fatal(err)
+	}
+	script, err := app.ScriptURL([]byte("execute()"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store("stale", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := app.GCStore(time.Hour)
+	if err != nil || deleted != 0 {
+		t.Fatalf("was not expecting recent values to be removed, got %d, %v", deleted, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	deleted, err = app.GCStore(time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("was expecting only the stale value to be removed, got %d", deleted)
+	}
+	if value, _ := store.Get("stale"); value != nil {
+		t.Fatal("was expecting the stale value to be removed")
+	}
+	if value, _ := store.Get("56cc634"); value == nil {
+		t.Fatal("was expecting the cached package to be kept")
+	}
+	if value, _ := store.Get(strings.TrimSuffix(path.Base(script), ".js")); value == nil {
+		t.Fatal("was expecting the cached script to be kept")
+	}
+}
+
+type countingStore struct {
+	commonjs.ByteStore
+	stores int
+}
+
+func (s *countingStore) Store(key string, value []byte) error {
+	s.stores++
+	return s.ByteStore.Store(key, value)
+}
+
+func TestScriptURLStoredOnce(t *testing.T) {
+	t.Parallel()
+	store := &countingStore{ByteStore: commonjs.NewMemoryStore()}
+	app := &commonjs.App{MountPath: "r", ContentStore: store}
+	first, err := app.ScriptURL([]byte("execute()"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := app.ScriptURL([]byte("execute()"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || store.stores != 1 {
+		t.Fatalf("was expecting a single store, got %d for %s and %s", store.stores, first, second)
+	}
+}
+
+func TestGCStoreMemory(t *testing.T) {
+	t.Parallel()
+	testGCStore(t, commonjs.NewMemoryStore())
+}
+
+func TestGCStoreDisk(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "commonjs-gc-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testGCStore(t, commonjs.NewDiskStore(dir))
+}
+
+func TestGCStoreAccess(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	if err := store.Store("k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cutoff := time.Now()
+	if _, err := store.Get("k"); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := store.(commonjs.StoreCollector).GC(cutoff, nil)
+	if err != nil || deleted != 0 {
+		t.Fatalf("was expecting the read to keep the value, got %d, %v", deleted, err)
+	}
+}
+
+func TestGCStoreNotSupported(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{ContentStore: commonjs.NewPrefixStore("p", commonjs.NewMemoryStore())}
+	if _, err := app.GCStore(time.Hour); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
@@ -0,0 +1,56 @@
+package commonjs
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Define modules for all files in the directory tree matching the pattern. The
+// pattern is matched against the slash separated path relative to the
+// directory, or against the file name if the pattern does not contain a
+// slash. Module names are the relative paths without the extension. The
+// content is read immediately, so errors are reported up front.
+func NewGlobModules(dirname, pattern string) ([]Module, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var modules []Module
+	err := filepath.Walk(dirname, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dirname, filename)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(rel)
+		}
+		if matched, _ := path.Match(pattern, target); !matched {
+			return nil
+		}
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		fileExt := path.Ext(rel)
+		modules = append(modules, &literalModule{
+			name:    strings.TrimSuffix(rel, fileExt),
+			content: content,
+			ext:     strings.TrimPrefix(fileExt, "."),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
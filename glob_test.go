@@ -0,0 +1,60 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"strings"
+	"testing"
+)
+
+func TestGlobModules(t *testing.T) {
+	t.Parallel()
+	cases := map[string][]string{
+		"a/*.js":       []string{"a/foo"},
+		"b/*.js":       []string{"b/baz"},
+		"p/*.js":       []string{"p/index", "p/q"},
+		"*.zip":        []string{"resources"},
+		"package.json": []string{"m/package", "node/node_modules/np/package"},
+		"*.coffee":     nil,
+	}
+	for pattern, expected := range cases {
+		modules, err := commonjs.NewGlobModules("_test", pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, m := range modules {
+			names = append(names, m.Name())
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Fatalf("for %s expected %v got %v", pattern, expected, names)
+		}
+	}
+}
+
+func TestGlobModulesContent(t *testing.T) {
+	t.Parallel()
+	modules, err := commonjs.NewGlobModules("_test/b", "*.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 1 || modules[0].Name() != "baz" {
+		t.Fatalf("did not find expected modules, found %v", modules)
+	}
+	require, err := modules[0].Require()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(require) != 1 || require[0] != "bar" {
+		t.Fatalf("did not find expected require, found %v", require)
+	}
+}
+
+func TestGlobModulesErrors(t *testing.T) {
+	t.Parallel()
+	if _, err := commonjs.NewGlobModules("_test", "["); err == nil {
+		t.Fatal("was expecting a bad pattern error")
+	}
+	if _, err := commonjs.NewGlobModules("_test/nonexistent", "*.js"); err == nil {
+		t.Fatal("was expecting a missing directory error")
+	}
+}
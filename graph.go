@@ -0,0 +1,225 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A module in the dependency graph.
+type GraphNode struct {
+	Name   string `json:"name"`
+	Origin string `json:"origin,omitempty"`
+	Size   int    `json:"size"` // size of the content in bytes before the Transform, 0 for inline only modules
+}
+
+// A require() call in the dependency graph.
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`      // resolved and aliased name of the required module
+	Require string `json:"require"` // name as written in the require() call
+	Line    int    `json:"line"`    // 1 based line of the first require() call, 0 if unknown
+}
+
+// A machine-readable dependency graph, sorted by name for predictable output.
+type GraphData struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Returns the dependency graph of the given modules including their
+// transitive dependencies, or of all the modules listed by ModuleNames if none
+// are given.
+func (a *App) GraphData(modules []string) (*GraphData, error) {
+	if len(modules) == 0 {
+		var err error
+		if modules, err = a.ModuleNames(); err != nil {
+			return nil, err
+		}
+	}
+	set := make(map[string]bool)
+	if err := a.buildDeps(modules, set); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	g := &GraphData{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, name := range names {
+		m, p, err := a.find(name)
+		if err != nil {
+			return nil, err
+		}
+		var content []byte
+		if !isInlineOnly(m) {
+			if content, err = m.Content(); err != nil {
+				return nil, err
+			}
+		}
+		g.Nodes = append(g.Nodes, GraphNode{
+			Name:   name,
+			Origin: moduleOrigin(m, p),
+			Size:   len(content),
+		})
+		require, err := a.require(context.Background(), m)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range require {
+			g.Edges = append(g.Edges, GraphEdge{
+				From:    name,
+				To:      a.Alias(ResolveName(name, raw)),
+				Require: raw,
+				Line:    requireLine(content, raw),
+			})
+		}
+	}
+	return g, nil
+}
+
+// The 1 based line of the first require() call for the name, or 0 if it is
+// not found, like for a custom RequireParser.
+func requireLine(content []byte, name string) int {
+	for _, loc := range reFunCall.FindAllSubmatchIndex(content, -1) {
+		if string(content[loc[2]:loc[3]]) == name {
+			return bytes.Count(content[:loc[0]], []byte("\n")) + 1
+		}
+	}
+	return 0
+}
+
+// Returns the dependency graph as JSON. See GraphData.
+func (a *App) GraphJSON(modules []string) ([]byte, error) {
+	g, err := a.GraphData(modules)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// Returns the dependency graph in the Graphviz DOT language, with the module
+// sizes in the node labels and the require sites in the edge labels. See
+// GraphData.
+func (a *App) GraphDOT(modules []string) ([]byte, error) {
+	g, err := a.GraphData(modules)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteString("digraph modules {\n")
+	out.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&out, "  %s [label=%s];\n",
+			dotQuote(n.Name), dotQuote(fmt.Sprintf("%s\n%d bytes", n.Name, n.Size)))
+	}
+	for _, e := range g.Edges {
+		label := e.Require
+		if e.Line > 0 {
+			label += fmt.Sprintf(":%d", e.Line)
+		}
+		fmt.Fprintf(&out, "  %s -> %s [label=%s];\n",
+			dotQuote(e.From), dotQuote(e.To), dotQuote(label))
+	}
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+// Quotes the string as a DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// The page visualizing the graph.json debug endpoint, laying out the modules
+// in columns by their depth from the requested modules, with the node height
+// showing their size.
+var graphHTML = []byte(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>module graph</title>
+<style>
+body { font: 12px sans-serif; margin: 20px; }
+rect { fill: #cde; stroke: #678; }
+line { stroke: #aaa; }
+text { pointer-events: none; }
+g:hover rect { fill: #fc8; }
+</style>
+</head>
+<body>
+<svg id="graph"></svg>
+<script>
+(function() {
+  var svg = document.getElementById('graph'),
+      ns = 'http://www.w3.org/2000/svg';
+
+  function el(name, attrs, parent) {
+    var e = document.createElementNS(ns, name);
+    for (var k in attrs) {
+      e.setAttribute(k, attrs[k]);
+    }
+    parent.appendChild(e);
+    return e;
+  }
+
+  fetch('graph.json' + window.location.search).then(function(r) {
+    return r.json();
+  }).then(function(g) {
+    var depth = {}, incoming = {}, columns = [], pos = {}, max = 1;
+    g.edges.forEach(function(e) { incoming[e.to] = true; });
+    g.nodes.forEach(function(n) {
+      max = Math.max(max, n.size);
+      if (!incoming[n.name]) {
+        depth[n.name] = 0;
+      }
+    });
+    for (var changed = true, rounds = 0; changed && rounds < g.nodes.length; rounds++) {
+      changed = false;
+      g.edges.forEach(function(e) {
+        if (e.from in depth && !(depth[e.to] >= depth[e.from] + 1)) {
+          depth[e.to] = depth[e.from] + 1;
+          changed = true;
+        }
+      });
+    }
+    g.nodes.forEach(function(n) {
+      var d = depth[n.name] || 0;
+      (columns[d] = columns[d] || []).push(n);
+    });
+    var height = 0;
+    columns.forEach(function(column, x) {
+      var y = 10;
+      column.forEach(function(n) {
+        var h = 20 + Math.round(40 * n.size / max);
+        pos[n.name] = { x: 10 + x * 240, y: y, h: h };
+        y += h + 10;
+      });
+      height = Math.max(height, y);
+    });
+    svg.setAttribute('width', columns.length * 240 + 20);
+    svg.setAttribute('height', height + 10);
+    g.edges.forEach(function(e) {
+      var f = pos[e.from], t = pos[e.to];
+      el('line', { x1: f.x + 200, y1: f.y + f.h / 2, x2: t.x, y2: t.y + t.h / 2 }, svg);
+    });
+    g.nodes.forEach(function(n) {
+      var p = pos[n.name],
+          group = el('g', {}, svg);
+      el('title', {}, group).textContent = n.name + ' (' + n.size + ' bytes)\n' + (n.origin || '');
+      el('rect', { x: p.x, y: p.y, width: 200, height: p.h }, group);
+      el('text', { x: p.x + 4, y: p.y + 14 }, group).textContent = n.name;
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`)
@@ -0,0 +1,103 @@
+package commonjs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func newGraphApp() *commonjs.App {
+	return &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("app/page", []byte("var a = 1;\nrequire('./widget');\nrequire('lib')")),
+			commonjs.NewScriptModule("app/widget", []byte("js")),
+			commonjs.NewScriptModule("vendor/lib", []byte("library")),
+			commonjs.NewScriptModule("other", []byte("")),
+		},
+		Aliases:   map[string]string{"lib": "vendor/lib"},
+		DebugAuth: func(r *http.Request) bool { return true },
+	}
+}
+
+func TestGraphJSON(t *testing.T) {
+	t.Parallel()
+	app := newGraphApp()
+	out, err := app.GraphJSON([]string{"app/page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g commonjs.GraphData
+	if err := json.Unmarshal(out, &g); err != nil {
+		t.Fatal(err)
+	}
+	expected := commonjs.GraphData{
+		Nodes: []commonjs.GraphNode{
+			{Name: "app/page", Origin: "App.Modules", Size: 46},
+			{Name: "app/widget", Origin: "App.Modules", Size: 2},
+			{Name: "vendor/lib", Origin: "App.Modules", Size: 7},
+		},
+		Edges: []commonjs.GraphEdge{
+			{From: "app/page", To: "app/widget", Require: "./widget", Line: 2},
+			{From: "app/page", To: "vendor/lib", Require: "lib", Line: 3},
+		},
+	}
+	if !reflect.DeepEqual(g, expected) {
+		t.Fatalf("expected %+v got %+v", expected, g)
+	}
+
+	all, err := app.GraphData(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Nodes) != 4 {
+		t.Fatalf("was expecting all the modules, got %+v", all.Nodes)
+	}
+}
+
+func TestGraphDOT(t *testing.T) {
+	t.Parallel()
+	app := newGraphApp()
+	out, err := app.GraphDOT([]string{"app/page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{
+		"digraph modules {",
+		`"vendor/lib" [label="vendor/lib\n7 bytes"];`,
+		`"app/page" -> "vendor/lib" [label="lib:3"];`,
+	} {
+		if !strings.Contains(string(out), e) {
+			println(string(out))
+			t.Fatalf("did not find %s", e)
+		}
+	}
+}
+
+func TestGraphDebugEndpoints(t *testing.T) {
+	t.Parallel()
+	app := newGraphApp()
+	for path, contentType := range map[string]string{
+		"/r/_debug/graph.json": "application/json",
+		"/r/_debug/graph.dot":  "text/vnd.graphviz",
+		"/r/_debug/graph.html": "text/html; charset=utf-8",
+	} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, &http.Request{
+			Method: "GET",
+			URL:    &url.URL{Path: path, RawQuery: "m=app/page"},
+			Header: http.Header{},
+		})
+		if w.Code != 200 || w.Header().Get("Content-Type") != contentType {
+			println(w.Body.String())
+			t.Fatalf("unexpected response %d %s for %s", w.Code, w.Header().Get("Content-Type"), path)
+		}
+	}
+}
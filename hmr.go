@@ -0,0 +1,101 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+)
+
+// The path under the MountPath streaming module updates when HotReload is
+// enabled.
+const HMRPath = "_hmr"
+
+var hmrRuntime = []byte(`
+(function(exports) {
+  var require = exports.require,
+      _hot = {};
+
+  require.init = function(m) {
+    var h = _hot[m.name] = { accept: false, dispose: [] };
+    m.hot = {
+      accept: function(cb) { h.accept = cb || true; },
+      dispose: function(cb) { h.dispose.push(cb); }
+    };
+  };
+
+  // replaces the module, returning false if a reload is needed
+  function update(name, payload) {
+    var m = require.redefine(name, payload);
+    if (!m) {
+      return true;
+    }
+    var h = _hot[name];
+    if (!h || !h.accept) {
+      return false;
+    }
+    for (var i=0, l=h.dispose.length; i<l; i++) {
+      h.dispose[i](m);
+    }
+    var accept = h.accept,
+        updated = require(name);
+    if (typeof accept === 'function') {
+      accept(updated);
+    }
+    return true;
+  }
+
+  var source = new EventSource(HMR_URL);
+  source.addEventListener('update', function(e) {
+    var data = JSON.parse(e.data),
+        ok = true;
+    new Function('define', data.code)(function() {
+      var a = arguments;
+      ok = update(a[0], a[a.length-1]) && ok;
+    });
+    if (!ok) {
+      window.location.reload();
+    }
+  });
+})(this);
+`)
+
+// Returns the URL streaming module updates when HotReload is enabled.
+func (a *App) HMRURL() string {
+	return path.Join("/", a.MountPath, HMRPath)
+}
+
+// The HMR runtime, which provides module.hot.accept(cb) and
+// module.hot.dispose(cb) and re-executes updated modules that accept updates.
+// Updates to executed modules which do not accept them reload the page.
+func (a *App) hmrRuntime() []byte {
+	url, _ := json.Marshal(a.HMRURL())
+	return bytes.Replace(hmrRuntime, []byte("HMR_URL"), url, 1)
+}
+
+type hmrEvent struct {
+	Modules []string `json:"modules"`
+	Code    string   `json:"code"`
+}
+
+// The update event for the changed modules, with the define() calls for those
+// which still exist.
+func (a *App) hmrUpdate(names []string) ([]byte, error) {
+	b, err := a.buildLimiter().start(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer b.done()
+	var code bytes.Buffer
+	for _, name := range names {
+		define, _, err := a.define(name, b)
+		if err != nil {
+			if IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		code.Write(define)
+	}
+	return json.Marshal(hmrEvent{Modules: names, Code: code.String()})
+}
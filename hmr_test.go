@@ -0,0 +1,103 @@
+package commonjs_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestHotReload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.js")
+	if err := ioutil.WriteFile(filename, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider(dir)},
+		ContentStore: commonjs.NewMemoryStore(),
+		HotReload:    true,
+	}
+	w, err := app.Watch(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close(context.Background())
+
+	prelude, err := app.BundlePrelude([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(prelude, []byte(`new EventSource("/r/_hmr")`)) {
+		println(string(prelude))
+		t.Fatal("did not find the HMR runtime in the prelude above")
+	}
+
+	s := httptest.NewServer(app)
+	defer s.Close()
+	res, err := http.Get(s.URL + app.HMRURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+	r := bufio.NewReader(res.Body)
+	if line, _ := r.ReadString('\n'); line != ": watching\n" {
+		t.Fatalf("unexpected first line %q", line)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Check(); err != nil {
+		t.Fatal(err)
+	}
+	var data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+			break
+		}
+	}
+	var event struct {
+		Modules []string
+		Code    string
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Code != "define(\"a\",\"changed\");\n" {
+		t.Fatalf("unexpected code %q", event.Code)
+	}
+}
+
+func TestHotReloadNotWatching(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		HotReload:    true,
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/r/_hmr", nil))
+	if w.Code != 404 {
+		t.Fatalf("was expecting 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package commonjs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The manifest listing the names of the modules available from a HTTP
+// Provider.
+const httpManifest = "modules.json"
+
+type httpProvider struct {
+	baseURL string
+	mu      sync.Mutex
+	loaded  bool
+	names   map[string]bool
+	modules map[string]Module
+	missing map[string]bool
+	fetches map[string]*httpFetch
+}
+
+// A fetch in progress, shared by the lookups waiting for it.
+type httpFetch struct {
+	done chan struct{}
+	m    Module
+	err  error
+}
+
+// Provides modules from a HTTP server, where a module is available at
+// baseURL/<name>.js. If the server provides a JSON array of module names at
+// baseURL/modules.json, only the names listed will be requested. Fetched
+// modules, and the names that were not found, are cached in memory.
+func NewHTTPProvider(baseURL string) Provider {
+	return &httpProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		modules: make(map[string]Module),
+		missing: make(map[string]bool),
+		fetches: make(map[string]*httpFetch),
+	}
+}
+
+func (p *httpProvider) Module(name string) (Module, error) {
+	return p.ModuleContext(context.Background(), name)
+}
+
+func (p *httpProvider) ModuleContext(ctx context.Context, name string) (Module, error) {
+	if err := p.loadManifest(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	m, ok := p.modules[name]
+	missing := p.missing[name] || (p.names != nil && !p.names[name])
+	p.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+	if missing {
+		return nil, errModuleNotFound(name)
+	}
+	return p.once(ctx, name+ext, func() (Module, error) {
+		content, err := p.get(ctx, name+ext)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if IsNotFound(err) {
+			p.missing[name] = true
+			return nil, errModuleNotFound(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m := NewOriginModule(NewScriptModule(name, content), p.url(name+ext))
+		p.modules[name] = m
+		return m, nil
+	})
+}
+
+// Load the manifest if necessary. A missing manifest means all names will be
+// requested.
+func (p *httpProvider) loadManifest(ctx context.Context) error {
+	p.mu.Lock()
+	loaded := p.loaded
+	p.mu.Unlock()
+	if loaded {
+		return nil
+	}
+	_, err := p.once(ctx, httpManifest, func() (Module, error) {
+		content, err := p.get(ctx, httpManifest)
+		if err != nil && !IsNotFound(err) {
+			return nil, err
+		}
+		var names map[string]bool
+		if err == nil {
+			var list []string
+			if err := json.Unmarshal(content, &list); err != nil {
+				return nil, fmt.Errorf("invalid manifest %s/%s: %s", p.baseURL, httpManifest, err)
+			}
+			names = make(map[string]bool)
+			for _, name := range list {
+				names[name] = true
+			}
+		}
+		p.mu.Lock()
+		p.names = names
+		p.loaded = true
+		p.mu.Unlock()
+		return nil, nil
+	})
+	return err
+}
+
+// Runs the fetch for the file unless one is already in progress, in which case
+// its result is shared. The lock is not held during the fetch, so lookups for
+// other names are not blocked by it.
+func (p *httpProvider) once(ctx context.Context, filename string, fetch func() (Module, error)) (Module, error) {
+	p.mu.Lock()
+	f := p.fetches[filename]
+	if f == nil {
+		f = &httpFetch{done: make(chan struct{})}
+		p.fetches[filename] = f
+		p.mu.Unlock()
+		f.m, f.err = fetch()
+		p.mu.Lock()
+		delete(p.fetches, filename)
+		close(f.done)
+	}
+	p.mu.Unlock()
+	select {
+	case <-f.done:
+		return f.m, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// The URL for the named file.
+func (p *httpProvider) url(filename string) string {
+	return p.baseURL + "/" + filename
+}
+
+// Fetch the given file, returning errModuleNotFound for a 404 response.
+func (p *httpProvider) get(ctx context.Context, filename string) ([]byte, error) {
+	url := p.url(filename)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errModuleNotFound(filename)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, url)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
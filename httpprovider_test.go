@@ -0,0 +1,115 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/r/modules.json":
+			w.Write([]byte(`["foo","bar"]`))
+		case "/r/foo.js":
+			w.Write([]byte("require('bar')"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	p := commonjs.NewHTTPProvider(s.URL + "/r/")
+	for i := 0; i < 2; i++ {
+		m, err := p.Module("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "require('bar')" {
+			t.Fatalf("did not find expected content, found %s", content)
+		}
+	}
+	if _, err := p.Module("baz"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error for an unlisted name, got %v", err)
+	}
+	if _, err := p.Module("bar"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error for a missing file, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("was expecting 3 requests, got %d", n)
+	}
+}
+
+func TestHTTPProviderWithoutManifest(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.FileServer(http.Dir("_test")))
+	defer s.Close()
+	p := commonjs.NewHTTPProvider(s.URL)
+	if _, err := p.Module("b/baz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Module("xyz"); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestHTTPProviderConcurrent(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/fast.js":
+			w.Write([]byte("fast"))
+		case "/slow.js":
+			<-release
+			w.Write([]byte("slow"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	defer close(release)
+
+	p := commonjs.NewHTTPProvider(s.URL)
+	if _, err := p.Module("fast"); err != nil {
+		t.Fatal(err)
+	}
+	go p.Module("slow")
+	for atomic.LoadInt32(&requests) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := p.Module("fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a cached lookup was blocked by a fetch in progress")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Module("missing"); !commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting a not found error, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Fatalf("was expecting the missing name to be requested once, got %d requests", n)
+	}
+}
@@ -0,0 +1,95 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// A Module may implement LocalizedModule to provide different content for
+// each locale. The App uses the locale requested via ModulesURLForLocale, or
+// the DefaultLocale.
+type LocalizedModule interface {
+	Module
+	LocaleContent(locale string) ([]byte, error)
+}
+
+type i18nModule struct {
+	name    string
+	bundles map[string]map[string]string
+}
+
+// Define a module exporting translated strings, with the bundles keyed by
+// locale. A locale like "fr-CA" falls back to "fr" when it has no bundle, and
+// to no strings if neither have one.
+func NewI18nModule(name string, bundles map[string]map[string]string) Module {
+	return &i18nModule{
+		name:    name,
+		bundles: bundles,
+	}
+}
+
+func (m *i18nModule) Name() string {
+	return m.name
+}
+
+// The content without a locale exports no strings.
+func (m *i18nModule) Content() ([]byte, error) {
+	return m.LocaleContent("")
+}
+
+func (m *i18nModule) LocaleContent(locale string) ([]byte, error) {
+	bundle := m.bundle(locale)
+	if bundle == nil {
+		bundle = map[string]string{}
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("module.exports=")
+	if err := json.NewEncoder(buf).Encode(bundle); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// The bundle for the locale, falling back to the language of the locale.
+func (m *i18nModule) bundle(locale string) map[string]string {
+	if bundle, ok := m.bundles[locale]; ok {
+		return bundle
+	}
+	if ix := strings.IndexAny(locale, "-_"); ix > 0 {
+		return m.bundles[locale[:ix]]
+	}
+	return nil
+}
+
+func (m *i18nModule) Require() ([]string, error) {
+	return nil, nil
+}
+
+func (m *i18nModule) Ext() string {
+	return jsExt
+}
+
+// Returns a URL for a given set of modules, where the LocalizedModules use the
+// given locale. The URL differs from that of other locales when the content
+// does, as packages are addressed by the hash of their content.
+func (a *App) ModulesURLForLocale(locale string, modules []string) (string, error) {
+	exclude, err := a.vendorSet()
+	if err != nil {
+		return "", err
+	}
+	return a.packageURL(context.Background(), packageSpec{modules: modules, locale: locale}, exclude)
+}
+
+// Provides a module with the content for the locale, or the DefaultLocale.
+func (a *App) localize(m LocalizedModule, locale string) (Module, error) {
+	if locale == "" {
+		locale = a.DefaultLocale
+	}
+	content, err := m.LocaleContent(locale)
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptModule(m.Name(), content), nil
+}
@@ -0,0 +1,56 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestModulesURLForLocale(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewI18nModule("strings", map[string]map[string]string{
+				"en": {"hello": "Hello"},
+				"fr": {"hello": "Bonjour"},
+			}),
+			commonjs.NewScriptModule("page", []byte("require('strings')")),
+		},
+		ContentStore:  commonjs.NewMemoryStore(),
+		DefaultLocale: "en",
+	}
+	cases := map[string]string{
+		"":      "Hello",
+		"fr":    "Bonjour",
+		"fr-CA": "Bonjour",
+		"de":    "{}",
+	}
+	urls := make(map[string]bool)
+	for locale, expected := range cases {
+		var actualURL string
+		var err error
+		if locale == "" {
+			actualURL, err = app.ModulesURL([]string{"page"})
+		} else {
+			actualURL, err = app.ModulesURLForLocale(locale, []string{"page"})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls[actualURL] = true
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+		if !strings.Contains(w.Body.String(), expected) {
+			println(w.Body.String())
+			t.Fatalf("for locale %q did not find %s in content above", locale, expected)
+		}
+	}
+	if len(urls) != 3 {
+		t.Fatalf("was expecting 3 distinct urls, got %v", urls)
+	}
+}
@@ -0,0 +1,132 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"sort"
+)
+
+// The modules provided inline for some inline modules, cached like the
+// package URLs until the packages are invalidated or the Vendor changes.
+type inlineEntry struct {
+	names      []string // sorted
+	contextual bool     // includes InlineOnly modules, built with the context
+	defines    []byte   // cached unless contextual
+}
+
+func (a *App) inlineEntry(inline []string) (*inlineEntry, error) {
+	vendor, err := a.vendorSet()
+	if err != nil {
+		return nil, err
+	}
+	key := packageSpec{inline: inline}.key()
+	a.mu.Lock()
+	entry := a.inlineEntries[key]
+	a.mu.Unlock()
+	if entry != nil {
+		return entry, nil
+	}
+
+	set := make(map[string]bool)
+	for name := range vendor {
+		set[name] = true
+	}
+	inlineOnly := make(map[string]bool)
+	if err := a.buildDepsFrom(context.Background(), nil, inline, set, inlineOnly); err != nil {
+		return nil, err
+	}
+	entry = &inlineEntry{contextual: len(inlineOnly) > 0}
+	for name := range set {
+		if !vendor[name] {
+			entry.names = append(entry.names, name)
+		}
+	}
+	sort.Strings(entry.names)
+
+	a.mu.Lock()
+	if a.inlineEntries == nil {
+		a.inlineEntries = make(map[string]*inlineEntry)
+	}
+	a.inlineEntries[key] = entry
+	a.mu.Unlock()
+	return entry, nil
+}
+
+// The set of modules provided inline, which are the given modules and their
+// dependencies excluding the Vendor modules.
+func (a *App) inlineSet(inline []string) (map[string]bool, error) {
+	entry, err := a.inlineEntry(inline)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	for _, name := range entry.names {
+		set[name] = true
+	}
+	return set, nil
+}
+
+// Returns the define() calls for the modules and their dependencies, excluding
+// the Vendor modules, for embedding in an inline script. This avoids a round
+// trip for small modules needed immediately. The package for the remaining
+// modules is provided by ModulesURLInline.
+func (a *App) InlineDefines(inline []string) ([]byte, error) {
+	return a.InlineDefinesContext(context.Background(), inline)
+}
+
+// Returns the define() calls like InlineDefines, using the context to build
+// the modules. InlineOnly modules like those from NewLazyJSONModule receive the
+// context, which allows for request specific values. The defines are cached
+// until the packages are invalidated, unless they include InlineOnly modules.
+func (a *App) InlineDefinesContext(ctx context.Context, inline []string) ([]byte, error) {
+	entry, err := a.inlineEntry(inline)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defines := entry.defines
+	a.mu.Unlock()
+	if defines != nil {
+		return append([]byte(nil), defines...), nil
+	}
+
+	b, err := a.buildLimiter().start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer b.done()
+	out := new(bytes.Buffer)
+	for _, name := range entry.names {
+		define, _, err := a.define(name, b)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(define)
+	}
+	if !entry.contextual {
+		a.mu.Lock()
+		entry.defines = append([]byte(nil), out.Bytes()...)
+		a.mu.Unlock()
+	}
+	return out.Bytes(), nil
+}
+
+// Returns a URL like ModulesURL for the package containing the modules, but
+// excluding those provided inline by InlineDefines for the inline modules.
+func (a *App) ModulesURLInline(modules []string, inline []string) (string, error) {
+	if len(inline) == 0 {
+		return a.ModulesURL(modules)
+	}
+	exclude, err := a.vendorSet()
+	if err != nil {
+		return "", err
+	}
+	set, err := a.inlineSet(inline)
+	if err != nil {
+		return "", err
+	}
+	for name := range exclude {
+		set[name] = true
+	}
+	return a.packageURL(context.Background(), packageSpec{modules: modules, inline: inline}, set)
+}
@@ -0,0 +1,80 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestInline(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: store,
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("critical", []byte("require('util')")),
+			commonjs.NewScriptModule("util", []byte("exports.util = 1")),
+			commonjs.NewScriptModule("page", []byte("require('util')")),
+		},
+	}
+	defines, err := app.InlineDefines([]string{"critical"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(defines, []byte(`define("critical"`)) ||
+		!bytes.Contains(defines, []byte(`define("util"`)) {
+		t.Fatalf("did not find expected defines, found %s", defines)
+	}
+
+	url, err := app.ModulesURLInline([]string{"critical", "page"}, []string{"critical"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := app.ModulesURL([]string{"critical", "page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url == full {
+		t.Fatal("was expecting a different package for inline modules")
+	}
+	content, err := store.Get(url[len("/r/") : len(url)-len(".js")])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte(`define("page"`)) ||
+		bytes.Contains(content, []byte(`define("util"`)) ||
+		bytes.Contains(content, []byte(`define("critical"`)) {
+		t.Fatalf("did not find expected package content, found %s", content)
+	}
+}
+
+func TestInlineDefinesCached(t *testing.T) {
+	t.Parallel()
+	counter := &countingProvider{Provider: commonjs.NewDirProvider("_test")}
+	app := &commonjs.App{Providers: []commonjs.Provider{counter}}
+	first, err := app.InlineDefines([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := counter.count
+	second, err := app.InlineDefines([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("was expecting the same defines, got %s and %s", first, second)
+	}
+	if counter.count != count {
+		t.Fatalf("was expecting cached defines, modules were found %d more times", counter.count-count)
+	}
+
+	app.InvalidatePackages()
+	if _, err := app.InlineDefines([]string{"a/foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count == count {
+		t.Fatal("was expecting the defines to be rebuilt after invalidating")
+	}
+}
@@ -0,0 +1,85 @@
+package commonjs
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// A Module backed by a file may implement FileModule to expose the path to the
+// file.
+type FileModule interface {
+	Module
+	Filename() string
+}
+
+func (m *fileModule) Filename() string {
+	return m.path
+}
+
+// Modules wrapping another module implement unwrapper to provide access to the
+// wrapped module.
+type unwrapper interface {
+	unwrap() Module
+}
+
+func (m *wrapModule) unwrap() Module     { return m.Module }
+func (m *parserModule) unwrap() Module   { return m.Module }
+func (m *extenderModule) unwrap() Module { return m.Module }
+func (m *renamedModule) unwrap() Module  { return m.Module }
+
+// Returns the file backing a module, looking through wrapped modules.
+func moduleFilename(m Module) string {
+	for {
+		if f, ok := m.(FileModule); ok {
+			return f.Filename()
+		}
+		w, ok := m.(unwrapper)
+		if !ok {
+			return ""
+		}
+		m = w.unwrap()
+	}
+}
+
+type jsConfig struct {
+	CompilerOptions struct {
+		BaseURL string              `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// Returns a jsconfig.json or tsconfig.json style configuration mapping the
+// names of the given modules and their dependencies to the absolute paths of
+// the files backing them. This allows editors to resolve require() calls.
+// Modules not backed by files are omitted.
+func (a *App) JSConfig(modules []string) ([]byte, error) {
+	set := make(map[string]bool)
+	if err := a.buildDeps(modules, set); err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var c jsConfig
+	c.CompilerOptions.BaseURL = "."
+	c.CompilerOptions.Paths = make(map[string][]string)
+	for _, name := range names {
+		m, err := a.Module(name)
+		if err != nil {
+			return nil, err
+		}
+		filename := moduleFilename(m)
+		if filename == "" {
+			continue
+		}
+		if filename, err = filepath.Abs(filename); err != nil {
+			return nil, err
+		}
+		c.CompilerOptions.Paths[name] = []string{filename}
+	}
+	return json.MarshalIndent(c, "", "  ")
+}
@@ -0,0 +1,44 @@
+package commonjs_test
+
+import (
+	"encoding/json"
+	"github.com/daaku/go.commonjs"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSConfig(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		Providers: []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("foo", []byte("require('a/foo')")),
+		},
+	}
+	out, err := a.JSConfig([]string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c struct {
+		CompilerOptions struct {
+			Paths map[string][]string
+		}
+	}
+	if err := json.Unmarshal(out, &c); err != nil {
+		t.Fatal(err)
+	}
+	paths := c.CompilerOptions.Paths
+	if len(paths) != 3 {
+		t.Fatalf("was expecting 3 paths, got %v", paths)
+	}
+	expected, err := filepath.Abs("_test/b/baz.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths["b/baz"]) != 1 || paths["b/baz"][0] != expected {
+		t.Fatalf("did not find expected path, found %v", paths["b/baz"])
+	}
+	if _, ok := paths["foo"]; ok {
+		t.Fatal("was not expecting a path for a script module")
+	}
+}
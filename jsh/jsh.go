@@ -3,7 +3,12 @@ package jsh
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
 	"github.com/daaku/go.commonjs"
 	"github.com/daaku/go.h"
 )
@@ -15,49 +20,354 @@ type Call struct {
 	Args     []interface{} `json:"args"`
 }
 
+// A consent check gating the Calls. The Function is called with a callback
+// which it should invoke once consent has been given.
+type Consent struct {
+	Module   string
+	Function string
+}
+
+// How the external scripts are loaded.
+type Loading int
+
+const (
+	// Load the package async, or deferred where the order matters.
+	LoadAsync Loading = iota
+	// Defer all scripts, running them in order after the document is parsed.
+	LoadDefer
+	// Load all scripts blocking the parser.
+	LoadBlocking
+)
+
 // A minimal set of script blocks and efficient loading of an external package
 // file.
 type AppScripts struct {
-	App   *commonjs.App
-	Calls []Call
+	App     *commonjs.App
+	Calls   []Call
+	Consent *Consent // optional consent check gating the Calls
+	Nonce   string   // optional nonce for the script tags
+
+	// Serve the prelude and calls from an external URL instead of an inline
+	// script, for pages with a Content-Security-Policy disallowing inline
+	// scripts. Each distinct set of Calls is stored once and served publicly,
+	// so the Calls should not contain per-user data like tokens or names.
+	External bool
+
+	// Load the prelude and loader configuration from App.PreludeURL instead of
+	// including them in the script with the calls, allowing browsers to cache
+	// them across pages.
+	ExternalPrelude bool
+
+	Loading Loading // optional loading strategy, defaults to LoadAsync
+
+	// Modules, along with their dependencies, to include in the inline script
+	// instead of the package. This avoids a round trip for small modules
+	// needed immediately.
+	InlineModules []string
+
+	// Optional context used to build the inline script, like the request
+	// context. InlineOnly modules needed by the Calls, like those from
+	// NewLazyJSONModule, are always included inline and receive the context.
+	Context context.Context
+
+	shared *sharedParts // from the last Head, for the following Body
+}
+
+// The modules used by the Calls and Consent.
+func (a *AppScripts) modules() []string {
+	modules := make([]string, len(a.Calls), len(a.Calls)+1)
+	for ix, call := range a.Calls {
+		modules[ix] = a.App.Alias(call.Module)
+	}
+	if a.Consent != nil {
+		modules = append(modules, a.App.Alias(a.Consent.Module))
+	}
+	return modules
+}
+
+// The modules to include inline, which are the InlineModules along with the
+// InlineOnly modules needed by the Calls.
+func (a *AppScripts) inlineModules() ([]string, error) {
+	modules := make([]string, len(a.InlineModules))
+	for ix, name := range a.InlineModules {
+		modules[ix] = a.App.Alias(name)
+	}
+	only, err := a.App.InlineOnlyModules(a.modules())
+	if err != nil {
+		return nil, err
+	}
+	if len(only) > 0 && a.External {
+		return nil, fmt.Errorf("inline only modules %v cannot be used with External", only)
+	}
+	return append(modules, only...), nil
+}
+
+// The context for building the inline script.
+func (a *AppScripts) context() context.Context {
+	if a.Context == nil {
+		return context.Background()
+	}
+	return a.Context
+}
+
+// Pushes the scripts the AppScripts will use using HTTP/2 server push, if
+// supported by the connection, including the prelude with ExternalPrelude.
+// This should be called before writing the response.
+func (a *AppScripts) Push(w http.ResponseWriter) error {
+	var urls []string
+	if a.ExternalPrelude {
+		prelude, err := a.App.PreludeURL()
+		if err != nil {
+			return err
+		}
+		urls = append(urls, prelude)
+	}
+	vendor, err := a.App.VendorURL()
+	if err != nil {
+		return err
+	}
+	src, err := a.URL()
+	if err != nil {
+		return err
+	}
+	return commonjs.Push(w, append(urls, vendor, src)...)
+}
+
+// Returns only the URL of the package for the Calls, without rendering any
+// HTML. This is useful where inline scripts are not allowed, or where the URL
+// is needed before rendering, for example to send a preload Link header.
+func (a *AppScripts) URL() (string, error) {
+	inline, err := a.inlineModules()
+	if err != nil {
+		return "", err
+	}
+	return a.App.ModulesURLInline(a.modules(), inline)
 }
 
 func (a *AppScripts) HTML() (h.HTML, error) {
+	head, body, err := a.parts()
+	if err != nil {
+		return nil, err
+	}
+	frag := append(head, body...)
+	return &frag, nil
+}
+
+// Renders only the prelude and calls, for placing in the <head> along with
+// Body at the end of the <body>. The scripts are built once for the Head and
+// the following Body.
+func (a *AppScripts) Head() h.HTML {
+	a.shared = new(sharedParts)
+	return &appScriptsPart{scripts: a, shared: a.shared, head: true}
+}
+
+// Renders only the package scripts, for placing at the end of the <body> along
+// with Head in the <head>.
+func (a *AppScripts) Body() h.HTML {
+	shared := a.shared
+	if shared == nil {
+		shared = new(sharedParts)
+	}
+	a.shared = nil
+	return &appScriptsPart{scripts: a, shared: shared}
+}
+
+// The parts built by the first of a Head and Body pair to render.
+type sharedParts struct {
+	once       sync.Once
+	head, body h.Frag
+	err        error
+}
+
+type appScriptsPart struct {
+	scripts *AppScripts
+	shared  *sharedParts
+	head    bool
+}
+
+func (p *appScriptsPart) HTML() (h.HTML, error) {
+	s := p.shared
+	s.once.Do(func() {
+		s.head, s.body, s.err = p.scripts.parts()
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+	if p.head {
+		return &s.head, nil
+	}
+	return &s.body, nil
+}
+
+// The scripts with the prelude and calls, and the scripts for the packages.
+func (a *AppScripts) parts() (h.Frag, h.Frag, error) {
 	buf := new(bytes.Buffer)
 	var tmp []byte
 	var err error
-	modules := make([]string, len(a.Calls))
-	for ix, call := range a.Calls {
-		modules[ix] = call.Module
+	modules := a.modules()
+	for _, call := range a.Calls {
+		call.Module = a.App.Alias(call.Module)
 		buf.WriteString("execute(")
 		tmp, err = json.Marshal(call)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		buf.Write(tmp)
 		buf.WriteString(");")
 	}
 
-	prelude, err := a.App.ScriptPrelude()
+	if a.Consent != nil {
+		consent := *a.Consent
+		consent.Module = a.App.Alias(consent.Module)
+		if buf, err = consentWrap(&consent, buf.Bytes()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var prelude, config []byte
+	var preludeURL string
+	if a.ExternalPrelude {
+		if preludeURL, err = a.App.PreludeURL(); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if prelude, err = a.App.BundlePrelude(modules); err != nil {
+			return nil, nil, err
+		}
+		config = a.App.LoaderConfig()
+	}
+
+	inline, err := a.inlineModules()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	src, err := a.App.ModulesURLInline(modules, inline)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	src, err := a.App.ModulesURL(modules)
+	var defines []byte
+	if len(inline) > 0 {
+		if defines, err = a.App.InlineDefinesContext(a.context(), inline); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	vendor, err := a.App.VendorURL()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return &h.Frag{
-		&h.Script{
-			Inner: &h.Frag{
-				h.UnsafeBytes(prelude),
-				h.UnsafeBytes(buf.Bytes()),
+	script := bytes.Join(
+		[][]byte{prelude, defines, config, buf.Bytes()}, nil)
+	loading := a.loading(vendor != "")
+	var head h.Frag
+	if preludeURL != "" {
+		// The inline script needs the prelude, so it blocks unless the script
+		// is also external and deferred in order.
+		preludeLoading := ""
+		if a.External {
+			preludeLoading = loading
+		}
+		head = append(head, a.script(preludeURL, preludeLoading))
+	}
+	if a.External {
+		bootstrap, err := a.App.ScriptURL(script)
+		if err != nil {
+			return nil, nil, err
+		}
+		head = append(head, a.script(bootstrap, loading))
+	} else {
+		attrs := h.Attributes{}
+		if a.Nonce != "" {
+			attrs["nonce"] = a.Nonce
+		}
+		head = append(head,
+			&h.Node{
+				Tag:        "script",
+				Attributes: attrs,
+				Inner:      h.UnsafeBytes(script),
 			},
+		)
+	}
+
+	var body h.Frag
+	if vendor != "" {
+		body = append(body, a.script(vendor, loading))
+	}
+	body = append(body, a.script(src, loading))
+	return head, body, nil
+}
+
+// The attribute used to load the external scripts, if any. The package may
+// depend on vendor modules, and an external prelude must run first, so in those
+// cases the scripts are deferred in order instead of being loaded async.
+func (a *AppScripts) loading(vendor bool) string {
+	switch a.Loading {
+	case LoadDefer:
+		return "defer"
+	case LoadBlocking:
+		return ""
+	}
+	if vendor || a.External {
+		return "defer"
+	}
+	return "async"
+}
+
+// An external script tag loaded using the given async or defer attribute, or
+// blocking if empty.
+func (a *AppScripts) script(src, loading string) h.HTML {
+	attrs := h.Attributes{"src": src}
+	if loading != "" {
+		attrs[loading] = true
+	}
+	if a.Nonce != "" {
+		attrs["nonce"] = a.Nonce
+	}
+	return &h.Node{
+		Tag:        "script",
+		Attributes: attrs,
+	}
+}
+
+// Wraps the calls in a consent check.
+func consentWrap(c *Consent, calls []byte) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(`execute({"module":`)
+	tmp, err := json.Marshal(c.Module)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(tmp)
+	buf.WriteString(`,"fn":`)
+	if tmp, err = json.Marshal(c.Function); err != nil {
+		return nil, err
+	}
+	buf.Write(tmp)
+	buf.WriteString(`,"args":[function(){`)
+	buf.Write(calls)
+	buf.WriteString(`}]});`)
+	return buf, nil
+}
+
+// A link tag for a stylesheet combining CSS modules.
+type Styles struct {
+	App     *commonjs.App
+	Modules []string
+}
+
+func (s *Styles) HTML() (h.HTML, error) {
+	href, err := s.App.StylesURL(s.Modules)
+	if err != nil {
+		return nil, err
+	}
+	return &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":  "stylesheet",
+			"href": href,
 		},
-		&h.Script{
-			Src:   src,
-			Async: true,
-		},
+		SelfClosing: true,
 	}, nil
 }
@@ -1,9 +1,12 @@
 package jsh_test
 
 import (
+	"context"
 	"github.com/daaku/go.commonjs"
 	"github.com/daaku/go.commonjs/jsh"
 	"github.com/daaku/go.h"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -46,3 +49,378 @@ func TestSanity(t *testing.T) {
 		}
 	}
 }
+
+func TestConsent(t *testing.T) {
+	t.Parallel()
+	var (
+		app = &commonjs.App{
+			MountPath:    "r",
+			ContentStore: commonjs.NewMemoryStore(),
+			Modules: []commonjs.Module{
+				commonjs.NewScriptModule("mname", []byte("js")),
+				commonjs.NewScriptModule("consent", []byte("js")),
+			},
+		}
+		appScripts = &jsh.AppScripts{
+			App: app,
+			Calls: []jsh.Call{
+				jsh.Call{Module: "mname", Function: "fname"},
+			},
+			Consent: &jsh.Consent{Module: "consent", Function: "wait"},
+		}
+		expected = `execute({"module":"consent","fn":"wait","args":[function(){` +
+			`execute({"module":"mname","fn":"fname","args":null});}]});`
+		actualHTML, err = h.Render(appScripts)
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actualHTML, expected) {
+		println(actualHTML)
+		t.Fatalf("did not find %s", expected)
+	}
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+	var (
+		app = &commonjs.App{
+			MountPath:    "r",
+			ContentStore: commonjs.NewMemoryStore(),
+			Modules: []commonjs.Module{
+				commonjs.NewScriptModule("mname", []byte("js")),
+			},
+		}
+		appScripts = &jsh.AppScripts{
+			App:   app,
+			Calls: []jsh.Call{jsh.Call{Module: "mname", Function: "fname"}},
+		}
+	)
+	src, err := appScripts.URL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != "/r/56cc634.js" {
+		t.Fatalf("did not find expected url, found %s", src)
+	}
+}
+
+func TestStyles(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewStyleModule("main.css", []byte("body{margin:0}")),
+		},
+	}
+	actualHTML, err := h.Render(&jsh.Styles{App: app, Modules: []string{"main.css"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actualHTML, `rel="stylesheet"`) || !strings.Contains(actualHTML, ".css") {
+		println(actualHTML)
+		t.Fatal("did not find expected link")
+	}
+}
+
+func TestNonce(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	actualHTML, err := h.Render(&jsh.AppScripts{
+		App:   app,
+		Calls: []jsh.Call{{Module: "mname", Function: "fname"}},
+		Nonce: "abc123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(actualHTML, `nonce="abc123"`) != 2 {
+		println(actualHTML)
+		t.Fatal("did not find expected nonce on both scripts")
+	}
+}
+
+func TestExternal(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	actualHTML, err := h.Render(&jsh.AppScripts{
+		App:      app,
+		Calls:    []jsh.Call{{Module: "mname", Function: "fname"}},
+		External: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(actualHTML, "execute(") {
+		println(actualHTML)
+		t.Fatal("was not expecting an inline script")
+	}
+	if strings.Count(actualHTML, " defer") != 2 {
+		println(actualHTML)
+		t.Fatal("was expecting two deferred scripts")
+	}
+	if !strings.Contains(actualHTML, `src="/r/56cc634.js"`) {
+		println(actualHTML)
+		t.Fatal("did not find expected package")
+	}
+}
+
+func TestExternalPrelude(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	preludeURL, err := app.PreludeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualHTML, err := h.Render(&jsh.AppScripts{
+		App:             app,
+		Calls:           []jsh.Call{{Module: "mname", Function: "fname"}},
+		ExternalPrelude: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(actualHTML, "exports.define = define") {
+		println(actualHTML)
+		t.Fatal("was not expecting an inline prelude")
+	}
+	if !strings.Contains(actualHTML, `<script src="`+preludeURL+`"></script>`) {
+		println(actualHTML)
+		t.Fatal("did not find expected blocking prelude")
+	}
+	if strings.Index(actualHTML, preludeURL) > strings.Index(actualHTML, "execute(") {
+		println(actualHTML)
+		t.Fatal("was expecting the prelude before the calls")
+	}
+}
+
+func TestLoading(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	cases := map[jsh.Loading]string{
+		jsh.LoadAsync:    " async",
+		jsh.LoadDefer:    " defer",
+		jsh.LoadBlocking: "",
+	}
+	for loading, attr := range cases {
+		actualHTML, err := h.Render(&jsh.AppScripts{
+			App:     app,
+			Calls:   []jsh.Call{{Module: "mname", Function: "fname"}},
+			Loading: loading,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attr != "" && !strings.Contains(actualHTML, attr) {
+			println(actualHTML)
+			t.Fatalf("did not find expected attribute %s", attr)
+		}
+		if attr == "" && (strings.Contains(actualHTML, " async") || strings.Contains(actualHTML, " defer")) {
+			println(actualHTML)
+			t.Fatal("was expecting a blocking script")
+		}
+	}
+}
+
+func TestHeadBody(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:   app,
+		Calls: []jsh.Call{{Module: "mname", Function: "fname"}},
+	}
+	head, err := h.Render(appScripts.Head())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := h.Render(appScripts.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(head, "execute(") || strings.Contains(head, "56cc634.js") {
+		println(head)
+		t.Fatal("did not find expected head")
+	}
+	if strings.Contains(body, "execute(") || !strings.Contains(body, "56cc634.js") {
+		println(body)
+		t.Fatal("did not find expected body")
+	}
+}
+
+func TestHeadBodyBuiltOnce(t *testing.T) {
+	t.Parallel()
+	var builds int
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("require('user')")),
+			commonjs.NewLazyJSONModule("user", func(ctx context.Context) (interface{}, error) {
+				builds++
+				return "alice", nil
+			}, commonjs.JSONOptions{}),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:   app,
+		Calls: []jsh.Call{{Module: "mname", Function: "fname"}},
+	}
+	head, body := appScripts.Head(), appScripts.Body()
+	if _, err := h.Render(head); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Render(body); err != nil {
+		t.Fatal(err)
+	}
+	if builds != 1 {
+		t.Fatalf("was expecting the scripts to be built once, got %d", builds)
+	}
+}
+
+func TestInlineModules(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("critical", []byte("js")),
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:           app,
+		Calls:         []jsh.Call{{Module: "critical", Function: "fname"}, {Module: "mname", Function: "fname"}},
+		InlineModules: []string{"critical"},
+	}
+	head, err := h.Render(appScripts.Head())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(head, `define("critical"`) || strings.Contains(head, `define("mname"`) {
+		println(head)
+		t.Fatal("did not find expected inline module")
+	}
+}
+
+type userKey struct{}
+
+func TestInlineOnlyModules(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("require('user')")),
+			commonjs.NewLazyJSONModule("user", func(ctx context.Context) (interface{}, error) {
+				return ctx.Value(userKey{}), nil
+			}, commonjs.JSONOptions{}),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:     app,
+		Calls:   []jsh.Call{{Module: "mname", Function: "fname"}},
+		Context: context.WithValue(context.Background(), userKey{}, "alice"),
+	}
+	head, err := h.Render(appScripts.Head())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(head, `define("user","module.exports=\"alice\"")`) {
+		println(head)
+		t.Fatal("did not find expected inline only module")
+	}
+	appScripts.External = true
+	if _, err := h.Render(appScripts.Head()); err == nil {
+		t.Fatal("was expecting an error for inline only modules with External")
+	}
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestPush(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:   app,
+		Calls: []jsh.Call{{Module: "mname", Function: "fname"}},
+	}
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := appScripts.Push(w); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pushed) != 1 || w.pushed[0] != "/r/56cc634.js" {
+		t.Fatalf("did not find expected pushes, found %v", w.pushed)
+	}
+}
+
+func TestPushExternalPrelude(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:             app,
+		Calls:           []jsh.Call{{Module: "mname", Function: "fname"}},
+		ExternalPrelude: true,
+	}
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := appScripts.Push(w); err != nil {
+		t.Fatal(err)
+	}
+	prelude, err := app.PreludeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pushed) != 2 || w.pushed[0] != prelude || w.pushed[1] != "/r/56cc634.js" {
+		t.Fatalf("did not find expected pushes, found %v", w.pushed)
+	}
+}
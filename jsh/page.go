@@ -0,0 +1,68 @@
+package jsh
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/daaku/go.h"
+)
+
+// Collects the Calls from the components of a page, rendering a single prelude
+// and package for all of them. The embedded AppScripts provides the App and
+// options, and a PageScripts is typically made available to components using
+// NewContext.
+type PageScripts struct {
+	AppScripts
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// Adds the Calls, skipping those identical to one already added.
+func (p *PageScripts) Add(calls ...Call) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.seen == nil {
+		p.seen = make(map[string]bool)
+		for _, call := range p.Calls {
+			key, err := json.Marshal(call)
+			if err != nil {
+				return err
+			}
+			p.seen[string(key)] = true
+		}
+	}
+	for _, call := range calls {
+		key, err := json.Marshal(call)
+		if err != nil {
+			return err
+		}
+		if p.seen[string(key)] {
+			continue
+		}
+		p.seen[string(key)] = true
+		p.Calls = append(p.Calls, call)
+	}
+	return nil
+}
+
+func (p *PageScripts) HTML() (h.HTML, error) {
+	p.mu.Lock()
+	scripts := p.AppScripts
+	scripts.Calls = append([]Call(nil), p.Calls...)
+	p.mu.Unlock()
+	return scripts.HTML()
+}
+
+type pageScriptsKey struct{}
+
+// Returns a context carrying the PageScripts.
+func NewContext(ctx context.Context, p *PageScripts) context.Context {
+	return context.WithValue(ctx, pageScriptsKey{}, p)
+}
+
+// Returns the PageScripts from the context, or nil if there is none.
+func FromContext(ctx context.Context) *PageScripts {
+	p, _ := ctx.Value(pageScriptsKey{}).(*PageScripts)
+	return p
+}
@@ -0,0 +1,51 @@
+package jsh_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/jsh"
+	"github.com/daaku/go.h"
+)
+
+func TestPageScripts(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("a", []byte("js")),
+			commonjs.NewScriptModule("b", []byte("js")),
+		},
+	}
+	ctx := jsh.NewContext(context.Background(), &jsh.PageScripts{
+		AppScripts: jsh.AppScripts{App: app},
+	})
+	for _, module := range []string{"a", "b", "a"} {
+		err := jsh.FromContext(ctx).Add(jsh.Call{Module: module, Function: "init"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	actualHTML, err := h.Render(jsh.FromContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(actualHTML, "exports.define = define") != 1 {
+		println(actualHTML)
+		t.Fatal("was expecting a single prelude")
+	}
+	if strings.Count(actualHTML, `"fn":"init"`) != 2 {
+		println(actualHTML)
+		t.Fatal("was expecting two calls")
+	}
+	if strings.Count(actualHTML, ".js\"") != 1 {
+		println(actualHTML)
+		t.Fatal("was expecting a single package")
+	}
+	if jsh.FromContext(context.Background()) != nil {
+		t.Fatal("was not expecting PageScripts")
+	}
+}
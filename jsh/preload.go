@@ -0,0 +1,72 @@
+package jsh
+
+import (
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.h"
+)
+
+// Returns preload link tags for the packages the AppScripts will use, allowing
+// the browser to start fetching them before the script tags are parsed. This
+// is typically placed early in the <head>.
+func (a *AppScripts) Preload() h.HTML {
+	return &appScriptsPreload{scripts: a}
+}
+
+type appScriptsPreload struct {
+	scripts *AppScripts
+}
+
+func (p *appScriptsPreload) HTML() (h.HTML, error) {
+	var frag h.Frag
+	vendor, err := p.scripts.App.VendorURL()
+	if err != nil {
+		return nil, err
+	}
+	if vendor != "" {
+		frag = append(frag, link("preload", vendor))
+	}
+	src, err := p.scripts.URL()
+	if err != nil {
+		return nil, err
+	}
+	frag = append(frag, link("preload", src))
+	return &frag, nil
+}
+
+// Prefetch link tags for the packages likely needed next, for example by the
+// pages linked from the current one. The browser fetches them at a low
+// priority once the current page has loaded.
+type Prefetch struct {
+	App     *commonjs.App
+	Modules [][]string // modules for each likely package
+}
+
+func (p *Prefetch) HTML() (h.HTML, error) {
+	var frag h.Frag
+	seen := make(map[string]bool)
+	for _, modules := range p.Modules {
+		src, err := p.App.ModulesURL(modules)
+		if err != nil {
+			return nil, err
+		}
+		if seen[src] {
+			continue
+		}
+		seen[src] = true
+		frag = append(frag, link("prefetch", src))
+	}
+	return &frag, nil
+}
+
+// A link tag for a script with the given relationship.
+func link(rel, href string) h.HTML {
+	return &h.Node{
+		Tag: "link",
+		Attributes: h.Attributes{
+			"rel":  rel,
+			"as":   "script",
+			"href": href,
+		},
+		SelfClosing: true,
+	}
+}
@@ -0,0 +1,58 @@
+package jsh_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/jsh"
+	"github.com/daaku/go.h"
+)
+
+func TestPreload(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+		},
+	}
+	appScripts := &jsh.AppScripts{
+		App:   app,
+		Calls: []jsh.Call{{Module: "mname", Function: "fname"}},
+	}
+	actualHTML, err := h.Render(appScripts.Preload())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{`rel="preload"`, `as="script"`, `href="/r/56cc634.js"`} {
+		if !strings.Contains(actualHTML, e) {
+			println(actualHTML)
+			t.Fatalf("did not find %s", e)
+		}
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("mname", []byte("js")),
+			commonjs.NewScriptModule("other", []byte("js")),
+		},
+	}
+	actualHTML, err := h.Render(&jsh.Prefetch{
+		App:     app,
+		Modules: [][]string{{"mname"}, {"other"}, {"mname"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(actualHTML, `rel="prefetch"`) != 2 {
+		println(actualHTML)
+		t.Fatal("was expecting two prefetch links")
+	}
+}
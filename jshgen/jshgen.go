@@ -0,0 +1,137 @@
+// Package jshgen generates typed Go functions constructing jsh.Call values for
+// the functions exported by JavaScript modules.
+package jshgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/daaku/go.commonjs"
+)
+
+var reExport = regexp.MustCompile(
+	`(?m)^\s*(?:module\.)?exports\.([A-Za-z_$][\w$]*)\s*=\s*function\s*[\w$]*\s*\(([^)]*)\)`)
+
+// An exported JavaScript function.
+type Function struct {
+	Module string   // name of the module exporting the function
+	Name   string   // name of the exported function
+	Params []string // names of the function parameters
+}
+
+// Find the functions exported by a module. This looks for assignments of
+// function expressions to exports or module.exports properties.
+func Exports(m commonjs.Module) ([]Function, error) {
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	var functions []Function
+	for _, match := range reExport.FindAllSubmatch(content, -1) {
+		f := Function{Module: m.Name(), Name: string(match[1])}
+		for _, param := range strings.Split(string(match[2]), ",") {
+			if param = strings.TrimSpace(param); param != "" {
+				f.Params = append(f.Params, param)
+			}
+		}
+		functions = append(functions, f)
+	}
+	return functions, nil
+}
+
+// Generate Go source for the named package containing a function for each
+// function exported by the given modules. A function for the function "log"
+// in the module "app/util" is named AppUtilLog, and returns a jsh.Call.
+func Generate(w io.Writer, pkg string, modules []commonjs.Module) error {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by jshgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintf(buf, "import \"github.com/daaku/go.commonjs/jsh\"\n")
+
+	seen := make(map[string]string)
+	for _, m := range modules {
+		functions, err := Exports(m)
+		if err != nil {
+			return err
+		}
+		for _, f := range functions {
+			name := identifier(f.Module + "/" + f.Name)
+			if prev, ok := seen[name]; ok {
+				return fmt.Errorf(
+					"jshgen: %s.%s and %s both generate %s", f.Module, f.Name, prev, name)
+			}
+			seen[name] = f.Module + "." + f.Name
+			writeFunction(buf, name, f)
+		}
+	}
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func writeFunction(buf *bytes.Buffer, name string, f Function) {
+	params := make([]string, len(f.Params))
+	for ix, param := range f.Params {
+		params[ix] = parameter(param)
+	}
+	fmt.Fprintf(buf, "\n// %s calls %s in the %s module.\n", name, f.Name, f.Module)
+	fmt.Fprintf(buf, "func %s(", name)
+	if len(params) > 0 {
+		fmt.Fprintf(buf, "%s interface{}", strings.Join(params, ", "))
+	}
+	fmt.Fprintf(buf, ") jsh.Call {\n")
+	fmt.Fprintf(buf, "\treturn jsh.Call{\n")
+	fmt.Fprintf(buf, "\t\tModule: %q,\n", f.Module)
+	fmt.Fprintf(buf, "\t\tFunction: %q,\n", f.Name)
+	fmt.Fprintf(buf, "\t\tArgs: []interface{}{%s},\n", strings.Join(params, ", "))
+	fmt.Fprintf(buf, "\t}\n}\n")
+}
+
+// Converts a module and function name to an exported Go identifier.
+func identifier(s string) string {
+	var out []rune
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		out = append(out, r)
+	}
+	if len(out) == 0 || unicode.IsDigit(out[0]) {
+		out = append([]rune("X"), out...)
+	}
+	return string(out)
+}
+
+// Converts a JavaScript parameter name to a Go parameter name.
+func parameter(s string) string {
+	var out []rune
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			out = append(out, r)
+		}
+	}
+	name := string(out)
+	if name == "" || unicode.IsDigit(out[0]) {
+		name = "p" + name
+	}
+	if token.Lookup(name).IsKeyword() {
+		name += "_"
+	}
+	return name
+}
@@ -0,0 +1,67 @@
+package jshgen_test
+
+import (
+	"bytes"
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/jshgen"
+	"strings"
+	"testing"
+)
+
+func TestExports(t *testing.T) {
+	t.Parallel()
+	m := commonjs.NewScriptModule("foo", []byte(`
+exports.a = function(x, y) {}
+  module.exports.b = function named() {}
+var c = function(z) {}
+`))
+	functions, err := jshgen.Exports(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 2 {
+		t.Fatalf("was expecting 2 functions, got %v", functions)
+	}
+	if functions[0].Name != "a" || len(functions[0].Params) != 2 {
+		t.Fatalf("did not find expected function, found %v", functions[0])
+	}
+	if functions[1].Name != "b" || len(functions[1].Params) != 0 {
+		t.Fatalf("did not find expected function, found %v", functions[1])
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	modules := []commonjs.Module{
+		commonjs.NewScriptModule("app/log-util",
+			[]byte("exports.log = function(id, type) {}")),
+	}
+	buf := new(bytes.Buffer)
+	if err := jshgen.Generate(buf, "bindings", modules); err != nil {
+		t.Fatal(err)
+	}
+	expectedThings := []string{
+		"package bindings",
+		"func AppLogUtilLog(id, type_ interface{}) jsh.Call {",
+		`Module:   "app/log-util",`,
+		`Function: "log",`,
+		"Args:     []interface{}{id, type_},",
+	}
+	for _, e := range expectedThings {
+		if !strings.Contains(buf.String(), e) {
+			println(buf.String())
+			t.Fatalf("did not find %s", e)
+		}
+	}
+}
+
+func TestGenerateCollision(t *testing.T) {
+	t.Parallel()
+	modules := []commonjs.Module{
+		commonjs.NewScriptModule("a/b", []byte("exports.c = function() {}")),
+		commonjs.NewScriptModule("a", []byte("exports.b_c = function() {}")),
+	}
+	if err := jshgen.Generate(new(bytes.Buffer), "bindings", modules); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
@@ -2,9 +2,170 @@
 package jslib
 
 import (
+	"fmt"
+
 	"github.com/daaku/go.commonjs"
 )
 
+// Describes where a library is found on the CDNs. The checksums verify the
+// known-good Version, the CDNs may serve different files for the same version.
+type Library struct {
+	Package        string // package name on cdnjs
+	NPM            string // package name on npm, used with jsDelivr
+	File           string // file within the package
+	Version        string // known-good version used when none is specified
+	CDNJSSHA256    string // hex encoded SHA-256 checksum of the Version on cdnjs
+	JSDelivrSHA256 string // hex encoded SHA-256 checksum of the Version on jsDelivr
+}
+
+// Catalog of common libraries by module name.
+var Catalog = map[string]Library{
+	"jquery": {
+		Package: "jquery",
+		NPM:     "jquery",
+		File:    "jquery.min.js",
+		Version: "1.8.2",
+	},
+	"lodash": {
+		Package: "lodash.js",
+		NPM:     "lodash",
+		File:    "lodash.min.js",
+		Version: "4.17.21",
+	},
+	"underscore": {
+		Package: "underscore.js",
+		NPM:     "underscore",
+		File:    "underscore-min.js",
+		Version: "1.13.6",
+	},
+	"backbone": {
+		Package: "backbone.js",
+		NPM:     "backbone",
+		File:    "backbone-min.js",
+		Version: "1.4.1",
+	},
+	"moment": {
+		Package: "moment.js",
+		NPM:     "moment",
+		File:    "moment.min.js",
+		Version: "2.29.4",
+	},
+	"bootstrap": {
+		Package: "twitter-bootstrap",
+		NPM:     "bootstrap",
+		File:    "bootstrap.min.js",
+		Version: "2.2.2",
+	},
+	"react": {
+		Package: "react",
+		NPM:     "react",
+		File:    "umd/react.production.min.js",
+		Version: "17.0.2",
+	},
+}
+
+// Returns the Library for the name from the Catalog, or one following the
+// usual naming convention.
+func library(name string) Library {
+	if l, ok := Catalog[name]; ok {
+		return l
+	}
+	return Library{Package: name, NPM: name, File: name + ".min.js"}
+}
+
+// Returns the cdnjs URL for the named library. The known-good version from the
+// Catalog is used if version is empty.
+func CDNJSURL(name, version string) string {
+	l := library(name)
+	if version == "" {
+		version = l.Version
+	}
+	return fmt.Sprintf(
+		"https://cdnjs.cloudflare.com/ajax/libs/%s/%s/%s", l.Package, version, l.File)
+}
+
+// Returns the jsDelivr URL for the named library. The known-good version from
+// the Catalog is used if version is empty.
+func JSDelivrURL(name, version string) string {
+	l := library(name)
+	if version == "" {
+		version = l.Version
+	}
+	return fmt.Sprintf("https://cdn.jsdelivr.net/npm/%s@%s/%s", l.NPM, version, l.File)
+}
+
+// Module for the named library served from cdnjs. The known-good version from
+// the Catalog is verified against its checksum, and fails to build if the
+// Catalog has none. Other versions are not verified, use PinnedCDNJS instead.
+func CDNJS(name, version string) commonjs.Module {
+	return catalogModule(name, version, CDNJSURL(name, version), library(name).CDNJSSHA256)
+}
+
+// Module for the named library served from cdnjs, verified against the hex
+// encoded SHA-256 checksum.
+func PinnedCDNJS(name, version, sha256 string) commonjs.Module {
+	return commonjs.NewPinnedURLModule(name, CDNJSURL(name, version), sha256)
+}
+
+// Module for the named library served from jsDelivr. The known-good version
+// from the Catalog is verified like with CDNJS.
+func JSDelivr(name, version string) commonjs.Module {
+	return catalogModule(name, version, JSDelivrURL(name, version), library(name).JSDelivrSHA256)
+}
+
+// Module for the named library pulled from the URL, pinned to the checksum
+// when the version is the known-good one from the Catalog.
+func catalogModule(name, version, url, sha256 string) commonjs.Module {
+	l, ok := Catalog[name]
+	if !ok || (version != "" && version != l.Version) {
+		return commonjs.NewURLModule(name, url)
+	}
+	if sha256 == "" {
+		return &unpinnedModule{name: name, url: url}
+	}
+	return commonjs.NewPinnedURLModule(name, url, sha256)
+}
+
+// A Catalog library without a checksum for the CDN, which fails to build
+// rather than loading unverified code.
+type unpinnedModule struct {
+	name string
+	url  string
+}
+
+func (m *unpinnedModule) err() error {
+	return fmt.Errorf("no known-good checksum for %s from %s, use a Pinned module", m.name, m.url)
+}
+
+func (m *unpinnedModule) Name() string {
+	return m.name
+}
+
+func (m *unpinnedModule) Content() ([]byte, error) {
+	return nil, m.err()
+}
+
+func (m *unpinnedModule) Require() ([]string, error) {
+	return nil, m.err()
+}
+
+func (m *unpinnedModule) Ext() string {
+	return "js"
+}
+
+// Module for the named library served from jsDelivr, verified against the hex
+// encoded SHA-256 checksum.
+func PinnedJSDelivr(name, version, sha256 string) commonjs.Module {
+	return commonjs.NewPinnedURLModule(name, JSDelivrURL(name, version), sha256)
+}
+
+// Module for jQuery served from cdnjs, exporting jQuery and restoring the
+// previous value of $.
+func JQuery(version string) commonjs.Module {
+	return ShimModule(CDNJS("jquery", version), "jQuery.noConflict()")
+}
+
+// Deprecated: use JQuery("1.8.2").
 var JQuery_1_8_2 = commonjs.NewWrapModule(
 	commonjs.NewURLModule(
 		"jquery",
@@ -12,6 +173,7 @@ var JQuery_1_8_2 = commonjs.NewWrapModule(
 	nil,
 	[]byte("module.exports = jQuery.noConflict()"))
 
+// Deprecated: use CDNJS("bootstrap", "2.2.2").
 var Bootstrap_2_2_2 = commonjs.NewURLModule(
 	"bootstrap",
 	"https://cdnjs.cloudflare.com/ajax/libs/twitter-bootstrap/2.2.2/bootstrap.min.js")
@@ -2,6 +2,7 @@ package jslib_test
 
 import (
 	"github.com/daaku/go.commonjs/jslib"
+	"strings"
 	"testing"
 )
 
@@ -12,3 +13,31 @@ func TestSanity(t *testing.T) {
 		t.Fatal("did not find expected name")
 	}
 }
+
+func TestCDNJS(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		jslib.CDNJSURL("lodash", "4.17.21"): "https://cdnjs.cloudflare.com/ajax/libs/lodash.js/4.17.21/lodash.min.js",
+		jslib.CDNJSURL("moment", ""):        "https://cdnjs.cloudflare.com/ajax/libs/moment.js/2.29.4/moment.min.js",
+		jslib.CDNJSURL("foo", "1.0.0"):      "https://cdnjs.cloudflare.com/ajax/libs/foo/1.0.0/foo.min.js",
+		jslib.JSDelivrURL("react", ""):      "https://cdn.jsdelivr.net/npm/react@17.0.2/umd/react.production.min.js",
+	}
+	for actual, expected := range cases {
+		if actual != expected {
+			t.Fatalf("was expecting %s, got %s", expected, actual)
+		}
+	}
+	if jslib.JQuery("1.8.2").Name() != "jquery" {
+		t.Fatal("did not find expected name")
+	}
+}
+
+func TestCatalogVersionPinned(t *testing.T) {
+	t.Parallel()
+	for _, version := range []string{"", "4.17.21"} {
+		_, err := jslib.CDNJS("lodash", version).Content()
+		if err == nil || !strings.Contains(err.Error(), "checksum") {
+			t.Fatalf("was expecting a checksum error for version %q, got %v", version, err)
+		}
+	}
+}
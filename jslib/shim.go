@@ -0,0 +1,53 @@
+package jslib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/daaku/go.commonjs"
+)
+
+// Module for a library which exports a global rather than using CommonJS,
+// pulled from the URL. See ShimModule.
+func Shim(name, url, exportsGlobal string, deps ...string) commonjs.Module {
+	return ShimModule(commonjs.NewURLModule(name, url), exportsGlobal, deps...)
+}
+
+// Wraps a module for a library which exports a global rather than using
+// CommonJS. The library is run with module, exports and define hidden, so
+// libraries which detect a module system use their global code path.
+// The exportsGlobal expression, like "Backbone" or "jQuery.noConflict()",
+// provides the exports of the module. The deps are required before the
+// library runs, and one given as "name:global", like "underscore:_", also
+// has its exports assigned to the global. The require() calls in the library
+// itself are ignored.
+func ShimModule(m commonjs.Module, exportsGlobal string, deps ...string) commonjs.Module {
+	var prelude, postlude bytes.Buffer
+	names := make([]string, len(deps))
+	for ix, dep := range deps {
+		name, global := dep, ""
+		if i := strings.Index(dep, ":"); i != -1 {
+			name, global = dep[:i], dep[i+1:]
+		}
+		names[ix] = name
+		if global == "" {
+			fmt.Fprintf(&prelude, "require(%q);\n", name)
+		} else {
+			fmt.Fprintf(&prelude, "window.%s = require(%q);\n", global, name)
+		}
+	}
+	if exportsGlobal != "" {
+		prelude.WriteString("module.exports = ")
+	}
+	prelude.WriteString("(function(module, exports, define) {\n")
+	if exportsGlobal != "" {
+		fmt.Fprintf(&postlude, "\nreturn %s;", exportsGlobal)
+	}
+	postlude.WriteString("\n}).call(window);\n")
+	return commonjs.NewRequireParserModule(
+		commonjs.NewWrapModule(m, prelude.Bytes(), postlude.Bytes()),
+		commonjs.RequireParserFunc(func([]byte) ([]string, error) {
+			return names, nil
+		}))
+}
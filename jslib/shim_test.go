@@ -0,0 +1,39 @@
+package jslib_test
+
+import (
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/jslib"
+)
+
+func TestShim(t *testing.T) {
+	t.Parallel()
+	m := jslib.ShimModule(
+		commonjs.NewScriptModule("backbone", []byte("var Backbone = {}; require('jquery')")),
+		"Backbone",
+		"underscore:_",
+		"jquery",
+	)
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `window._ = require("underscore");
+require("jquery");
+module.exports = (function(module, exports, define) {
+var Backbone = {}; require('jquery')
+return Backbone;
+}).call(window);
+`
+	if string(content) != expected {
+		t.Fatalf("did not find expected content, found:\n%s", content)
+	}
+	require, err := m.Require()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(require) != 2 || require[0] != "underscore" || require[1] != "jquery" {
+		t.Fatalf("did not find expected require, found %v", require)
+	}
+}
@@ -0,0 +1,3 @@
+exports.html = function(s) {
+  return s.replace(/&/g, '&amp;').replace(/</g, '&lt;');
+};
@@ -0,0 +1,19 @@
+var escape = require('escape');
+
+exports.testLessThan = function(t) {
+  if (escape.html('<') !== '&lt;') {
+    t.error('did not escape <');
+  }
+};
+
+exports.testAmpersand = function(t) {
+  var actual = escape.html('a & b');
+  t.log(actual);
+  if (actual !== 'a &amp; b') {
+    t.error('did not escape &');
+  }
+};
+
+exports.helper = function() {};
+
+exports.testDisabled = null;
@@ -0,0 +1,78 @@
+// Package jstest runs tests written in JavaScript modules as Go tests.
+//
+// A test module exports functions with names starting with "test", each of
+// which is called with an object providing error(message) and log(message)
+// functions. A test fails if it calls error or throws. Modules are resolved
+// through the App, so tests may require the modules they cover:
+//
+//	var escape = require('escape');
+//	exports.testHTML = function(t) {
+//	  if (escape.html('<') !== '&lt;') {
+//	    t.error('did not escape <');
+//	  }
+//	};
+package jstest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/ssr"
+)
+
+// The prefix for the names of exported test functions.
+const TestPrefix = "test"
+
+// Runs the tests exported by the modules as subtests named module/function.
+// Each test function runs in a fresh ssr.Runtime.
+func Run(t *testing.T, app *commonjs.App, modules ...string) {
+	for _, module := range modules {
+		names, err := Tests(app, module)
+		if err != nil {
+			t.Errorf("loading tests from %s: %s", module, err)
+			continue
+		}
+		for _, name := range names {
+			module, name := module, name
+			t.Run(module+"/"+name, func(t *testing.T) {
+				runTest(t, app, module, name)
+			})
+		}
+	}
+}
+
+// Returns the sorted names of the test functions exported by the module.
+func Tests(app *commonjs.App, module string) ([]string, error) {
+	exports, err := ssr.New(app).Require(module)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := exports.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("module %s does not export an object", module)
+	}
+	var names []string
+	for name, v := range m {
+		if strings.HasPrefix(name, TestPrefix) && v != nil && reflect.TypeOf(v).Kind() == reflect.Func {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Runs a single test function, reporting calls to error and exceptions as
+// failures.
+func runTest(t *testing.T, app *commonjs.App, module, name string) {
+	helper := map[string]interface{}{
+		"error": func(message string) { t.Error(message) },
+		"log":   func(message string) { t.Log(message) },
+	}
+	if _, err := ssr.New(app).Call(module, name, helper); err != nil {
+		t.Fatal(err)
+	}
+}
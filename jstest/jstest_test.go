@@ -0,0 +1,23 @@
+package jstest_test
+
+import (
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/jstest"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers: []commonjs.Provider{commonjs.NewDirProvider("_test")},
+	}
+	names, err := jstest.Tests(app, "escape_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "testAmpersand" || names[1] != "testLessThan" {
+		t.Fatalf("did not find expected tests, found %v", names)
+	}
+	jstest.Run(t, app, "escape_test")
+}
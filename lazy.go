@@ -0,0 +1,121 @@
+package commonjs
+
+import (
+	"context"
+	"sort"
+)
+
+// A Module may implement InlineOnly to be left out of packages, and only be
+// provided by InlineDefines. This keeps request specific data out of cached
+// packages.
+type InlineOnly interface {
+	Module
+	InlineOnly()
+}
+
+type inlineOnlyModule struct {
+	Module
+}
+
+// Wraps another module marking it as InlineOnly.
+func NewInlineOnlyModule(m Module) Module {
+	return &inlineOnlyModule{Module: m}
+}
+
+func (m *inlineOnlyModule) InlineOnly() {}
+
+func (m *inlineOnlyModule) ContentContext(ctx context.Context) ([]byte, error) {
+	return contentContext(ctx, m.Module)
+}
+
+func (m *inlineOnlyModule) unwrap() Module { return m.Module }
+
+// Check if the module, or one it wraps, is InlineOnly.
+func isInlineOnly(m Module) bool {
+	for {
+		if _, ok := m.(InlineOnly); ok {
+			return true
+		}
+		w, ok := m.(unwrapper)
+		if !ok {
+			return false
+		}
+		m = w.unwrap()
+	}
+}
+
+type lazyJSONModule struct {
+	name  string
+	value func(ctx context.Context) (interface{}, error)
+	opts  JSONOptions
+}
+
+// Define an InlineOnly module exporting the JSON value returned by the
+// function, which is called each time the module is built. The context is the
+// one given to InlineDefinesContext, like the request context, which allows
+// for values specific to the request like the current user.
+func NewLazyJSONModule(name string, value func(ctx context.Context) (interface{}, error), opts JSONOptions) Module {
+	return &lazyJSONModule{
+		name:  name,
+		value: value,
+		opts:  opts,
+	}
+}
+
+func (m *lazyJSONModule) Name() string {
+	return m.name
+}
+
+func (m *lazyJSONModule) Content() ([]byte, error) {
+	return m.ContentContext(context.Background())
+}
+
+func (m *lazyJSONModule) ContentContext(ctx context.Context) ([]byte, error) {
+	v, err := m.value(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONModuleWithOptions(m.name, v, m.opts).Content()
+}
+
+func (m *lazyJSONModule) Require() ([]string, error) {
+	return nil, nil
+}
+
+func (m *lazyJSONModule) Ext() string {
+	return jsExt
+}
+
+func (m *lazyJSONModule) InlineOnly() {}
+
+// Returns the sorted names of the InlineOnly modules among the modules and
+// their dependencies. These are left out of packages, and must be provided
+// using InlineDefines. The result is cached until the packages are
+// invalidated.
+func (a *App) InlineOnlyModules(modules []string) ([]string, error) {
+	key := packageSpec{modules: modules}.key()
+	a.mu.Lock()
+	names, ok := a.inlineOnly[key]
+	a.mu.Unlock()
+	if ok {
+		return names, nil
+	}
+
+	inlineOnly := make(map[string]bool)
+	err := a.buildDepsFrom(context.Background(), nil, modules, make(map[string]bool), inlineOnly)
+	if err != nil {
+		return nil, err
+	}
+	for name := range inlineOnly {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a.mu.Lock()
+	if a.inlineOnly == nil {
+		a.inlineOnly = make(map[string][]string)
+	}
+	a.inlineOnly[key] = names
+	a.mu.Unlock()
+	return names, nil
+}
@@ -0,0 +1,95 @@
+package commonjs_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type requestKey struct{}
+
+func TestLazyJSONModule(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("app", []byte("require('data')")),
+			commonjs.NewLazyJSONModule("data", func(ctx context.Context) (interface{}, error) {
+				calls++
+				return ctx.Value(requestKey{}), nil
+			}, commonjs.JSONOptions{}),
+			commonjs.NewInlineOnlyModule(commonjs.NewScriptModule("marked", []byte("marked"))),
+		},
+	}
+	u, err := app.ModulesURL([]string{"app", "marked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("was expecting no calls while building the package, got %d", calls)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u}})
+	if body := w.Body.String(); body != "define(\"app\",\"require('data')\");\n" {
+		t.Fatalf("was expecting only app in the package, got %q", body)
+	}
+
+	only, err := app.InlineOnlyModules([]string{"app", "marked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(only, []string{"data", "marked"}) {
+		t.Fatalf("unexpected inline only modules %v", only)
+	}
+	ctx := context.WithValue(context.Background(), requestKey{}, 42)
+	defines, err := app.InlineDefinesContext(ctx, []string{"data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(defines), `define("data","module.exports=42")`) {
+		t.Fatalf("did not find the request value in %q", defines)
+	}
+}
+
+type requireCountingModule struct {
+	commonjs.Module
+	requires int
+}
+
+func (m *requireCountingModule) Require() ([]string, error) {
+	m.requires++
+	return m.Module.Require()
+}
+
+func TestInlineOnlyModulesCached(t *testing.T) {
+	t.Parallel()
+	m := &requireCountingModule{Module: commonjs.NewScriptModule("app", []byte("js"))}
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      []commonjs.Module{m},
+	}
+	for i := 0; i < 2; i++ {
+		if only, err := app.InlineOnlyModules([]string{"app"}); err != nil || only != nil {
+			t.Fatalf("was expecting no inline only modules, got %v, %v", only, err)
+		}
+	}
+	if m.requires != 1 {
+		t.Fatalf("was expecting a single walk, got %d", m.requires)
+	}
+	app.InvalidatePackages()
+	if _, err := app.InlineOnlyModules([]string{"app"}); err != nil {
+		t.Fatal(err)
+	}
+	if m.requires != 2 {
+		t.Fatalf("was expecting a walk after invalidation, got %d", m.requires)
+	}
+}
@@ -0,0 +1,169 @@
+package commonjs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	errBuildTooLarge = errors.New("package build exceeds the MaxBuildBytes limit")
+	errAppClosed     = errors.New("App is closed")
+)
+
+// Statistics about package builds.
+type BuildStats struct {
+	InFlight int   // builds currently running
+	Queued   int   // builds waiting for one of the MaxBuilds slots
+	Bytes    int64 // bytes currently buffered by running builds
+	Rejected int   // builds that failed for exceeding MaxBuildBytes
+}
+
+// Limits the number of concurrent builds and the bytes they buffer.
+type buildLimiter struct {
+	mu       sync.Mutex
+	slots    chan struct{}
+	maxBytes int64
+	stats    BuildStats
+	closed   bool
+	idle     chan struct{} // closed once the last build finishes after close
+}
+
+func newBuildLimiter(maxBuilds int, maxBytes int64) *buildLimiter {
+	l := &buildLimiter{maxBytes: maxBytes}
+	if maxBuilds > 0 {
+		l.slots = make(chan struct{}, maxBuilds)
+	}
+	return l
+}
+
+// A single build holding a slot in the limiter.
+type build struct {
+	limiter *buildLimiter
+	bytes   int64
+	ctx     context.Context // context for the build, if any
+	locale  string          // locale used for LocalizedModules, if any
+
+	bootstrap []byte // code appended to the package, if any
+	prelude   []byte // prelude prepended to the package, if any
+
+	licenses     [][]byte // license comments collected if PreserveLicenses
+	seenLicenses map[string]bool
+}
+
+// The context for the build, defaulting to the background context.
+func (b *build) context() context.Context {
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// Start a build for the context, waiting for a slot if necessary unless the
+// context is done first. Builds are rejected once the limiter is closed.
+func (l *buildLimiter) start(ctx context.Context) (*build, error) {
+	if l.slots != nil {
+		l.mu.Lock()
+		l.stats.Queued++
+		l.mu.Unlock()
+		var err error
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		l.mu.Lock()
+		l.stats.Queued--
+		l.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		if l.slots != nil {
+			<-l.slots
+		}
+		return nil, errAppClosed
+	}
+	l.stats.InFlight++
+	return &build{limiter: l, ctx: ctx}, nil
+}
+
+// Reject new builds and wait for the running ones to finish.
+func (l *buildLimiter) close() {
+	l.mu.Lock()
+	l.closed = true
+	if l.stats.InFlight == 0 {
+		l.mu.Unlock()
+		return
+	}
+	if l.idle == nil {
+		l.idle = make(chan struct{})
+	}
+	idle := l.idle
+	l.mu.Unlock()
+	<-idle
+}
+
+func (l *buildLimiter) Stats() BuildStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// Account for n more bytes buffered by the build.
+func (b *build) grow(n int) error {
+	l := b.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxBytes > 0 && l.stats.Bytes+int64(n) > l.maxBytes {
+		l.stats.Rejected++
+		return errBuildTooLarge
+	}
+	l.stats.Bytes += int64(n)
+	b.bytes += int64(n)
+	return nil
+}
+
+// A build for part of this build, which may run concurrently with it. The
+// result must be merged into this build.
+func (b *build) child() *build {
+	return &build{limiter: b.limiter, ctx: b.ctx, locale: b.locale}
+}
+
+// Takes over the bytes and licenses from a child build.
+func (b *build) merge(c *build) {
+	b.bytes += c.bytes
+	c.bytes = 0
+	for _, license := range c.licenses {
+		b.addLicense(license)
+	}
+}
+
+// Release the bytes held by the build, once they are no longer buffered.
+func (b *build) release() {
+	l := b.limiter
+	l.mu.Lock()
+	l.stats.Bytes -= b.bytes
+	l.mu.Unlock()
+	b.bytes = 0
+}
+
+// Release the slot and the bytes held by the build.
+func (b *build) done() {
+	l := b.limiter
+	l.mu.Lock()
+	l.stats.Bytes -= b.bytes
+	l.stats.InFlight--
+	if l.stats.InFlight == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+	l.mu.Unlock()
+	b.bytes = 0
+	if l.slots != nil {
+		<-l.slots
+	}
+}
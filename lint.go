@@ -0,0 +1,237 @@
+package commonjs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A problem found by a Linter.
+type Diagnostic struct {
+	Module   string // name of the module
+	Filename string // file backing the module, if any
+	Line     int    // 1 based line, 0 if unknown
+	Column   int    // 1 based column, 0 if unknown
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	location := d.Filename
+	if location == "" {
+		location = d.Module
+	}
+	if d.Line > 0 {
+		location += ":" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			location += ":" + strconv.Itoa(d.Column)
+		}
+	}
+	return location + ": " + d.Message
+}
+
+// Indicates a module failed linting.
+type LintError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *LintError) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for ix, d := range e.Diagnostics {
+		lines[ix] = d.String()
+	}
+	return "lint failed:\n" + strings.Join(lines, "\n")
+}
+
+// A Linter checks the content of a module. Problems are reported as
+// Diagnostics, while an error indicates the Linter itself failed.
+type Linter interface {
+	Lint(name string, content []byte) ([]Diagnostic, error)
+}
+
+// Provides a Linter for a function.
+type LinterFunc func(name string, content []byte) ([]Diagnostic, error)
+
+// Lint calls f(name, content).
+func (f LinterFunc) Lint(name string, content []byte) ([]Diagnostic, error) {
+	return f(name, content)
+}
+
+type lintTransform struct {
+	linter Linter
+}
+
+// Provides a Transform failing the build with a LintError if the Linter
+// reports problems in a JavaScript module. The module is not modified. Used
+// as the App Transform, App.Precompile catches problems before deploy.
+func NewLintTransform(l Linter) Transform {
+	return &lintTransform{linter: l}
+}
+
+func (t *lintTransform) Transform(m Module) (Module, error) {
+	if strings.TrimPrefix(m.Ext(), ".") != jsExt {
+		return m, nil
+	}
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	diagnostics, err := t.linter.Lint(m.Name(), content)
+	if err != nil {
+		return nil, err
+	}
+	if len(diagnostics) == 0 {
+		return m, nil
+	}
+	filename := moduleFilename(m)
+	for ix := range diagnostics {
+		diagnostics[ix].Module = m.Name()
+		diagnostics[ix].Filename = filename
+	}
+	return nil, &LintError{Diagnostics: diagnostics}
+}
+
+var reDebugger = regexp.MustCompile(`^\s*debugger\s*;?\s*$`)
+
+// Provides a built in basic check for debugger statements and unterminated
+// comments.
+var BasicLinter Linter = LinterFunc(basicLint)
+
+func basicLint(name string, content []byte) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	for ix, line := range bytes.Split(content, []byte("\n")) {
+		if reDebugger.Match(line) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:    ix + 1,
+				Column:  bytes.Index(line, []byte("debugger")) + 1,
+				Message: "debugger statement",
+			})
+		}
+	}
+	if start := unterminatedComment(content); start >= 0 {
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:    bytes.Count(content[:start], []byte("\n")) + 1,
+			Message: "unterminated comment",
+		})
+	}
+	return diagnostics, nil
+}
+
+// Keywords after which a "/" starts a regular expression.
+var regexpKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true,
+	"in": true, "instanceof": true, "void": true, "delete": true, "new": true,
+	"throw": true,
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// Returns the offset of a block comment which is not terminated, or -1. The
+// content of strings, template and regular expression literals is skipped.
+func unterminatedComment(content []byte) int {
+	var last byte   // the last significant byte
+	var word []byte // the last identifier, if it was the last token
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end < 0 {
+				return i
+			}
+			i += end + 3
+			continue
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			i = skipLiteral(content, i, c, false)
+		case c == '/' && (last == 0 || bytes.IndexByte([]byte("(,=:[!&|?{};+-*%<>~^"), last) >= 0 ||
+			regexpKeywords[string(word)]):
+			i = skipLiteral(content, i, '/', true)
+		case isIdentByte(c):
+			start := i
+			for i+1 < len(content) && isIdentByte(content[i+1]) {
+				i++
+			}
+			word, last = content[start:i+1], c
+			continue
+		}
+		word, last = nil, c
+	}
+	return -1
+}
+
+// Returns the offset of the end of the literal starting at the offset, which
+// is a string, template or regular expression literal.
+func skipLiteral(content []byte, i int, quote byte, regexp bool) int {
+	class := false
+	for i++; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '\\':
+			i++
+		case c == '\n' && quote != '`':
+			return i
+		case regexp && c == '[':
+			class = true
+		case regexp && c == ']':
+			class = false
+		case c == quote && !class:
+			return i
+		}
+	}
+	return i
+}
+
+var reUnixDiagnostic = regexp.MustCompile(`^[^:]*:(\d+):(\d+):\s*(.*)$`)
+
+type execLinter struct {
+	name string
+	args []string
+}
+
+// Provides a Linter running an external command, like eslint or jshint. The
+// content is written to its stdin, and it should report problems in the unix
+// format of "file:line:column: message". For example:
+//
+//	NewExecLinter("eslint", "--stdin", "--format", "unix")
+//	NewExecLinter("jshint", "--reporter", "unix", "-")
+func NewExecLinter(name string, args ...string) Linter {
+	return &execLinter{name: name, args: args}
+}
+
+func (l *execLinter) Lint(name string, content []byte) ([]Diagnostic, error) {
+	cmd := exec.Command(l.name, l.args...)
+	cmd.Stdin = bytes.NewReader(content)
+	out, runErr := cmd.Output()
+
+	var diagnostics []Diagnostic
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		match := reUnixDiagnostic.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(match[1])
+		column, _ := strconv.Atoi(match[2])
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:    line,
+			Column:  column,
+			Message: match[3],
+		})
+	}
+	if len(diagnostics) == 0 && runErr != nil {
+		return nil, fmt.Errorf("running %s for %s: %s", l.name, name, runErr)
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,75 @@
+package commonjs_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestLintTransform(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("good", []byte("var a = 1; /* ok */")),
+			commonjs.NewScriptModule("bad", []byte("var a = 1;\n  debugger;\n/* open")),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+		Transform:    commonjs.NewLintTransform(commonjs.BasicLinter),
+	}
+	if err := app.Precompile([][]string{{"good"}}); err != nil {
+		t.Fatal(err)
+	}
+	err := app.Precompile([][]string{{"bad"}})
+	var lintErr *commonjs.LintError
+	if !errors.As(err, &lintErr) {
+		t.Fatalf("was expecting a lint error, got %v", err)
+	}
+	if len(lintErr.Diagnostics) != 2 {
+		t.Fatalf("was expecting 2 diagnostics, got %v", lintErr.Diagnostics)
+	}
+	expected := "bad:2:3: debugger statement"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("did not find %s in %s", expected, err)
+	}
+}
+
+func TestBasicLinterLiterals(t *testing.T) {
+	t.Parallel()
+	for _, content := range []string{
+		`var a = "/*";`,
+		`var a = '/*', b = ` + "`/*${a}`" + `;`,
+		`var re = /\/*/g;`,
+		`if (/[/*]/.test(a)) {}`,
+		"return /a/*\n*/;",
+		`var a = b / c; /* ok */`,
+		`// /* not a comment`,
+	} {
+		diagnostics, err := commonjs.BasicLinter.Lint("a", []byte(content))
+		if err != nil || len(diagnostics) != 0 {
+			t.Fatalf("was not expecting diagnostics for %s, got %v, %v", content, diagnostics, err)
+		}
+	}
+	diagnostics, err := commonjs.BasicLinter.Lint("a", []byte("var a = \"x\";\n/* open"))
+	if err != nil || len(diagnostics) != 1 || diagnostics[0].Line != 2 {
+		t.Fatalf("was expecting an unterminated comment on line 2, got %v, %v", diagnostics, err)
+	}
+}
+
+func TestExecLinter(t *testing.T) {
+	t.Parallel()
+	linter := commonjs.NewExecLinter("sh", "-c", `cat >/dev/null; echo "<text>:3:7: Missing semicolon."; exit 1`)
+	diagnostics, err := linter.Lint("a", []byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 3 || diagnostics[0].Column != 7 ||
+		diagnostics[0].Message != "Missing semicolon." {
+		t.Fatalf("unexpected diagnostics %v", diagnostics)
+	}
+	if _, err := commonjs.NewExecLinter("sh", "-c", "exit 2").Lint("a", nil); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
@@ -0,0 +1,90 @@
+package commonjs
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var errListNotSupported = errors.New("provider does not support listing modules")
+
+// A Provider may implement Lister to enumerate the names of the modules it
+// provides.
+type Lister interface {
+	ModuleNames() ([]string, error)
+}
+
+func (p *fsProvider) ModuleNames() ([]string, error) {
+	if p.fsys == nil {
+		return nil, errListNotSupported
+	}
+	var names []string
+	err := fs.WalkDir(p.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(name) != ext {
+			return nil
+		}
+		names = append(names, strings.TrimSuffix(name, ext))
+		return nil
+	})
+	return names, err
+}
+
+func (p *prefixProvider) ModuleNames() ([]string, error) {
+	l, ok := p.provider.(Lister)
+	if !ok {
+		return nil, errListNotSupported
+	}
+	names, err := l.ModuleNames()
+	if err != nil {
+		return nil, err
+	}
+	for ix := range names {
+		names[ix] = p.prefix + names[ix]
+	}
+	return names, nil
+}
+
+func (p *CachingProvider) ModuleNames() ([]string, error) {
+	l, ok := p.provider.(Lister)
+	if !ok {
+		return nil, errListNotSupported
+	}
+	return l.ModuleNames()
+}
+
+// Returns the sorted names of the Modules directly provided by the App along
+// with those from the Providers implementing Lister. Providers that do not
+// implement Lister are skipped.
+func (a *App) ModuleNames() ([]string, error) {
+	set := make(map[string]bool)
+	for _, m := range a.Modules {
+		set[m.Name()] = true
+	}
+	for _, p := range a.Providers {
+		l, ok := p.(Lister)
+		if !ok {
+			continue
+		}
+		names, err := l.ModuleNames()
+		if err == errListNotSupported {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			set[name] = true
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,54 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDirProviderModuleNames(t *testing.T) {
+	t.Parallel()
+	l := commonjs.NewDirProvider("_test/p").(commonjs.Lister)
+	names, err := l.ModuleNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "index,q" {
+		t.Fatalf("did not find expected names, found %v", names)
+	}
+}
+
+func TestFSProviderModuleNames(t *testing.T) {
+	t.Parallel()
+	l := commonjs.NewFSProvider(os.DirFS("_test/p")).(commonjs.Lister)
+	names, err := l.ModuleNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "index,q" {
+		t.Fatalf("did not find expected names, found %v", names)
+	}
+}
+
+func TestAppModuleNames(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("q", nil),
+			commonjs.NewScriptModule("z", nil),
+		},
+		Providers: []commonjs.Provider{
+			commonjs.NewPrefixProvider("x", commonjs.NewDirProvider("_test/p")),
+			commonjs.NewDirProvider("_test/b"),
+			providerWithError(0),
+		},
+	}
+	names, err := a.ModuleNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "baz,q,x/index,x/q,z" {
+		t.Fatalf("did not find expected names, found %v", names)
+	}
+}
@@ -0,0 +1,39 @@
+package commonjs
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+)
+
+// The path under the MountPath streaming the names of changed modules when
+// LiveReload is enabled.
+const EventsPath = "_events"
+
+var liveReloadScript = []byte(`
+(function() {
+  new EventSource(EVENTS_URL).addEventListener('change', function() {
+    window.location.reload();
+  });
+})();
+`)
+
+// Returns the URL streaming the names of changed modules when LiveReload is
+// enabled.
+func (a *App) EventsURL() string {
+	return path.Join("/", a.MountPath, EventsPath)
+}
+
+// The script reloading the page when a module changes.
+func (a *App) liveReloadScript() []byte {
+	url, _ := json.Marshal(a.EventsURL())
+	return bytes.Replace(liveReloadScript, []byte("EVENTS_URL"), url, 1)
+}
+
+type changeEvent struct {
+	Modules []string `json:"modules"`
+}
+
+func liveReloadChange(names []string) ([]byte, error) {
+	return json.Marshal(changeEvent{Modules: names})
+}
@@ -0,0 +1,73 @@
+package commonjs_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestLiveReload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.js")
+	if err := ioutil.WriteFile(filename, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider(dir)},
+		ContentStore: commonjs.NewMemoryStore(),
+		LiveReload:   true,
+	}
+	w, err := app.Watch(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close(context.Background())
+
+	prelude, err := app.BundlePrelude([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(prelude, []byte(`new EventSource("/r/_events")`)) {
+		println(string(prelude))
+		t.Fatal("did not find the live reload script in the prelude above")
+	}
+
+	s := httptest.NewServer(app)
+	defer s.Close()
+	res, err := http.Get(s.URL + app.EventsURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	r := bufio.NewReader(res.Body)
+	if line, _ := r.ReadString('\n'); line != ": watching\n" {
+		t.Fatalf("unexpected first line %q", line)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Check(); err != nil {
+		t.Fatal(err)
+	}
+	r.ReadString('\n')
+	for _, expected := range []string{"event: change\n", "data: {\"modules\":[\"a\"]}\n"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != expected {
+			t.Fatalf("was expecting %q, got %q", expected, line)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package commonjs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// The path under the MountPath serving individual modules for require.load.
+const ModulePath = "module"
+
+// The default require.base in the prelude.
+const defaultModuleBaseURL = "/r/module/"
+
+// Returns the base URL individual modules are served from, for use as
+// require.base in the prelude.
+func (a *App) ModuleBaseURL() string {
+	return strings.TrimSuffix(a.BaseURL, "/") +
+		path.Join("/", a.MountPath, ModulePath) + "/"
+}
+
+// Returns the script to configure require.base, or nil if the default is
+// correct.
+func (a *App) LoaderConfig() []byte {
+	base := a.ModuleBaseURL()
+	if base == defaultModuleBaseURL {
+		return nil
+	}
+	quoted, _ := json.Marshal(base)
+	return []byte("require.base=" + string(quoted) + ";")
+}
+
+// Extracts the module name from a module URL path.
+func (a *App) moduleName(urlPath string) (string, bool) {
+	prefix := path.Join("/", a.MountPath, ModulePath) + "/"
+	if !strings.HasPrefix(urlPath, prefix) || !strings.HasSuffix(urlPath, ext) {
+		return "", false
+	}
+	name := urlPath[len(prefix) : len(urlPath)-extLen]
+	if name == "" || path.Clean("/"+name) != "/"+name {
+		return "", false
+	}
+	return name, true
+}
+
+// Serves a single define() call for the named module.
+func (a *App) serveModule(w http.ResponseWriter, r *http.Request, name string) {
+	if err := a.validateRequestName(name); err != nil {
+		a.serveError(w, r, 400, "invalid module name", err)
+		return
+	}
+	b, err := a.buildLimiter().start(r.Context())
+	if err != nil {
+		a.serveError(w, r, 503, "unavailable", err)
+		return
+	}
+	defer b.done()
+	content, _, err := a.define(name, b)
+	if err != nil {
+		if IsNotFound(err) {
+			a.serveError(w, r, 404, "not found", err)
+			return
+		}
+		if IsInvalidName(err) {
+			a.serveError(w, r, 400, "invalid module name", err)
+			return
+		}
+		a.serveError(w, r, 500, "error building module", err)
+		return
+	}
+	a.setHeaders(w, r.URL.Path)
+	http.ServeContent(w, r, name+ext, time.Time{}, bytes.NewReader(content))
+}
@@ -0,0 +1,54 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestServeModule(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	cases := map[string]int{
+		"/r/module/a/foo.js":          200,
+		"/r/module/does-not-exist.js": 404,
+		"/r/module/../a/foo.js":       404,
+		"/r/module/.js":               404,
+	}
+	for url, code := range cases {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", url, nil)
+		app.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("for %s was expecting %d, got %d", url, code, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/r/module/a/foo.js", nil)
+	app.ServeHTTP(w, r)
+	expected := "define(\"a/foo\",\"require('bar')\\nrequire('b/baz')\");\n"
+	if w.Body.String() != expected {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestLoaderConfig(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{MountPath: "r"}
+	if config := app.LoaderConfig(); config != nil {
+		t.Fatalf("was not expecting config, got %s", config)
+	}
+	app = &commonjs.App{MountPath: "js", BaseURL: "https://cdn.example.com"}
+	expected := `require.base="https://cdn.example.com/js/module/";`
+	if config := string(app.LoaderConfig()); config != expected {
+		t.Fatalf("expected %s got %s", expected, config)
+	}
+}
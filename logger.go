@@ -0,0 +1,71 @@
+package commonjs
+
+import (
+	"fmt"
+	"log"
+)
+
+// The severity of a log message.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota // builds and cache events
+	LogInfo
+	LogWarn  // problems that do not fail requests
+	LogError // failed requests
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// A Logger receives the build, cache and serving events of an App.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Log(level LogLevel, format string, args ...interface{})
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	min    LogLevel
+}
+
+// Provides a Logger writing messages of at least the given level to the
+// log.Logger, or to the standard logger if it is nil.
+func NewStdLogger(l *log.Logger, min LogLevel) Logger {
+	return &stdLogger{logger: l, min: min}
+}
+
+func (s *stdLogger) Log(level LogLevel, format string, args ...interface{}) {
+	if level < s.min {
+		return
+	}
+	msg := level.String() + ": " + fmt.Sprintf(format, args...)
+	if s.logger == nil {
+		log.Print(msg)
+		return
+	}
+	s.logger.Print(msg)
+}
+
+var defaultLogger = NewStdLogger(nil, LogInfo)
+
+// Logs using the Logger, or the standard logger for messages of at least
+// LogInfo if one is not set.
+func (a *App) log(level LogLevel, format string, args ...interface{}) {
+	if a.Logger != nil {
+		a.Logger.Log(level, format, args...)
+		return
+	}
+	defaultLogger.Log(level, format, args...)
+}
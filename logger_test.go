@@ -0,0 +1,62 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type memoryLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (m *memoryLogger) Log(level commonjs.LogLevel, format string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, level.String()+" "+fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+	logger := &memoryLogger{}
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		OnDemand:     true,
+		Logger:       logger,
+	}
+	if _, err := app.ModulesURL([]string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	app.Modules = append(app.Modules, commonjs.NewScriptModule("broken", []byte("require('gone')")))
+	r, _ := http.NewRequest("GET", "/r/pkg.js?m=broken", nil)
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	all := strings.Join(logger.messages, "\n")
+	for _, expected := range []string{"debug building package for [bar]", "debug built package /r/", "error GET /r/pkg.js?m=broken: error building package"} {
+		if !strings.Contains(all, expected) {
+			println(all)
+			t.Fatalf("did not find %q in messages above", expected)
+		}
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	logger := commonjs.NewStdLogger(log.New(buf, "", 0), commonjs.LogWarn)
+	logger.Log(commonjs.LogInfo, "hidden")
+	logger.Log(commonjs.LogError, "shown %d", 1)
+	if buf.String() != "error: shown 1\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
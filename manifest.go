@@ -0,0 +1,178 @@
+package commonjs
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A manifest lists the packages built by an App.
+type manifest struct {
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Packages    []manifestPackage `json:"packages"`
+}
+
+type manifestPackage struct {
+	Modules   []string `json:"modules"`
+	Vendor    bool     `json:"vendor,omitempty"`
+	Locale    string   `json:"locale,omitempty"`
+	Inline    []string `json:"inline,omitempty"`
+	Bootstrap string   `json:"bootstrap,omitempty"`
+	Prelude   bool     `json:"prelude,omitempty"`
+	URL       string   `json:"url"`
+	Integrity string   `json:"integrity,omitempty"`
+	Size      int      `json:"size,omitempty"`
+
+	ModuleSizes map[string]int `json:"moduleSizes,omitempty"`
+}
+
+// The spec the package was built for.
+func (p manifestPackage) spec() packageSpec {
+	return packageSpec{
+		modules:   p.Modules,
+		vendor:    p.Vendor,
+		locale:    p.Locale,
+		inline:    p.Inline,
+		bootstrap: []byte(p.Bootstrap),
+		prelude:   p.Prelude,
+	}
+}
+
+// The size of each module, for comparing manifests.
+func moduleSizes(info []ModuleInfo) map[string]int {
+	sizes := make(map[string]int, len(info))
+	for _, m := range info {
+		sizes[m.Name] = m.Size
+	}
+	return sizes
+}
+
+// Returns the subresource integrity value for the content, suitable for use
+// in the integrity attribute of a script tag.
+func Integrity(content []byte) string {
+	sum := sha512.Sum384(content)
+	return integrity(sum[:])
+}
+
+// The subresource integrity value for the SHA-384 sum.
+func integrity(sum []byte) string {
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum)
+}
+
+// Returns a JSON manifest of the packages built by the App, mapping the
+// modules each package was built for to its URL, integrity and size. The
+// manifest can be loaded using LoadManifest.
+func (a *App) Manifest() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := a.WriteManifest(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Writes a JSON manifest of the packages built by the App. The manifest can be
+// loaded using LoadManifest by a freshly started instance.
+func (a *App) WriteManifest(w io.Writer) error {
+	return writeManifest(w, a.BuildFingerprint(), a.manifestPackages())
+}
+
+// The packages currently cached by the App for the current BuildFingerprint.
+func (a *App) manifestPackages() []manifestPackage {
+	fingerprint := a.BuildFingerprint()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var packages []manifestPackage
+	for key, entry := range a.packageURLs {
+		if key != fingerprintKey(fingerprint, entry.packageSpec) {
+			continue
+		}
+		packages = append(packages, manifestPackage{
+			Modules:   entry.modules,
+			Vendor:    entry.vendor,
+			Locale:    entry.locale,
+			Inline:    entry.inline,
+			Bootstrap: string(entry.bootstrap),
+			Prelude:   entry.prelude,
+			URL:       entry.url,
+			Integrity: entry.integrity,
+			Size:      entry.size,
+
+			ModuleSizes: entry.moduleSizes,
+		})
+	}
+	return packages
+}
+
+func writeManifest(w io.Writer, fingerprint string, packages []manifestPackage) error {
+	sort.Sort(byURL(packages))
+	return json.NewEncoder(w).Encode(manifest{Fingerprint: fingerprint, Packages: packages})
+}
+
+// Loads a manifest written by WriteManifest, making the listed package URLs
+// available without rebuilding them. If VerifyManifest is set, packages
+// missing from the ContentStore are ignored and will be rebuilt when
+// requested.
+func (a *App) LoadManifest(r io.Reader) error {
+	var m manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	return a.loadPackages(m.Packages, m.Fingerprint, a.VerifyManifest)
+}
+
+// Make the given packages available without rebuilding them, optionally
+// ignoring those missing from the ContentStore. Packages built with another
+// BuildFingerprint are ignored.
+func (a *App) loadPackages(packages []manifestPackage, fingerprint string, verify bool) error {
+	if current := a.BuildFingerprint(); fingerprint != current {
+		a.log(LogInfo, "ignoring packages built with fingerprint %q instead of %q", fingerprint, current)
+		return nil
+	}
+	entries := make(map[string]*packageEntry)
+	for _, p := range packages {
+		key, ok := a.route(p.URL)
+		if ok {
+			a.storedKey(key, fingerprint)
+		}
+		if verify {
+			if !ok {
+				return fmt.Errorf("invalid package url %s in manifest", p.URL)
+			}
+			content, err := a.storedContent(key)
+			if err != nil {
+				return err
+			}
+			if content == nil {
+				continue
+			}
+		}
+		spec := p.spec()
+		entries[fingerprintKey(fingerprint, spec)] = &packageEntry{
+			packageSpec: spec,
+			url:         p.URL,
+			integrity:   p.Integrity,
+			size:        p.Size,
+			moduleSizes: p.ModuleSizes,
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.packageURLs == nil {
+		a.packageURLs = make(map[string]*packageEntry)
+	}
+	for key, entry := range entries {
+		a.packageURLs[key] = entry
+	}
+	return nil
+}
+
+type byURL []manifestPackage
+
+func (p byURL) Len() int           { return len(p) }
+func (p byURL) Less(i, j int) bool { return p[i].URL < p[j].URL }
+func (p byURL) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
@@ -0,0 +1,108 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/daaku/go.commonjs"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: store,
+		Vendor:       []string{"bar"},
+	}
+	expectedURL, err := a.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedVendorURL, err := a.VendorURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := a.WriteManifest(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// without providers a package can only come from the manifest
+	b := &commonjs.App{
+		MountPath:      "r",
+		ContentStore:   store,
+		VerifyManifest: true,
+	}
+	if err := b.LoadManifest(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	actualURL, err := b.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualURL != expectedURL {
+		t.Fatalf("expected %s got %s", expectedURL, actualURL)
+	}
+	b.Vendor = []string{"bar"}
+	actualVendorURL, err := b.VendorURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualVendorURL != expectedVendorURL {
+		t.Fatalf("expected %s got %s", expectedVendorURL, actualVendorURL)
+	}
+}
+
+func TestManifestVerify(t *testing.T) {
+	t.Parallel()
+	const manifest = `{"packages":[{"modules":["bar"],"url":"/r/d613ea9.js"}]}`
+	a := &commonjs.App{
+		MountPath:      "r",
+		ContentStore:   commonjs.NewMemoryStore(),
+		VerifyManifest: true,
+	}
+	if err := a.LoadManifest(bytes.NewReader([]byte(manifest))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ModulesURL([]string{"bar"}); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestManifestInvalid(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{}
+	if err := a.LoadManifest(bytes.NewReader([]byte("{"))); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	if _, err := a.ModulesURL([]string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("define(\"bar\",\"bar\");\n")
+	expected := fmt.Sprintf(
+		`{"packages":[{"modules":["bar"],"url":"/r/%s.js","integrity":"%s","size":%d,"moduleSizes":{"bar":4}}]}`+"\n",
+		hashOf(content), commonjs.Integrity(content), len(content))
+	actual, err := a.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != expected {
+		t.Fatalf("expected %s got %s", expected, actual)
+	}
+}
+
+func hashOf(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))[:7]
+}
@@ -0,0 +1,179 @@
+package commonjs
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Metrics receives events from the App, allowing asset serving to be
+// monitored. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// A package was built for the modules.
+	PackageBuilt(modules []string, duration time.Duration, size int)
+
+	// A package URL was found in the cache, or had to be built.
+	CacheHit(modules []string)
+	CacheMiss(modules []string)
+
+	// A response was served, with the number of body bytes written.
+	Served(urlPath string, status int, bytes int)
+
+	// A request resulted in a 404.
+	NotFound(urlPath string)
+}
+
+// Counts the status and bytes written to a response.
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+// Flushes the underlying response, for streaming responses.
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Serves the request, reporting to Metrics if set.
+func (a *App) serveWithMetrics(w http.ResponseWriter, r *http.Request) {
+	cw := &countingWriter{ResponseWriter: w}
+	a.serve(cw, r)
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.status == http.StatusNotFound {
+		a.Metrics.NotFound(r.URL.Path)
+	}
+	a.Metrics.Served(r.URL.Path, cw.status, cw.bytes)
+}
+
+type expvarMetrics struct {
+	m *expvar.Map
+}
+
+// Provides Metrics published as an expvar.Map with the given name, with
+// counters for builds, build_ns, build_bytes, cache_hits, cache_misses,
+// responses, bytes_served and not_found. Like expvar.NewMap, this panics if
+// the name is already in use.
+func NewExpvarMetrics(name string) Metrics {
+	return &expvarMetrics{m: expvar.NewMap(name)}
+}
+
+func (e *expvarMetrics) PackageBuilt(modules []string, duration time.Duration, size int) {
+	e.m.Add("builds", 1)
+	e.m.Add("build_ns", int64(duration))
+	e.m.Add("build_bytes", int64(size))
+}
+
+func (e *expvarMetrics) CacheHit(modules []string) {
+	e.m.Add("cache_hits", 1)
+}
+
+func (e *expvarMetrics) CacheMiss(modules []string) {
+	e.m.Add("cache_misses", 1)
+}
+
+func (e *expvarMetrics) Served(urlPath string, status int, bytes int) {
+	e.m.Add("responses", 1)
+	e.m.Add("bytes_served", int64(bytes))
+}
+
+func (e *expvarMetrics) NotFound(urlPath string) {
+	e.m.Add("not_found", 1)
+}
+
+// Provides Metrics as counters in the Prometheus text exposition format. It is
+// also a http.Handler serving the metrics, to be mounted at /metrics for
+// example, without depending on the Prometheus client library.
+type PrometheusMetrics struct {
+	namespace string
+	mu        sync.Mutex
+	counters  map[string]float64
+}
+
+// Create PrometheusMetrics with the metric names prefixed by the namespace.
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	return &PrometheusMetrics{
+		namespace: namespace,
+		counters:  make(map[string]float64),
+	}
+}
+
+var prometheusCounters = []struct {
+	name string
+	help string
+}{
+	{"builds_total", "Packages built."},
+	{"build_seconds_total", "Time spent building packages."},
+	{"build_bytes_total", "Bytes of packages built."},
+	{"cache_hits_total", "Package URLs found in the cache."},
+	{"cache_misses_total", "Package URLs that had to be built."},
+	{"responses_total", "Responses served."},
+	{"served_bytes_total", "Bytes served."},
+	{"not_found_total", "Requests resulting in a 404."},
+}
+
+func (p *PrometheusMetrics) add(name string, v float64) {
+	p.mu.Lock()
+	p.counters[name] += v
+	p.mu.Unlock()
+}
+
+func (p *PrometheusMetrics) PackageBuilt(modules []string, duration time.Duration, size int) {
+	p.add("builds_total", 1)
+	p.add("build_seconds_total", duration.Seconds())
+	p.add("build_bytes_total", float64(size))
+}
+
+func (p *PrometheusMetrics) CacheHit(modules []string) {
+	p.add("cache_hits_total", 1)
+}
+
+func (p *PrometheusMetrics) CacheMiss(modules []string) {
+	p.add("cache_misses_total", 1)
+}
+
+func (p *PrometheusMetrics) Served(urlPath string, status int, bytes int) {
+	p.add("responses_total", 1)
+	p.add("served_bytes_total", float64(bytes))
+}
+
+func (p *PrometheusMetrics) NotFound(urlPath string) {
+	p.add("not_found_total", 1)
+}
+
+// Serves the metrics in the Prometheus text exposition format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range prometheusCounters {
+		name := c.name
+		if p.namespace != "" {
+			name = p.namespace + "_" + name
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %g\n",
+			name, c.help, name, name, p.counters[c.name])
+	}
+}
@@ -0,0 +1,75 @@
+package commonjs_test
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+	metrics := commonjs.NewPrometheusMetrics("assets")
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		Metrics:      metrics,
+	}
+	url, err := app.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ModulesURL([]string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{url, "/r/0000000.js"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		app.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/metrics", nil)
+	metrics.ServeHTTP(w, r)
+	for _, expected := range []string{
+		"assets_builds_total 1\n",
+		"assets_cache_hits_total 1\n",
+		"assets_cache_misses_total 1\n",
+		"assets_responses_total 2\n",
+		"assets_served_bytes_total 31\n",
+		"assets_not_found_total 1\n",
+		"# TYPE assets_builds_total counter\n",
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			println(w.Body.String())
+			t.Fatalf("did not find %q in metrics above", expected)
+		}
+	}
+}
+
+// Counts the expvar maps published, as each name can only be published once
+// per process, including with -count.
+var expvarRuns int64
+
+func TestExpvarMetrics(t *testing.T) {
+	t.Parallel()
+	name := fmt.Sprintf("commonjs_test_expvar_%d", atomic.AddInt64(&expvarRuns, 1))
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("bar", []byte("bar"))},
+		ContentStore: commonjs.NewMemoryStore(),
+		Metrics:      commonjs.NewExpvarMetrics(name),
+	}
+	if _, err := app.ModulesURL([]string{"bar"}); err != nil {
+		t.Fatal(err)
+	}
+	m := expvar.Get(name).(*expvar.Map)
+	if v := m.Get("builds"); v == nil || v.String() != "1" {
+		t.Fatalf("unexpected builds %v", v)
+	}
+}
@@ -0,0 +1,123 @@
+package commonjs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Serves multiple named Apps, each with its own MountPath, from a single
+// http.Handler. This allows for Apps with different Providers and Transforms,
+// for example for an admin and a public site, to share a ContentStore.
+type Mux struct {
+	store  ByteStore
+	mu     sync.RWMutex
+	apps   map[string]*App
+	mounts map[string]*App // keyed by the mount prefix
+}
+
+// Create a Mux using the store for Apps without a ContentStore.
+func NewMux(store ByteStore) *Mux {
+	return &Mux{
+		store:  store,
+		apps:   make(map[string]*App),
+		mounts: make(map[string]*App),
+	}
+}
+
+// The path prefix of URLs served by the App.
+func mountPrefix(a *App) string {
+	return strings.TrimSuffix(path.Join("/", a.MountPath), "/") + "/"
+}
+
+// Registers the App with the name. The name and MountPath must be unique. The
+// App is given the shared store if it does not have a ContentStore.
+func (m *Mux) Handle(name string, a *App) error {
+	prefix := mountPrefix(a)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.apps[name]; ok {
+		return fmt.Errorf("app %s is already registered", name)
+	}
+	if _, ok := m.mounts[prefix]; ok {
+		return fmt.Errorf("mount path %s is already registered", a.MountPath)
+	}
+	if a.ContentStore == nil {
+		a.ContentStore = m.store
+		a.mu.Lock()
+		a.sharedStore = true
+		a.mu.Unlock()
+	}
+	m.apps[name] = a
+	m.mounts[prefix] = a
+	return nil
+}
+
+// Returns the App registered with the name, or nil.
+func (m *Mux) App(name string) *App {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.apps[name]
+}
+
+// Serves the request with the App with the longest matching MountPath.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var match *App
+	m.mu.RLock()
+	for prefix := strings.TrimSuffix(r.URL.Path, "/"); ; {
+		ix := strings.LastIndex(prefix, "/")
+		if ix < 0 {
+			break
+		}
+		prefix = prefix[:ix]
+		if a, ok := m.mounts[prefix+"/"]; ok {
+			match = a
+			break
+		}
+	}
+	m.mu.RUnlock()
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+	match.ServeHTTP(w, r)
+}
+
+// Removes the values not accessed within the given duration from the shared
+// store, which must implement StoreCollector, like App.GCStore. Packages whose
+// URLs are cached by any App using the shared store are kept. Returns the
+// number of removed values.
+func (m *Mux) GCStore(olderThan time.Duration) (int, error) {
+	m.mu.RLock()
+	keep := make(map[string]bool)
+	for _, a := range m.apps {
+		a.mu.Lock()
+		shared := a.sharedStore
+		a.mu.Unlock()
+		if !shared {
+			continue
+		}
+		for key := range a.cachedKeys() {
+			keep[key] = true
+		}
+	}
+	m.mu.RUnlock()
+	return gcStore(m.store, olderThan, keep)
+}
+
+// Closes all the Apps, returning the first error.
+func (m *Mux) Close(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var first error
+	for _, a := range m.apps {
+		if err := a.Close(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,112 @@
+package commonjs_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestMux(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	mux := commonjs.NewMux(store)
+	site := &commonjs.App{
+		MountPath: "r",
+		Modules:   []commonjs.Module{commonjs.NewScriptModule("site", []byte("site"))},
+	}
+	admin := &commonjs.App{
+		MountPath: "admin/r",
+		Modules:   []commonjs.Module{commonjs.NewScriptModule("admin", []byte("admin"))},
+	}
+	if err := mux.Handle("site", site); err != nil {
+		t.Fatal(err)
+	}
+	if err := mux.Handle("admin", admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := mux.Handle("other", &commonjs.App{MountPath: "/r/"}); err == nil {
+		t.Fatal("was expecting an error for a duplicate mount path")
+	}
+	if err := mux.Handle("site", &commonjs.App{MountPath: "s"}); err == nil {
+		t.Fatal("was expecting an error for a duplicate name")
+	}
+	if mux.App("admin") != admin || admin.ContentStore != store {
+		t.Fatal("was expecting the registered admin app with the shared store")
+	}
+
+	for _, c := range []struct {
+		app    *commonjs.App
+		module string
+	}{{site, "site"}, {admin, "admin"}} {
+		u, err := c.app.ModulesURL([]string{c.module})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		if w.Code != 200 {
+			t.Fatalf("was expecting 200 for %s, got %d", u, w.Code)
+		}
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", c.app.ModuleBaseURL()+c.module+".js", nil))
+		if w.Code != 200 {
+			t.Fatalf("was expecting 200 for module %s, got %d", c.module, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/other/x.js", nil))
+	if w.Code != 404 {
+		t.Fatalf("was expecting 404, got %d", w.Code)
+	}
+	if err := mux.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMuxGCStore(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewMemoryStore()
+	mux := commonjs.NewMux(store)
+	site := &commonjs.App{
+		MountPath: "r",
+		Modules:   []commonjs.Module{commonjs.NewScriptModule("site", []byte("site"))},
+	}
+	admin := &commonjs.App{
+		MountPath: "admin/r",
+		Modules:   []commonjs.Module{commonjs.NewScriptModule("admin", []byte("admin"))},
+	}
+	for name, a := range map[string]*commonjs.App{"site": site, "admin": admin} {
+		if err := mux.Handle(name, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var urls []string
+	for _, c := range []struct {
+		app    *commonjs.App
+		module string
+	}{{site, "site"}, {admin, "admin"}} {
+		u, err := c.app.ModulesURL([]string{c.module})
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	if _, err := site.GCStore(0); err == nil {
+		t.Fatal("was expecting an error collecting a shared store from an App")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if deleted, err := mux.GCStore(time.Millisecond); err != nil || deleted != 0 {
+		t.Fatalf("was expecting the packages of both apps to be kept, got %d, %v", deleted, err)
+	}
+	for _, u := range urls {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		if w.Code != 200 {
+			t.Fatalf("was expecting 200 for %s, got %d", u, w.Code)
+		}
+	}
+}
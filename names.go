@@ -0,0 +1,52 @@
+package commonjs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// Indicates a module name was rejected by the App's name validation.
+type InvalidNameError struct {
+	Name string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid module name %q", e.Name)
+}
+
+// Check if the error indicates an invalid module name.
+func IsInvalidName(err error) bool {
+	var ie *InvalidNameError
+	return errors.As(err, &ie)
+}
+
+var reValidName = regexp.MustCompile(`^(@[A-Za-z0-9_.-]+/)?[A-Za-z0-9_/.-]+$`)
+
+// The default module name validation. Names may only contain letters, digits,
+// "_", "/", "." and "-", optionally following an npm scope like "@babel/", and
+// must be relative paths without "." or ".." elements. This keeps names safe
+// to use as file paths, in URLs and in emitted code.
+func ValidateName(name string) error {
+	if !reValidName.MatchString(name) || path.Clean("/"+name) != "/"+name {
+		return &InvalidNameError{Name: name}
+	}
+	return nil
+}
+
+// Validates the name of a module being built using the NameValidator, if any.
+func (a *App) validateName(name string) error {
+	if a.NameValidator != nil {
+		return a.NameValidator(name)
+	}
+	return nil
+}
+
+// Validates a name from a request using the NameValidator or ValidateName.
+func (a *App) validateRequestName(name string) error {
+	if a.NameValidator != nil {
+		return a.NameValidator(name)
+	}
+	return ValidateName(name)
+}
@@ -0,0 +1,90 @@
+package commonjs_test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestValidateName(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"a", "a/b", "a-b_c.d", "jquery-1.8.2/jquery.min", "@babel/runtime", "@babel/runtime/helpers/x"} {
+		if err := commonjs.ValidateName(name); err != nil {
+			t.Fatalf("was expecting %q to be valid, got %s", name, err)
+		}
+	}
+	for _, name := range []string{"", "/a", "a/", "a//b", "../a", "a/../b", "./a", `a"b`, "a b", "a\nb", "@scope", "@/a", "a/@b/c", "@a@b/c"} {
+		if err := commonjs.ValidateName(name); !commonjs.IsInvalidName(err) {
+			t.Fatalf("was expecting %q to be invalid, got %v", name, err)
+		}
+	}
+}
+
+func TestAppInvalidName(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:     "r",
+		Modules:       []commonjs.Module{commonjs.NewScriptModule("a", []byte("require('../b')"))},
+		ContentStore:  commonjs.NewMemoryStore(),
+		OnDemand:      true,
+		NameValidator: commonjs.ValidateName,
+	}
+	if _, err := app.ModulesURL([]string{"a"}); !commonjs.IsInvalidName(err) {
+		t.Fatalf("was expecting an invalid name error, got %v", err)
+	}
+	for _, target := range []string{"/r/module/a%22b.js", "/r/pkg.js?m=a%22b"} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != 400 {
+			t.Fatalf("was expecting 400 for %s, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAppNameValidator(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret.js"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules:   []commonjs.Module{commonjs.NewScriptModule("@scope/a", []byte("a"))},
+		Providers: []commonjs.Provider{commonjs.NewDirProvider(root)},
+		NameValidator: func(string) error {
+			return nil
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	if _, err := app.ModulesURL([]string{"@scope/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ModulesURL([]string{"../secret"}); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestAppNameValidationOptIn(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("a b", []byte("a"))},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	if _, err := app.ModulesURL([]string{"a b"}); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/r/module/a%22b.js", nil))
+	if w.Code != 400 {
+		t.Fatalf("was expecting 400 for a name from a request, got %d", w.Code)
+	}
+}
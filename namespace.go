@@ -0,0 +1,72 @@
+package commonjs
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// A ByteStore which can delete all the values with keys starting with a
+// prefix, like those of an old release namespaced using NewPrefixStore. The
+// stores from NewMemoryStore and NewDiskStore implement it.
+type PrefixDeleter interface {
+	// Delete the values with keys starting with the prefix, returning the
+	// number of deleted values.
+	DeletePrefix(prefix string) (int, error)
+}
+
+var errDeleteNotSupported = errors.New("store does not support deleting by prefix")
+
+type prefixStore struct {
+	prefix string
+	store  ByteStore
+}
+
+// Provides the values of another ByteStore under a key prefix. For example
+// with the prefix "v2", the key "56cc634" is stored as "v2/56cc634". This
+// allows multiple Apps, or multiple deployed versions of one, to share a
+// backend without collisions, and the values for a prefix to be deleted
+// together if the backend is a PrefixDeleter. Publishing and flushing are
+// passed through, while the optional FileStore and StreamStore interfaces
+// are not.
+func NewPrefixStore(prefix string, store ByteStore) ByteStore {
+	return &prefixStore{
+		prefix: strings.TrimSuffix(prefix, "/") + "/",
+		store:  store,
+	}
+}
+
+func (s *prefixStore) Store(key string, value []byte) error {
+	return s.store.Store(s.prefix+key, value)
+}
+
+func (s *prefixStore) Get(key string) ([]byte, error) {
+	return s.store.Get(s.prefix + key)
+}
+
+// Publishes the value under the prefixed key if the ByteStore is a
+// PublishStore. The file name is not prefixed, since it is where the package
+// is served.
+func (s *prefixStore) Publish(key, filename string, value []byte) error {
+	if ps, ok := s.store.(PublishStore); ok {
+		return ps.Publish(s.prefix+key, filename, value)
+	}
+	return s.Store(key, value)
+}
+
+func (s *prefixStore) Flush(ctx context.Context) error {
+	if f, ok := s.store.(StoreFlusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Deletes the values under the prefix and the given key prefix, so an empty
+// prefix deletes all the values of the namespace.
+func (s *prefixStore) DeletePrefix(prefix string) (int, error) {
+	d, ok := s.store.(PrefixDeleter)
+	if !ok {
+		return 0, errDeleteNotSupported
+	}
+	return d.DeletePrefix(s.prefix + prefix)
+}
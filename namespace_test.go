@@ -0,0 +1,71 @@
+package commonjs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func testPrefixStore(t *testing.T, backend commonjs.ByteStore) {
+	v1 := commonjs.NewPrefixStore("v1", backend)
+	v2 := commonjs.NewPrefixStore("v2/", backend)
+	if err := v1.Store("k", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err := v2.Store("k", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := v1.Get("k"); err != nil || string(value) != "one" {
+		t.Fatalf("unexpected value %q, %v", value, err)
+	}
+	if value, err := backend.Get("v2/k"); err != nil || string(value) != "two" {
+		t.Fatalf("was expecting the prefixed key in the backend, got %q, %v", value, err)
+	}
+
+	deleted, err := v1.(commonjs.PrefixDeleter).DeletePrefix("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("was expecting 1 deleted value, got %d", deleted)
+	}
+	if value, _ := v1.Get("k"); value != nil {
+		t.Fatalf("was expecting the value to be deleted, got %q", value)
+	}
+	if value, _ := v2.Get("k"); string(value) != "two" {
+		t.Fatalf("was expecting the other namespace to be kept, got %q", value)
+	}
+}
+
+func TestPrefixStoreMemory(t *testing.T) {
+	t.Parallel()
+	testPrefixStore(t, commonjs.NewMemoryStore())
+}
+
+func TestPrefixStoreDisk(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "commonjs-prefix-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testPrefixStore(t, commonjs.NewDiskStore(dir))
+}
+
+func TestPrefixStoreApp(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewPrefixStore("app", commonjs.NewMemoryStore()),
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("mname", []byte("js"))},
+	}
+	u, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "/r/56cc634.js" {
+		t.Fatalf("was expecting the prefix to not change the url, got %s", u)
+	}
+}
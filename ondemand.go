@@ -0,0 +1,41 @@
+package commonjs
+
+import (
+	"net/http"
+	"path"
+)
+
+// The file name under the MountPath which builds packages on demand when
+// OnDemand is enabled, for example /r/pkg.js?m=a/foo,b/baz.
+const OnDemandName = "pkg.js"
+
+// Builds, or finds the cached package for the comma separated modules in the
+// "m" query parameter and redirects to its hashed URL.
+func (a *App) serveOnDemand(w http.ResponseWriter, r *http.Request) {
+	modules := queryModules(r)
+	if len(modules) == 0 {
+		a.serveError(w, r, 400, "no modules specified", nil)
+		return
+	}
+	for _, name := range modules {
+		if err := a.validateRequestName(name); err != nil {
+			a.serveError(w, r, 400, err.Error(), err)
+			return
+		}
+	}
+	url, err := a.ModulesURLContext(r.Context(), modules)
+	if err != nil {
+		if IsNotFound(err) {
+			a.serveError(w, r, 404, err.Error(), err)
+			return
+		}
+		a.serveError(w, r, 500, "error building package", err)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// Check if the request is for an on demand package.
+func (a *App) isOnDemand(urlPath string) bool {
+	return a.OnDemand && urlPath == path.Join("/", a.MountPath, OnDemandName)
+}
@@ -0,0 +1,71 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestOnDemand(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		OnDemand:     true,
+	}
+	expected, err := app.ModulesURL([]string{"a/foo", "b/baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/r/pkg.js?m=a/foo,b/baz", nil)
+	app.ServeHTTP(w, r)
+	if w.Code != http.StatusFound {
+		t.Fatalf("was expecting a 302, got %d", w.Code)
+	}
+	if location := w.Header().Get("Location"); location != expected {
+		t.Fatalf("expected redirect to %s got %s", expected, location)
+	}
+}
+
+func TestOnDemandErrors(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		OnDemand:     true,
+	}
+	cases := map[string]int{
+		"/r/pkg.js":                  400,
+		"/r/pkg.js?m=":               400,
+		"/r/pkg.js?m=does-not-exist": 404,
+	}
+	for url, code := range cases {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", url, nil)
+		app.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("for %s was expecting %d, got %d", url, code, w.Code)
+		}
+	}
+}
+
+func TestOnDemandDisabled(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/r/pkg.js?m=a/foo", nil)
+	app.ServeHTTP(w, r)
+	if w.Code != 404 {
+		t.Fatalf("was expecting a 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package commonjs
+
+import "fmt"
+
+// A Module may implement Originator to describe where it came from, like a
+// file path or URL. This is used in errors and debugging information.
+type Originator interface {
+	Origin() string
+}
+
+func (m *fileModule) Origin() string {
+	return m.path
+}
+
+type originModule struct {
+	Module
+	origin string
+}
+
+// Wraps another module and describes where it came from.
+func NewOriginModule(m Module, origin string) Module {
+	return &originModule{
+		Module: m,
+		origin: origin,
+	}
+}
+
+func (m *originModule) Origin() string {
+	return m.origin
+}
+
+func (m *originModule) unwrap() Module { return m.Module }
+
+// Returns the origin of a module, looking through wrapped modules, and
+// falling back to a description of the Provider that supplied it.
+func moduleOrigin(m Module, p Provider) string {
+	for w := m; w != nil; {
+		if o, ok := w.(Originator); ok {
+			return o.Origin()
+		}
+		u, ok := w.(unwrapper)
+		if !ok {
+			break
+		}
+		w = u.unwrap()
+	}
+	if p == nil {
+		return "App.Modules"
+	}
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// Returns where the named module came from, like a file path or URL.
+func (a *App) Origin(name string) (string, error) {
+	m, p, err := a.find(name)
+	if err != nil {
+		return "", err
+	}
+	return moduleOrigin(m, p), nil
+}
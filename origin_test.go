@@ -0,0 +1,45 @@
+package commonjs_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestOrigin(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath: "r",
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("inline", []byte("require('a/foo')")),
+			commonjs.NewOriginModule(commonjs.NewScriptModule("custom", nil), "custom origin"),
+		},
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	cases := map[string]string{
+		"inline": "App.Modules",
+		"custom": "custom origin",
+		"a/foo":  filepath.Join("_test", "a", "foo.js"),
+	}
+	for name, expected := range cases {
+		actual, err := app.Origin(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Fatalf("for %s expected %s got %s", name, expected, actual)
+		}
+	}
+
+	url, err := app.ModulesURL([]string{"inline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range app.BundleInfo(url).Modules {
+		if info.Origin != cases[info.Name] && cases[info.Name] != "" {
+			t.Fatalf("for %s expected %s got %s", info.Name, cases[info.Name], info.Origin)
+		}
+	}
+}
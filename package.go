@@ -0,0 +1,98 @@
+package commonjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	packageJSON = "package.json"
+	indexName   = "index"
+	nodeModules = "node_modules"
+)
+
+// Returns the name of the module a package directory refers to. This is the
+// browser or main field from the package.json content if one is available, or
+// the index module otherwise. The field must refer to a module in the package.
+func packageTarget(name string, pkg []byte) (string, error) {
+	var p struct {
+		Main    string      `json:"main"`
+		Browser interface{} `json:"browser"`
+	}
+	if pkg != nil && json.Unmarshal(pkg, &p) == nil {
+		// only the string form of the browser field is supported
+		main := p.Main
+		if browser, ok := p.Browser.(string); ok && browser != "" {
+			main = browser
+		}
+		if main != "" {
+			target := path.Join(name, strings.TrimSuffix(main, ext))
+			if target != name {
+				if !strings.HasPrefix(target, name+"/") {
+					return "", fmt.Errorf("main %q of package %s is outside the package", main, name)
+				}
+				return target, nil
+			}
+		}
+	}
+	return path.Join(name, indexName), nil
+}
+
+// Define a module for a package which simply exports the target module. Using
+// a separate module ensures relative names in the target are resolved
+// relative to the target and not the package. The target is required using a
+// relative name so the package can be renamed. The target must be in the
+// package.
+func newPackageModule(name, target string) Module {
+	rel := strings.TrimPrefix(target, name+"/")
+	content, _ := json.Marshal("./" + path.Join(path.Base(name), rel))
+	return NewScriptModule(
+		name,
+		[]byte("module.exports = require("+string(content)+")"),
+	)
+}
+
+type nodeModulesProvider struct {
+	dirs []Provider
+}
+
+// Provides modules from node_modules directories, allowing for npm installed
+// packages to be used. Names are looked up in the node_modules directory in
+// root and then in each of its parent directories. Package directories are
+// resolved using the browser or main field in their package.json, falling back
+// to their index.js.
+func NewNodeModulesProvider(root string) Provider {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		dir = root
+	}
+	p := &nodeModulesProvider{}
+	for {
+		if filepath.Base(dir) != nodeModules {
+			p.dirs = append(p.dirs,
+				NewPackageDirProvider(filepath.Join(dir, nodeModules)))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return p
+}
+
+func (p *nodeModulesProvider) Module(name string) (Module, error) {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "/") {
+		return nil, errModuleNotFound(name)
+	}
+	for _, d := range p.dirs {
+		m, err := d.Module(name)
+		if err == nil || !IsNotFound(err) {
+			return m, err
+		}
+	}
+	return nil, errModuleNotFound(name)
+}
@@ -0,0 +1,88 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNodeModulesProvider(t *testing.T) {
+	t.Parallel()
+	const expectedContent = `define("np","module.exports = require(\"np/lib/browser\")");
+define("np/lib/browser","require('np/lib/util')");
+define("np/lib/util","util");
+define("nq","module.exports = require(\"nq/index\")");
+define("nq/index","nq");
+`
+	p := &commonjs.App{
+		MountPath: "r",
+		Providers: []commonjs.Provider{
+			commonjs.NewNodeModulesProvider("_test/node/sub/dir"),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := p.ModulesURL([]string{"np", "nq"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != expectedContent {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestNodeModulesProviderNotFound(t *testing.T) {
+	t.Parallel()
+	p := commonjs.NewNodeModulesProvider("_test/node")
+	for _, name := range []string{"xyz", "./np", "np/xyz"} {
+		if _, err := p.Module(name); !commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting a not found error for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestPackageMainOutside(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"x.js":           "x",
+		"a/package.json": `{"main": "../x.js"}`,
+		"b/package.json": `{"main": "../bc/y.js"}`,
+		"bc/y.js":        "y",
+		"c/package.json": `{"main": "./lib/../lib/c.js"}`,
+		"c/lib/c.js":     "c",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := commonjs.NewPackageDirProvider(dir)
+	for _, name := range []string{"a", "b"} {
+		if _, err := p.Module(name); err == nil || commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting an error for %s, got %v", name, err)
+		}
+	}
+	m, err := p.Module("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `module.exports = require("./c/lib/c")` {
+		t.Fatalf("unexpected package module %q", content)
+	}
+}
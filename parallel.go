@@ -0,0 +1,112 @@
+package commonjs
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Runs fn for 0 to n-1 with at most BuildParallelism running at once.
+func (a *App) parallel(n int, fn func(i int)) {
+	slots := make(chan struct{}, a.BuildParallelism)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// A required module found by prefetch, along with the result of fetching its
+// content if it was fetched.
+type prefetched struct {
+	m        Module
+	p        Provider
+	err      error // from finding the module
+	fetched  bool
+	fetchErr error
+}
+
+// Finds the required modules not in the set, and fetches the content of those
+// supporting contexts like those from NewURLModule concurrently, which cache
+// it for use in the build. The results are returned by name, so the modules
+// are found and fetched once, and errors are reported in order when the
+// module is used. Returns nil without BuildParallelism.
+func (a *App) prefetch(ctx context.Context, require []string, set map[string]bool) map[string]*prefetched {
+	if a.BuildParallelism <= 1 {
+		return nil
+	}
+	found := make(map[string]*prefetched)
+	var fetch []*prefetched
+	for _, name := range require {
+		name = a.Alias(name)
+		if set[name] || found[name] != nil {
+			continue
+		}
+		f := new(prefetched)
+		found[name] = f
+		if f.m, f.p, f.err = a.findContext(ctx, name); f.err != nil {
+			continue
+		}
+		if _, ok := f.m.(ContextModule); ok && !isInlineOnly(f.m) {
+			fetch = append(fetch, f)
+		}
+	}
+	if len(fetch) < 2 {
+		return found
+	}
+	a.parallel(len(fetch), func(i int) {
+		f := fetch[i]
+		_, f.fetchErr = contentContext(ctx, f.m)
+		f.fetched = true
+	})
+	return found
+}
+
+type defineResult struct {
+	define []byte
+	info   ModuleInfo
+	err    error
+	build  *build
+}
+
+// Writes the define() calls for the named modules like writeDefines, building
+// up to BuildParallelism modules at once. The output is written in order, so
+// it is the same as a serial build.
+func (a *App) writeDefinesParallel(w io.Writer, names []string, b *build, release bool) ([]ModuleInfo, error) {
+	info := make([]ModuleInfo, len(names))
+	for start := 0; start < len(names); start += a.BuildParallelism {
+		end := start + a.BuildParallelism
+		if end > len(names) {
+			end = len(names)
+		}
+		results := make([]defineResult, end-start)
+		a.parallel(len(results), func(i int) {
+			r := &results[i]
+			r.build = b.child()
+			r.define, r.info, r.err = a.define(names[start+i], r.build)
+		})
+		// merge all results first, so their bytes are released with the build
+		for _, r := range results {
+			b.merge(r.build)
+		}
+		for i, r := range results {
+			if r.err != nil {
+				return nil, r.err
+			}
+			info[start+i] = r.info
+			if _, err := w.Write(r.define); err != nil {
+				return nil, err
+			}
+		}
+		if release {
+			b.release()
+		}
+	}
+	return info, nil
+}
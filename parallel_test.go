@@ -0,0 +1,94 @@
+package commonjs_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestBuildParallelism(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintf(w, "exports.path = %q /*! %s */", r.URL.Path, r.URL.Path)
+	}))
+	defer s.Close()
+
+	newApp := func(parallelism int) *commonjs.App {
+		app := &commonjs.App{
+			MountPath:        "r",
+			ContentStore:     commonjs.NewMemoryStore(),
+			BuildParallelism: parallelism,
+			PreserveLicenses: true,
+		}
+		var main []byte
+		for i := 0; i < 6; i++ {
+			name := fmt.Sprintf("m%d", i)
+			app.Modules = append(app.Modules,
+				commonjs.NewURLModule(name, fmt.Sprintf("%s/%s.js", s.URL, name)))
+			main = append(main, fmt.Sprintf("require('%s');", name)...)
+		}
+		app.Modules = append(app.Modules, commonjs.NewScriptModule("main", main))
+		return app
+	}
+
+	serial, err := newApp(0).ModulesURL([]string{"main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&maxInFlight); n != 1 {
+		t.Fatalf("was expecting serial fetches, got %d at once", n)
+	}
+	atomic.StoreInt32(&maxInFlight, 0)
+	parallel, err := newApp(3).ModulesURL([]string{"main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&maxInFlight); n < 2 || n > 3 {
+		t.Fatalf("was expecting up to 3 fetches at once, got %d", n)
+	}
+	if parallel != serial {
+		t.Fatalf("was expecting the same package %s, got %s", serial, parallel)
+	}
+}
+
+func TestPrefetchFailureFetchedOnce(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	app := &commonjs.App{
+		MountPath:        "r",
+		ContentStore:     commonjs.NewMemoryStore(),
+		BuildParallelism: 2,
+		Modules: []commonjs.Module{
+			commonjs.NewURLModule("m0", s.URL+"/m0.js"),
+			commonjs.NewURLModule("m1", s.URL+"/m1.js"),
+			commonjs.NewScriptModule("main", []byte("require('m0');require('m1')")),
+		},
+	}
+	if _, err := app.ModulesURL([]string{"main"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("was expecting each module to be fetched once, got %d fetches", n)
+	}
+}
@@ -0,0 +1,28 @@
+package commonjs
+
+import (
+	"strings"
+)
+
+// Applies the PostProcess transforms to the package content. The transforms
+// see the package as a single module named after the requested modules.
+// Packages are buffered when there are PostProcess transforms, since they
+// need the whole package.
+func (a *App) postProcess(modules []string, content []byte, b *build) ([]byte, error) {
+	if len(a.PostProcess) == 0 {
+		return content, nil
+	}
+	name := strings.Join(modules, ",")
+	var m Module = NewScriptModule(name, content)
+	for _, t := range a.PostProcess {
+		var err error
+		if m, err = transformContext(b.context(), t, m); err != nil {
+			return nil, buildError(OpPostProcess, name, "", nil, err)
+		}
+	}
+	content, err := contentContext(b.context(), m)
+	if err != nil {
+		return nil, buildError(OpPostProcess, name, "", nil, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,69 @@
+package commonjs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type bannerTransform string
+
+func (b bannerTransform) Transform(m commonjs.Module) (commonjs.Module, error) {
+	content, err := m.Content()
+	if err != nil {
+		return nil, err
+	}
+	return commonjs.NewScriptModule(m.Name(), append([]byte(b), content...)), nil
+}
+
+func TestPostProcess(t *testing.T) {
+	t.Parallel()
+	modules := []commonjs.Module{commonjs.NewScriptModule("a", []byte("js"))}
+	plain := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      modules,
+	}
+	processed := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      modules,
+		PostProcess:  []commonjs.Transform{bannerTransform("/*1*/"), bannerTransform("/*2*/")},
+	}
+	plainURL, err := plain.ModulesURL([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := processed.ModulesURL([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u == plainURL {
+		t.Fatal("was expecting the post processed package to have a different hash")
+	}
+	w := httptest.NewRecorder()
+	processed.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u}})
+	if body := w.Body.String(); !strings.HasPrefix(body, "/*2*//*1*/define(\"a\"") {
+		println(body)
+		t.Fatal("did not find expected post processed content")
+	}
+}
+
+func TestPostProcessError(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("a", []byte("js"))},
+		PostProcess:  []commonjs.Transform{failingTransform(0)},
+	}
+	_, err := app.ModulesURL([]string{"a"})
+	var be *commonjs.BuildError
+	if !errors.As(err, &be) || be.Op != commonjs.OpPostProcess {
+		t.Fatalf("was expecting a post process build error, got %v", err)
+	}
+}
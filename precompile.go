@@ -0,0 +1,37 @@
+package commonjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builds and stores the packages for the given entry points, along with the
+// vendor package. Doing this at startup or in CI ensures the first request
+// does not pay the build cost, and that errors surface early. Packages
+// exceeding MaxPackageSize result in a BudgetError. Modules not reachable
+// from the entry points are handled according to the Unused mode.
+func (a *App) Precompile(entrypoints [][]string) error {
+	if _, err := a.VendorURL(); err != nil {
+		return fmt.Errorf("precompiling vendor package: %w", err)
+	}
+	if len(a.Vendor) > 0 {
+		if err := a.checkBudget(packageSpec{modules: a.Vendor, vendor: true}, nil); err != nil {
+			return fmt.Errorf("precompiling vendor package: %w", err)
+		}
+	}
+	exclude, err := a.vendorSet()
+	if err != nil {
+		return err
+	}
+	for _, modules := range entrypoints {
+		if _, err := a.ModulesURL(modules); err != nil {
+			return fmt.Errorf(
+				"precompiling package for %s: %w", strings.Join(modules, ", "), err)
+		}
+		if err := a.checkBudget(packageSpec{modules: modules}, exclude); err != nil {
+			return fmt.Errorf(
+				"precompiling package for %s: %w", strings.Join(modules, ", "), err)
+		}
+	}
+	return a.checkUnused(entrypoints)
+}
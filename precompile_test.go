@@ -0,0 +1,43 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"strings"
+	"testing"
+)
+
+func TestPrecompile(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	err := a.Precompile([][]string{{"a/foo"}, {"bar"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedURL, err := a.ModulesURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.BundleInfo(expectedURL) == nil {
+		t.Fatal("was expecting the package to be built")
+	}
+}
+
+func TestPrecompileError(t *testing.T) {
+	t.Parallel()
+	a := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	err := a.Precompile([][]string{{"bar"}, {"a/foo", "xyz"}})
+	if err == nil {
+		t.Fatal("was expecting an error")
+	}
+	if !strings.Contains(err.Error(), "a/foo, xyz") {
+		t.Fatalf("was expecting the entry point in the error, got %s", err)
+	}
+}
@@ -0,0 +1,44 @@
+package commonjs
+
+import (
+	"strings"
+)
+
+type prefixProvider struct {
+	prefix   string
+	provider Provider
+}
+
+// Provides the modules from another Provider under a name prefix. For example
+// with the prefix "vendor", the module "foo" from the Provider is available
+// as "vendor/foo". This prevents name collisions between Providers. Note that
+// only relative require() calls within the modules see the prefix.
+func NewPrefixProvider(prefix string, p Provider) Provider {
+	return &prefixProvider{
+		prefix:   strings.TrimSuffix(prefix, "/") + "/",
+		provider: p,
+	}
+}
+
+func (p *prefixProvider) Module(name string) (Module, error) {
+	if !strings.HasPrefix(name, p.prefix) {
+		return nil, errModuleNotFound(name)
+	}
+	m, err := p.provider.Module(name[len(p.prefix):])
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, errModuleNotFound(name)
+		}
+		return nil, err
+	}
+	return &renamedModule{Module: m, name: name}, nil
+}
+
+type renamedModule struct {
+	Module
+	name string
+}
+
+func (m *renamedModule) Name() string {
+	return m.name
+}
@@ -0,0 +1,39 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPrefixProvider(t *testing.T) {
+	t.Parallel()
+	const expectedContent = `define("vendor/p","module.exports = require(\"vendor/p/index\")");
+define("vendor/p/index","require('vendor/p/q')");
+define("vendor/p/q","q");
+`
+	p := &commonjs.App{
+		MountPath: "r",
+		Providers: []commonjs.Provider{
+			commonjs.NewPrefixProvider("vendor", commonjs.NewPackageDirProvider("_test")),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := p.ModulesURL([]string{"vendor/p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Body.String() != expectedContent {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+	for _, name := range []string{"p", "vendorp", "vendor/xyz"} {
+		if _, err := p.Module(name); !commonjs.IsNotFound(err) {
+			t.Fatalf("was expecting a not found error for %s, got %v", name, err)
+		}
+	}
+}
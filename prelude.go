@@ -1,10 +1,17 @@
 package commonjs
 
+import "strconv"
+
+// The version of the prelude, changed along with the way it defines and
+// executes modules. The prelude exposes it as require.version.
+const PreludeVersion = 1
+
 var scriptPrelude = []byte(`
 (function(exports) {
   var _payloads = {},
       _modules = {},
       _execute = [],
+      _loading = {},
       _schedule = null;
 
   function key(name) {
@@ -55,8 +62,13 @@ var scriptPrelude = []byte(`
       throw 'module ' + name + ' not found';
     }
     delete _payloads[k];
-    fn = new Function('require', 'exports', 'module', fn);
+    if (typeof fn === 'string') {
+      fn = new Function('require', 'exports', 'module', fn);
+    }
     _modules[k] = m = { name: name, exports: {} };
+    if (require.init) {
+      require.init(m);
+    }
     fn.call(exports, require, m.exports, m);
     return m.exports;
   }
@@ -70,6 +82,56 @@ var scriptPrelude = []byte(`
     schedule();
   }
 
+  // replaces the payload of a defined module, returning the module if it had
+  // been executed
+  function redefine(name, payload) {
+    var k = key(name),
+        m = _modules[k];
+    if (!m && !(k in _payloads)) {
+      return null;
+    }
+    delete _modules[k];
+    _payloads[k] = payload;
+    return m || null;
+  }
+
+  function load(name, cb) {
+    var k = key(name);
+    if (_modules[k] || _payloads[k]) {
+      window.setTimeout(function() { cb(null, require(name)); }, 0);
+      return;
+    }
+    if (_loading[k]) {
+      _loading[k].push(cb);
+      return;
+    }
+    _loading[k] = [cb];
+
+    function done(err) {
+      var cbs = _loading[k];
+      delete _loading[k];
+      for (var i=0, l=cbs.length; i<l; i++) {
+        if (err) {
+          cbs[i](err);
+        } else {
+          cbs[i](null, require(name));
+        }
+      }
+    }
+
+    var s = document.createElement('script');
+    s.async = true;
+    s.src = require.base + name + '.js';
+    s.onload = function() { done(); };
+    s.onerror = function() { done('module ' + name + ' failed to load'); };
+    document.getElementsByTagName('head')[0].appendChild(s);
+  }
+
+  require.load = load;
+  require.redefine = redefine;
+  require.base = '/r/module/';
+  require.version = ` + strconv.Itoa(PreludeVersion) + `;
+
   exports.define = define;
   exports.require = require;
   exports.execute = execute;
@@ -77,7 +139,10 @@ var scriptPrelude = []byte(`
 `)
 
 // Returns the CommonJS/npm style prelude that provides define, require &
-// execute functions.
+// execute functions. Modules may be defined with a string payload or a
+// function(require, exports, module) factory. Modules can be loaded lazily
+// using require.load(name, cb) from require.base, which should point to
+// App.ModuleBaseURL. The prelude version is available as require.version.
 func ScriptPrelude() Module {
 	return NewScriptModule("prelude", scriptPrelude)
 }
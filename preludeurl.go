@@ -0,0 +1,105 @@
+package commonjs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// The first prelude version able to load modules defined in each OutputFormat.
+// Formats missing here, like the AMDFormat, need another loader.
+var formatPreludeVersions = map[OutputFormat]int{
+	StringFormat:   1,
+	FunctionFormat: 1,
+}
+
+// Indicates the define() calls in the OutputFormat cannot be loaded by the
+// prelude version.
+type PreludeVersionError struct {
+	Format  OutputFormat
+	Version int // the prelude version pages load
+	Needed  int // the first prelude version supporting the format, 0 if none does
+}
+
+func (e *PreludeVersionError) Error() string {
+	if e.Needed == 0 {
+		return fmt.Sprintf("output format %d cannot be loaded by the prelude", e.Format)
+	}
+	if e.Version > PreludeVersion {
+		return fmt.Sprintf("prelude version %d is newer than the current version %d", e.Version, PreludeVersion)
+	}
+	return fmt.Sprintf(
+		"output format %d needs prelude version %d, pages load version %d",
+		e.Format, e.Needed, e.Version)
+}
+
+// The prelude version pages load, which is PagePreludeVersion if set.
+func (a *App) pagePreludeVersion() int {
+	if a.PagePreludeVersion > 0 {
+		return a.PagePreludeVersion
+	}
+	return PreludeVersion
+}
+
+// Asserts the define() calls in the OutputFormat can be loaded by the prelude
+// version pages load. Formats needing another loader are only an error when
+// building the prelude itself.
+func (a *App) checkPreludeVersion(prelude bool) error {
+	version := a.pagePreludeVersion()
+	needed := formatPreludeVersions[a.OutputFormat]
+	if needed == 0 && !prelude {
+		return nil
+	}
+	if needed == 0 || version < needed || version > PreludeVersion {
+		return &PreludeVersionError{Format: a.OutputFormat, Version: version, Needed: needed}
+	}
+	return nil
+}
+
+// Returns a URL for the prelude, along with all the PreludeExtensions, the
+// HMR runtime and the live reload script when enabled, and the loader
+// configuration. Unlike the inline prelude it can be cached by browsers across
+// pages, and the URL changes with the prelude. See jsh.AppScripts
+// ExternalPrelude.
+func (a *App) PreludeURL() (string, error) {
+	a.mu.Lock()
+	url := a.preludeURL
+	a.mu.Unlock()
+	if url != "" {
+		return url, nil
+	}
+	if err := a.checkPreludeVersion(true); err != nil {
+		return "", err
+	}
+	prelude, err := a.ScriptPrelude()
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBuffer(append([]byte(nil), prelude...))
+	names := make([]string, 0, len(a.PreludeExtensions))
+	for name := range a.PreludeExtensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		content, err := a.preludeExtension(name)
+		if err != nil {
+			return "", err
+		}
+		buf.Write(content)
+	}
+	if a.HotReload {
+		buf.Write(a.hmrRuntime())
+	}
+	if a.LiveReload {
+		buf.Write(a.liveReloadScript())
+	}
+	buf.Write(a.LoaderConfig())
+	if url, err = a.storePackage(nil, buf.Bytes(), ext); err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	a.preludeURL = url
+	a.mu.Unlock()
+	return url, nil
+}
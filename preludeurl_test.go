@@ -0,0 +1,84 @@
+package commonjs_test
+
+import (
+	"fmt"
+	"github.com/daaku/go.commonjs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPreludeURL(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	u, err := app.PreludeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := (&commonjs.App{MountPath: "r", ContentStore: commonjs.NewMemoryStore()}).PreludeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != other {
+		t.Fatalf("was expecting a stable url, got %s and %s", u, other)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: u}})
+	body := w.Body.String()
+	if !strings.Contains(body, "require.version = 1;") {
+		println(body)
+		t.Fatal("did not find expected prelude version")
+	}
+	if !strings.Contains(body, "exports.define = define") {
+		println(body)
+		t.Fatal("did not find expected prelude")
+	}
+}
+
+func TestPreludeURLUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		OutputFormat: commonjs.AMDFormat,
+	}
+	_, err := app.PreludeURL()
+	if _, ok := err.(*commonjs.PreludeVersionError); !ok {
+		t.Fatalf("was expecting a PreludeVersionError, got %v", err)
+	}
+}
+
+func TestPagePreludeVersion(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:          "r",
+		ContentStore:       commonjs.NewMemoryStore(),
+		Modules:            []commonjs.Module{commonjs.NewScriptModule("foo", []byte("js"))},
+		PagePreludeVersion: commonjs.PreludeVersion + 1,
+	}
+	_, err := app.ModulesURL([]string{"foo"})
+	if _, ok := err.(*commonjs.PreludeVersionError); !ok {
+		t.Fatalf("was expecting a PreludeVersionError, got %v", err)
+	}
+	app.PagePreludeVersion = commonjs.PreludeVersion
+	if _, err := app.ModulesURL([]string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreludeExposesVersion(t *testing.T) {
+	t.Parallel()
+	content, err := commonjs.ScriptPrelude().Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("require.version = %d;", commonjs.PreludeVersion)
+	if !strings.Contains(string(content), expected) {
+		t.Fatalf("did not find %q in the prelude", expected)
+	}
+}
@@ -0,0 +1,73 @@
+package commonjs
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Pushes the URLs using HTTP/2 server push, if supported by the connection.
+// URLs on another origin, like those using a BaseURL, are skipped since they
+// cannot be pushed.
+func Push(w http.ResponseWriter, urls ...string) error {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return nil
+	}
+	for _, url := range urls {
+		if url == "" || !strings.HasPrefix(url, "/") || strings.HasPrefix(url, "//") {
+			continue
+		}
+		if err := pusher.Push(url, nil); err != nil {
+			if errors.Is(err, http.ErrNotSupported) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Options for PushModulesWithOptions.
+type PushOptions struct {
+	Prelude bool // also push the PreludeURL, for pages loading the prelude from it
+}
+
+// Pushes the Vendor package and the package for the modules using HTTP/2
+// server push, if supported by the connection. This should be called before
+// writing the response which includes the package URLs.
+func (a *App) PushModules(w http.ResponseWriter, modules []string) error {
+	return a.PushModulesWithOptions(w, modules, PushOptions{})
+}
+
+// Pushes the packages like PushModules, using the given options.
+func (a *App) PushModulesWithOptions(w http.ResponseWriter, modules []string, opts PushOptions) error {
+	var urls []string
+	if opts.Prelude {
+		prelude, err := a.PreludeURL()
+		if err != nil {
+			return err
+		}
+		urls = append(urls, prelude)
+	}
+	vendor, err := a.VendorURL()
+	if err != nil {
+		return err
+	}
+	src, err := a.ModulesURL(modules)
+	if err != nil {
+		return err
+	}
+	return Push(w, append(urls, vendor, src)...)
+}
+
+// Returns a Handler which pushes the packages for the modules before calling
+// the handler. Push errors are logged and do not prevent handling the request.
+func (a *App) PushHandler(modules []string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.PushModules(w, modules); err != nil {
+			a.log(LogWarn, "error pushing packages for %v: %s", modules, err)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
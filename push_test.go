@@ -0,0 +1,78 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestPushHandler(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("lib", []byte("exports.lib = 1")),
+			commonjs.NewScriptModule("page", []byte("require('lib')")),
+		},
+		Vendor: []string{"lib"},
+	}
+	var served bool
+	h := app.PushHandler([]string{"page"}, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) { served = true }))
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !served {
+		t.Fatal("was expecting the handler to be called")
+	}
+	vendor, _ := app.VendorURL()
+	src, _ := app.ModulesURL([]string{"page"})
+	if len(w.pushed) != 2 || w.pushed[0] != vendor || w.pushed[1] != src {
+		t.Fatalf("did not find expected pushes, found %v", w.pushed)
+	}
+}
+
+func TestPushSkipsOtherOrigins(t *testing.T) {
+	t.Parallel()
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	err := commonjs.Push(w, "https://cdn.example.com/r/a.js", "//cdn.example.com/r/b.js", "", "/r/c.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pushed) != 1 || w.pushed[0] != "/r/c.js" {
+		t.Fatalf("did not find expected pushes, found %v", w.pushed)
+	}
+	if err := commonjs.Push(httptest.NewRecorder(), "/r/c.js"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushModulesPrelude(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("page", []byte("js"))},
+	}
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := app.PushModulesWithOptions(w, []string{"page"}, commonjs.PushOptions{Prelude: true}); err != nil {
+		t.Fatal(err)
+	}
+	prelude, _ := app.PreludeURL()
+	src, _ := app.ModulesURL([]string{"page"})
+	if len(w.pushed) != 2 || w.pushed[0] != prelude || w.pushed[1] != src {
+		t.Fatalf("did not find expected pushes, found %v", w.pushed)
+	}
+}
@@ -0,0 +1,240 @@
+// Package s3store provides a commonjs.ByteStore uploading packages to object
+// storage speaking the S3 REST API, like Amazon S3, Google Cloud Storage
+// using HMAC keys, Cloudflare R2 or MinIO. Building a package with
+// ModulesURL then effectively deploys it, and App.BaseURL can point at the
+// bucket or a CDN in front of it:
+//
+//	app := &commonjs.App{
+//		MountPath:    "r",
+//		BaseURL:      "https://cdn.example.com",
+//		ContentStore: &s3store.Store{Bucket: "assets", Prefix: "r/", ...},
+//	}
+package s3store
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEndpoint     = "https://s3.amazonaws.com"
+	defaultRegion       = "us-east-1"
+	defaultCacheControl = "public, max-age=31536000, immutable"
+	defaultTimeout      = 30 * time.Second
+	amzDate             = "20060102T150405Z"
+)
+
+var defaultClient = &http.Client{Timeout: defaultTimeout}
+
+// Stores values as objects in a bucket. Values are stored under the Prefix
+// followed by the key, where Get finds them. Packages are additionally
+// published under the Prefix followed by their file name, like
+// "r/56cc634.js", which is the path they are served at under BaseURL if the
+// Prefix matches the MountPath.
+type Store struct {
+	Endpoint     string       // optional endpoint, defaults to https://s3.amazonaws.com, use https://storage.googleapis.com for GCS
+	Region       string       // optional region, defaults to us-east-1, use auto for GCS and R2
+	Bucket       string       // bucket storing the objects
+	Prefix       string       // optional prefix for the object names, like "r/"
+	AccessKey    string       // access key id, or GCS HMAC key
+	SecretKey    string       // secret access key, or GCS HMAC secret
+	SessionToken string       // optional token for temporary credentials
+	ACL          string       // optional canned ACL for published packages, like "public-read"
+	CacheControl string       // optional Cache-Control for published packages, defaults to a year and immutable
+	Client       *http.Client // optional client, defaults to one with a 30 second timeout
+}
+
+// Store the value under the key.
+func (s *Store) Store(key string, value []byte) error {
+	return s.put(key, value, nil)
+}
+
+// Get the value stored under the key. A missing value will return nil, nil.
+func (s *Store) Get(key string) ([]byte, error) {
+	req, err := s.request("GET", key, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, responseError(req, res)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Store the value under the key, and publish it under the file name it is
+// served at with the Content-Type for the extension, the Cache-Control and the
+// ACL. The published object is a server-side copy, so the value is only
+// uploaded once.
+func (s *Store) Publish(key, filename string, value []byte) error {
+	if key != filename {
+		if err := s.Store(key, value); err != nil {
+			return err
+		}
+	}
+	cacheControl := s.CacheControl
+	if cacheControl == "" {
+		cacheControl = defaultCacheControl
+	}
+	header := http.Header{"Cache-Control": {cacheControl}}
+	if contentType := mime.TypeByExtension(path.Ext(filename)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if s.ACL != "" {
+		header.Set("X-Amz-Acl", s.ACL)
+	}
+	if key == filename {
+		return s.put(filename, value, header)
+	}
+	header.Set("X-Amz-Copy-Source", (&url.URL{Path: "/" + s.Bucket + "/" + s.Prefix + key}).EscapedPath())
+	header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	return s.put(filename, nil, header)
+}
+
+func (s *Store) put(name string, value []byte, header http.Header) error {
+	req, err := s.request("PUT", name, value)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return responseError(req, res)
+	}
+	return nil
+}
+
+// Creates an unsigned request for the object with the name under the Prefix,
+// using path style URLs which all the implementations support.
+func (s *Store) request(method, name string, body []byte) (*http.Request, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + s.Bucket + "/" + s.Prefix + name
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body, req.ContentLength = nil, 0
+	}
+	return req, nil
+}
+
+func (s *Store) do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	s.sign(req, body, time.Now())
+	client := s.Client
+	if client == nil {
+		client = defaultClient
+	}
+	return client.Do(req)
+}
+
+// Signs the request using AWS Signature Version 4, covering the host and all
+// the headers set on the request.
+func (s *Store) sign(req *http.Request, body []byte, now time.Time) {
+	region := s.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	now = now.UTC()
+	date := now.Format(amzDate)
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date[:8] + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		date,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{date[:8], region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// Describes a failed request, including the error message from the response.
+func responseError(req *http.Request, res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("s3store: %s %s: %s: %s",
+		req.Method, req.URL.Path, res.Status, bytes.TrimSpace(body))
+}
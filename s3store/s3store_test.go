@@ -0,0 +1,125 @@
+package s3store_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/s3store"
+)
+
+type object struct {
+	value  []byte
+	header http.Header
+}
+
+// A fake bucket server keeping objects in memory.
+type bucket struct {
+	mu       sync.Mutex
+	objects  map[string]object
+	uploaded int // bytes uploaded
+}
+
+func (b *bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") ||
+		!strings.Contains(auth, "/auto/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Content-Sha256") == "" {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch r.Method {
+	case "PUT":
+		value, _ := ioutil.ReadAll(r.Body)
+		b.uploaded += len(value)
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			o, ok := b.objects[source]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			value = o.value
+		}
+		b.objects[r.URL.Path] = object{value: value, header: r.Header}
+	case "GET":
+		o, ok := b.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(o.value)
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	b := &bucket{objects: make(map[string]object)}
+	server := httptest.NewServer(b)
+	defer server.Close()
+	store := &s3store.Store{
+		Endpoint:  server.URL,
+		Region:    "auto",
+		Bucket:    "assets",
+		Prefix:    "r/",
+		AccessKey: "key",
+		SecretKey: "secret",
+		ACL:       "public-read",
+	}
+
+	value, err := store.Get("missing")
+	if err != nil || value != nil {
+		t.Fatalf("was expecting nil, nil for a missing value, got %q, %v", value, err)
+	}
+	if err := store.Store("k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.Get("k"); err != nil || string(value) != "v" {
+		t.Fatalf("unexpected value %q, %v", value, err)
+	}
+
+	app := &commonjs.App{
+		MountPath:    "r",
+		BaseURL:      "https://cdn.example.com",
+		ContentStore: store,
+		Modules:      []commonjs.Module{commonjs.NewScriptModule("mname", []byte("js"))},
+	}
+	u, err := app.ModulesURL([]string{"mname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://cdn.example.com/r/56cc634.js" {
+		t.Fatalf("unexpected url %s", u)
+	}
+	published, ok := b.objects["/assets/r/56cc634.js"]
+	if !ok {
+		t.Fatalf("did not find the published package in %v", b.objects)
+	}
+	if published.header.Get("X-Amz-Acl") != "public-read" ||
+		!strings.Contains(published.header.Get("Content-Type"), "javascript") ||
+		!strings.Contains(published.header.Get("Cache-Control"), "immutable") {
+		t.Fatalf("unexpected published headers %v", published.header)
+	}
+	if _, ok := b.objects["/assets/r/56cc634"]; !ok {
+		t.Fatal("did not find the package stored under its key")
+	}
+	if string(published.value) != "define(\"mname\",\"js\");\n" || b.uploaded != len(published.value)+1 {
+		t.Fatalf("was expecting the package to be uploaded once, got %q after %d bytes", published.value, b.uploaded)
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(&bucket{objects: make(map[string]object)})
+	defer server.Close()
+	store := &s3store.Store{Endpoint: server.URL, Bucket: "assets", AccessKey: "other"}
+	err := store.Store("k", []byte("v"))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Fatalf("was expecting an access denied error, got %v", err)
+	}
+}
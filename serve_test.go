@@ -0,0 +1,101 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func newServeApp(t *testing.T) (*commonjs.App, string) {
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("foo", []byte("exports.foo = 1")),
+		},
+	}
+	url, err := app.ModulesURL([]string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, url
+}
+
+func TestServeHead(t *testing.T) {
+	t.Parallel()
+	app, url := newServeApp(t)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("HEAD", url, nil))
+	if w.Code != 200 {
+		t.Fatalf("was expecting 200, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("was not expecting a body, got %s", w.Body)
+	}
+	if w.Header().Get("Content-Length") == "" {
+		t.Fatal("was expecting a Content-Length")
+	}
+}
+
+func TestServeRange(t *testing.T) {
+	t.Parallel()
+	app, url := newServeApp(t)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	full := w.Body.String()
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", url, nil)
+	r.Header.Set("Range", "bytes=0-5")
+	app.ServeHTTP(w, r)
+	if w.Code != 206 {
+		t.Fatalf("was expecting 206, got %d", w.Code)
+	}
+	if w.Body.String() != full[:6] {
+		t.Fatalf("did not find expected range, found %q", w.Body)
+	}
+}
+
+func TestServeMethodNotAllowed(t *testing.T) {
+	t.Parallel()
+	app, url := newServeApp(t)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("POST", url, nil))
+	if w.Code != 405 {
+		t.Fatalf("was expecting 405, got %d", w.Code)
+	}
+	if w.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("did not find expected Allow header, found %s", w.Header().Get("Allow"))
+	}
+}
+
+func TestServeHeaders(t *testing.T) {
+	t.Parallel()
+	app, url := newServeApp(t)
+	app.Headers = http.Header{"Access-Control-Allow-Origin": {"*"}}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	expected := map[string]string{
+		"Content-Type":                "application/javascript; charset=utf-8",
+		"X-Content-Type-Options":      "nosniff",
+		"Access-Control-Allow-Origin": "*",
+	}
+	for k, v := range expected {
+		if actual := w.Header().Get(k); actual != v {
+			t.Fatalf("was expecting %s: %s, got %s", k, v, actual)
+		}
+	}
+}
+
+func TestServeContentTypes(t *testing.T) {
+	t.Parallel()
+	app, url := newServeApp(t)
+	app.ContentTypes = map[string]string{".js": "text/javascript"}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	if actual := w.Header().Get("Content-Type"); actual != "text/javascript" {
+		t.Fatalf("did not find expected Content-Type, found %s", actual)
+	}
+}
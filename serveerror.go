@@ -0,0 +1,43 @@
+package commonjs
+
+import (
+	"net/http"
+)
+
+// Responds to a request which failed with the error.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// An error serving a request, passed to the App ErrorHandler. The Message is
+// the generic description sent to clients by default, and Err is the
+// underlying error, if any.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Responds with an error, using the ErrorHandler if one is set. Server errors
+// are logged.
+func (a *App) serveError(w http.ResponseWriter, r *http.Request, status int, message string, err error) {
+	e := &HTTPError{Status: status, Message: message, Err: err}
+	if status >= 500 {
+		a.log(LogError, "%s %s: %s", r.Method, r.URL, e)
+	}
+	if a.ErrorHandler != nil {
+		a.ErrorHandler(w, r, e)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(message + "\n"))
+}
@@ -0,0 +1,57 @@
+package commonjs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+type failingStore struct {
+	commonjs.ByteStore
+}
+
+func (failingStore) Get(key string) ([]byte, error) {
+	return nil, errors.New("store is down")
+}
+
+func TestServeStoreError(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: failingStore{commonjs.NewMemoryStore()},
+		Logger:       commonjs.NewStdLogger(nil, commonjs.LogError+1),
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/r/a77da86.js", nil))
+	if w.Code != 500 {
+		t.Fatalf("was expecting a 500, got %d", w.Code)
+	}
+	if w.Body.String() != "error retriving package from store\n" {
+		t.Fatalf("did not find expected body, found %q", w.Body)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	t.Parallel()
+	var handled *commonjs.HTTPError
+	app := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: failingStore{commonjs.NewMemoryStore()},
+		Logger:       commonjs.NewStdLogger(nil, commonjs.LogError+1),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			errors.As(err, &handled)
+			w.WriteHeader(503)
+		},
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/r/a77da86.js", nil))
+	if w.Code != 503 {
+		t.Fatalf("was expecting a 503, got %d", w.Code)
+	}
+	if handled == nil || handled.Status != 500 || handled.Err.Error() != "store is down" {
+		t.Fatalf("did not find expected error, found %v", handled)
+	}
+}
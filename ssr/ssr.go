@@ -0,0 +1,154 @@
+// Package ssr runs the modules of an App server-side using an embedded
+// JavaScript engine, so the same modules can render HTML on the server and in
+// the browser.
+package ssr
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/dop251/goja"
+)
+
+// A Runtime executes the modules of an App. Modules are loaded on first use
+// and their exports are cached for the life of the Runtime. A Runtime is safe
+// for concurrent use, but calls are serialized.
+type Runtime struct {
+	app      *commonjs.App
+	mu       sync.Mutex
+	vm       *goja.Runtime
+	modules  map[string]*goja.Object
+	payloads map[string]string
+}
+
+// Creates a Runtime for the modules of the App. The App Transform, if any, is
+// applied to the modules.
+func New(app *commonjs.App) *Runtime {
+	r := &Runtime{
+		app:      app,
+		vm:       goja.New(),
+		modules:  make(map[string]*goja.Object),
+		payloads: make(map[string]string),
+	}
+	r.vm.Set("require", r.jsRequire)
+	r.vm.Set("define", r.jsDefine)
+	return r
+}
+
+// Calls the function exported by the module with the arguments, returning the
+// result converted to a Go value.
+func (r *Runtime) Call(module, fn string, args ...interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exports, err := r.require(module)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := goja.AssertFunction(exports.Get(fn))
+	if !ok {
+		return nil, fmt.Errorf("ssr: %s.%s is not a function", module, fn)
+	}
+	values := make([]goja.Value, len(args))
+	for ix, arg := range args {
+		values[ix] = r.vm.ToValue(arg)
+	}
+	result, err := f(goja.Undefined(), values...)
+	if err != nil {
+		return nil, err
+	}
+	return result.Export(), nil
+}
+
+// Returns the exports of the module converted to a Go value.
+func (r *Runtime) Require(module string) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exports, err := r.require(module)
+	if err != nil {
+		return nil, err
+	}
+	return exports.Export(), nil
+}
+
+// Loads the module if necessary, returning its exports.
+func (r *Runtime) require(name string) (*goja.Object, error) {
+	name = r.app.Alias(name)
+	if exports, ok := r.modules[name]; ok {
+		return exports, nil
+	}
+	source, ok := r.payloads[name]
+	if ok {
+		delete(r.payloads, name)
+	} else {
+		content, err := r.content(name)
+		if err != nil {
+			return nil, err
+		}
+		source = string(content)
+	}
+	wrapped := "(function(require, exports, module) {\n" + source + "\n})"
+	v, err := r.vm.RunScript(name, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(v)
+	if !ok {
+		return nil, fmt.Errorf("ssr: module %s did not compile to a function", name)
+	}
+	module := r.vm.NewObject()
+	exports := r.vm.NewObject()
+	module.Set("exports", exports)
+	module.Set("name", name)
+	// cache before running to allow for cyclic dependencies
+	r.modules[name] = exports
+	require := func(dep string) goja.Value {
+		return r.jsRequire(commonjs.ResolveName(name, dep))
+	}
+	if _, err := fn(exports, r.vm.ToValue(require), exports, module); err != nil {
+		delete(r.modules, name)
+		return nil, err
+	}
+	final := module.Get("exports").ToObject(r.vm)
+	r.modules[name] = final
+	return final, nil
+}
+
+// The content of the module, with the App Transform applied.
+func (r *Runtime) content(name string) ([]byte, error) {
+	m, err := r.app.Module(name)
+	if err != nil {
+		return nil, err
+	}
+	if r.app.Transform != nil {
+		if m, err = r.app.Transform.Transform(m); err != nil {
+			return nil, err
+		}
+	}
+	return m.Content()
+}
+
+// The global require function. Modules are given their own require, which
+// resolves relative names against the module.
+func (r *Runtime) jsRequire(name string) goja.Value {
+	exports, err := r.require(name)
+	if err != nil {
+		if ex, ok := err.(*goja.Exception); ok {
+			panic(ex.Value())
+		}
+		panic(r.vm.NewGoError(err))
+	}
+	return exports
+}
+
+// The define function, for modules defined with a string payload like those
+// in a package. Function payloads are not supported.
+func (r *Runtime) jsDefine(name, payload string) {
+	if _, ok := r.modules[name]; ok {
+		panic(r.vm.NewTypeError("module %s already defined", name))
+	}
+	if _, ok := r.payloads[name]; ok {
+		panic(r.vm.NewTypeError("module %s already defined", name))
+	}
+	r.payloads[name] = payload
+}
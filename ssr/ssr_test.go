@@ -0,0 +1,88 @@
+package ssr_test
+
+import (
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+	"github.com/daaku/go.commonjs/ssr"
+)
+
+func TestCall(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("escape", []byte(
+				"exports.html = function(s) { return s.replace(/</g, '&lt;') }")),
+			commonjs.NewScriptModule("view", []byte(
+				"var escape = require('escape');\n"+
+					"exports.render = function(name, n) {\n"+
+					"  return '<p>' + escape.html(name) + ' ' + (n + 1) + '</p>'\n"+
+					"}")),
+		},
+	}
+	r := ssr.New(app)
+	for i := 0; i < 2; i++ {
+		actual, err := r.Call("view", "render", "<b>", 41)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != "<p>&lt;b> 42</p>" {
+			t.Fatalf("did not find expected result, found %v", actual)
+		}
+	}
+}
+
+func TestModuleExports(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("answer", []byte("module.exports = {answer: 42}")),
+		},
+	}
+	v, err := ssr.New(app).Require("answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := v.(map[string]interface{}); !ok || m["answer"] != int64(42) {
+		t.Fatalf("did not find expected exports, found %#v", v)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("broken", []byte("require('missing')")),
+			commonjs.NewScriptModule("throws", []byte("exports.fn = function() { throw new Error('boom') }")),
+		},
+	}
+	r := ssr.New(app)
+	if _, err := r.Call("broken", "fn"); err == nil {
+		t.Fatal("was expecting an error for a missing module")
+	}
+	if _, err := r.Call("throws", "fn"); err == nil {
+		t.Fatal("was expecting an error from the function")
+	}
+	if _, err := r.Call("throws", "missing"); err == nil {
+		t.Fatal("was expecting an error for a missing function")
+	}
+}
+
+func TestRelativeRequire(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("views/header", []byte("exports.title = 'header'")),
+			commonjs.NewScriptModule("views/page", []byte(
+				"var header = require('./header');\n"+
+					"exports.render = function() { return header.title }")),
+		},
+	}
+	actual, err := ssr.New(app).Call("views/page", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != "header" {
+		t.Fatalf("did not find expected result, found %v", actual)
+	}
+}
@@ -0,0 +1,79 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+var errStandaloneAMD = errors.New("standalone bundles cannot use the AMDFormat")
+
+// Provides a self contained bundle for the given modules and their
+// dependencies, including the vendor modules. The prelude is inlined and
+// scoped to the bundle, and the modules are required in order at the end.
+// This allows for the bundle to be included in any page with a single script
+// tag, for example for widgets.
+func (a *App) Standalone(modules []string) ([]byte, error) {
+	if a.OutputFormat == AMDFormat {
+		return nil, errStandaloneAMD
+	}
+	prelude, err := a.BundlePrelude(modules)
+	if err != nil {
+		return nil, err
+	}
+	b, err := a.buildLimiter().start(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer b.done()
+	content, _, err := a.content(modules, nil, b)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(bytes.Buffer)
+	out.WriteString("(function(){\nvar cjs={};\n(function(){\n")
+	out.Write(prelude)
+	out.WriteString("\n}).call(cjs);\n(function(define,require){\n")
+	out.Write(content)
+	for _, name := range modules {
+		tmp, err := json.Marshal(a.Alias(name))
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("require(")
+		out.Write(tmp)
+		out.WriteString(");\n")
+	}
+	out.WriteString("})(cjs.define,cjs.require);\n})();\n")
+	return out.Bytes(), nil
+}
+
+// Returns a URL for the Standalone bundle for the given modules. The URLs are
+// cached, but unlike ModulesURL they are not included in manifests.
+func (a *App) StandaloneURL(modules []string) (string, error) {
+	key := packageSpec{modules: modules}.key()
+	a.mu.Lock()
+	url, ok := a.standaloneURLs[key]
+	a.mu.Unlock()
+	if ok {
+		return url, nil
+	}
+
+	content, err := a.Standalone(modules)
+	if err != nil {
+		return "", err
+	}
+	if url, err = a.storePackage(modules, content, ext); err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	if a.standaloneURLs == nil {
+		a.standaloneURLs = make(map[string]string)
+	}
+	a.standaloneURLs[key] = url
+	a.mu.Unlock()
+	return url, nil
+}
@@ -0,0 +1,53 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestStandalone(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+		Vendor:       []string{"bar"},
+	}
+	content, err := app.Standalone([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"define(\"bar\",", "define(\"b/baz\",", "require(\"a/foo\");\n"} {
+		if !bytes.Contains(content, []byte(expected)) {
+			println(string(content))
+			t.Fatalf("did not find %s in content above", expected)
+		}
+	}
+
+	actualURL, err := app.StandaloneURL([]string{"a/foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if !bytes.Equal(w.Body.Bytes(), content) {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestStandaloneAMD(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		OutputFormat: commonjs.AMDFormat,
+	}
+	if _, err := app.Standalone([]string{"a/foo"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
@@ -0,0 +1,56 @@
+package commonjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// The state of an App, consisting of the package URL cache and optionally the
+// package content.
+type state struct {
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Packages    []manifestPackage `json:"packages"`
+	Content     map[string][]byte `json:"content,omitempty"`
+}
+
+// Writes the package URL cache to w, so it can be restored using LoadState
+// after a restart to avoid rebuilding packages. If SaveContent is set the
+// package content from the ContentStore is included, which is useful for
+// stores that do not persist across restarts.
+func (a *App) SaveState(w io.Writer) error {
+	s := state{Fingerprint: a.BuildFingerprint(), Packages: a.manifestPackages()}
+	if a.SaveContent {
+		s.Content = make(map[string][]byte)
+		for _, p := range s.Packages {
+			key, ok := a.route(p.URL)
+			if !ok {
+				return fmt.Errorf("invalid package url %s", p.URL)
+			}
+			content, err := a.storedContent(key)
+			if err != nil {
+				return err
+			}
+			if content != nil {
+				s.Content[key] = content
+			}
+		}
+	}
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Restores the state written by SaveState. Any included package content is
+// put in the ContentStore, and packages missing from the ContentStore are
+// ignored so they will be rebuilt when requested.
+func (a *App) LoadState(r io.Reader) error {
+	var s state
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return err
+	}
+	for key, content := range s.Content {
+		if err := a.ContentStore.Store(key, content); err != nil {
+			return err
+		}
+	}
+	return a.loadPackages(s.Packages, s.Fingerprint, true)
+}
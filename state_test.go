@@ -0,0 +1,54 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"github.com/daaku/go.commonjs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	t.Parallel()
+	for _, saveContent := range []bool{true, false} {
+		a := &commonjs.App{
+			MountPath:    "r",
+			Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+			ContentStore: commonjs.NewMemoryStore(),
+			SaveContent:  saveContent,
+		}
+		expectedURL, err := a.ModulesURL([]string{"bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		if err := a.SaveState(buf); err != nil {
+			t.Fatal(err)
+		}
+
+		// without providers a package can only come from the state
+		b := &commonjs.App{MountPath: "r", ContentStore: commonjs.NewMemoryStore()}
+		if err := b.LoadState(buf); err != nil {
+			t.Fatal(err)
+		}
+		actualURL, err := b.ModulesURL([]string{"bar"})
+		if !saveContent {
+			if !commonjs.IsNotFound(err) {
+				t.Fatalf("was expecting a not found error, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actualURL != expectedURL {
+			t.Fatalf("expected %s got %s", expectedURL, actualURL)
+		}
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+		if w.Body.String() != "define(\"bar\",\"bar\");\n" {
+			t.Fatalf("did not find expected content, found %s", w.Body.String())
+		}
+	}
+}
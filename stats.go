@@ -0,0 +1,139 @@
+package commonjs
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Size statistics for a module.
+type ModuleStats struct {
+	Name     string
+	Raw      int // size of the module content
+	Minified int // size after Transform, which usually minifies
+	Gzipped  int // gzipped size after Transform
+}
+
+// Size statistics for a package.
+type PackageStats struct {
+	Modules  []ModuleStats
+	Raw      int // total raw size of the modules
+	Minified int // total size of the modules after Transform
+	Gzipped  int // gzipped size of the entire package
+}
+
+// Indicates a package exceeds MaxPackageSize.
+type BudgetError struct {
+	Modules []string // modules the package was built for
+	Size    int      // gzipped size of the package
+	Limit   int
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf(
+		"package for %s is %d bytes gzipped, exceeding the budget of %d bytes",
+		strings.Join(e.Modules, ", "), e.Size, e.Limit)
+}
+
+// Returns size statistics for the package containing the given modules and
+// their dependencies, excluding the Vendor modules as in ModulesURL. The
+// package built by the App is used if there is one.
+func (a *App) Stats(modules []string) (*PackageStats, error) {
+	exclude, err := a.vendorSet()
+	if err != nil {
+		return nil, err
+	}
+	return a.packageStats(packageSpec{modules: modules}, exclude)
+}
+
+func (a *App) packageStats(spec packageSpec, exclude map[string]bool) (*PackageStats, error) {
+	content, info, err := a.builtPackage(spec)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		b, err := a.buildLimiter().start(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		defer b.done()
+		if content, info, err = a.content(spec.modules, exclude, b); err != nil {
+			return nil, err
+		}
+	}
+	stats := &PackageStats{
+		Modules: make([]ModuleStats, len(info)),
+		Gzipped: gzipSize(content),
+	}
+	for ix, mi := range info {
+		stats.Modules[ix] = ModuleStats{
+			Name:     mi.Name,
+			Raw:      mi.RawSize,
+			Minified: mi.Size,
+			Gzipped:  mi.Gzipped,
+		}
+		stats.Raw += mi.RawSize
+		stats.Minified += mi.Size
+	}
+	return stats, nil
+}
+
+// The stored content and module information for the package built by the App
+// for the spec, or nil if there is none.
+func (a *App) builtPackage(spec packageSpec) ([]byte, []ModuleInfo, error) {
+	a.mu.Lock()
+	entry := a.packageURLs[fingerprintKey(a.BuildFingerprint(), spec)]
+	var bundle *BundleInfo
+	if entry != nil {
+		bundle = a.bundles[entry.url]
+	}
+	a.mu.Unlock()
+	if bundle == nil {
+		return nil, nil, nil
+	}
+	key, ok := a.route(bundle.URL)
+	if !ok {
+		return nil, nil, nil
+	}
+	content, err := a.storedContent(key)
+	return content, bundle.Modules, err
+}
+
+// Checks the package for the spec against MaxPackageSize.
+func (a *App) checkBudget(spec packageSpec, exclude map[string]bool) error {
+	if a.MaxPackageSize <= 0 {
+		return nil
+	}
+	stats, err := a.packageStats(spec, exclude)
+	if err != nil {
+		return err
+	}
+	if stats.Gzipped > a.MaxPackageSize {
+		return &BudgetError{Modules: spec.modules, Size: stats.Gzipped, Limit: a.MaxPackageSize}
+	}
+	return nil
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// The gzipped size of the content.
+func gzipSize(content []byte) int {
+	var c byteCounter
+	w := gzipWriters.Get().(*gzip.Writer)
+	w.Reset(&c)
+	w.Write(content)
+	w.Close()
+	gzipWriters.Put(w)
+	return int(c)
+}
+
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,91 @@
+package commonjs_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("page", []byte("require('widget');   ")),
+			commonjs.NewScriptModule("widget", []byte(strings.Repeat("x", 1000))),
+		},
+		Transform: commonjs.NewDefineTransform(map[string]string{"x": "y"}),
+	}
+	stats, err := app.Stats([]string{"page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Modules) != 2 || stats.Modules[1].Name != "widget" {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+	if stats.Raw != 1021 || stats.Minified != 1021 {
+		t.Fatalf("unexpected totals %v", stats)
+	}
+	if stats.Gzipped <= 0 || stats.Gzipped >= stats.Raw {
+		t.Fatalf("unexpected gzipped size %d", stats.Gzipped)
+	}
+}
+
+func TestPrecompileBudget(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("small", []byte("a")),
+			commonjs.NewScriptModule("large", incompressible(1000)),
+		},
+		ContentStore:   commonjs.NewMemoryStore(),
+		MaxPackageSize: 60,
+	}
+	if err := app.Precompile([][]string{{"small"}}); err != nil {
+		t.Fatal(err)
+	}
+	err := app.Precompile([][]string{{"large"}})
+	var budget *commonjs.BudgetError
+	if !errors.As(err, &budget) || budget.Limit != 60 || budget.Size <= 60 {
+		t.Fatalf("was expecting a budget error, got %v", err)
+	}
+}
+
+func TestPrecompileBudgetReusesBuild(t *testing.T) {
+	t.Parallel()
+	transform := &countingTransform{}
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("page", []byte("require('widget')")),
+			commonjs.NewScriptModule("widget", []byte("widget")),
+		},
+		ContentStore:   commonjs.NewMemoryStore(),
+		Transform:      transform,
+		MaxPackageSize: 1000,
+	}
+	if err := app.Precompile([][]string{{"page"}}); err != nil {
+		t.Fatal(err)
+	}
+	if transform.count != 2 {
+		t.Fatalf("was expecting each module to be transformed once, got %d", transform.count)
+	}
+	stats, err := app.Stats([]string{"page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transform.count != 2 || stats.Raw != 23 || stats.Modules[1].Gzipped <= 0 {
+		t.Fatalf("unexpected stats %v after %d transforms", stats, transform.count)
+	}
+}
+
+// Content that does not compress well.
+func incompressible(n int) []byte {
+	content := make([]byte, n)
+	seed := uint32(1)
+	for ix := range content {
+		seed = seed*1664525 + 1013904223
+		content[ix] = 'a' + byte(seed>>24)%26
+	}
+	return content
+}
@@ -0,0 +1,194 @@
+package commonjs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ByteStore that keeps values in files. The App serves such values using
+// http.ServeContent rather than loading them into memory.
+type FileStore interface {
+	ByteStore
+
+	// Open the file for a stored value. A missing value will return nil, nil.
+	Open(key string) (*os.File, error)
+}
+
+// A ByteStore which publishes packages under the file name they are served
+// at, like stores uploading to object storage for use with BaseURL. The App
+// calls Publish instead of Store for the packages it builds, and the value
+// must then be available using Get with the key.
+type PublishStore interface {
+	ByteStore
+
+	// Store the value with the given key, and publish it with the file name,
+	// including the extension, it is served at under the MountPath.
+	Publish(key, filename string, value []byte) error
+}
+
+type diskStore struct {
+	dir      string
+	mu       sync.Mutex
+	accessed map[string]time.Time
+}
+
+// Provides a ByteStore that keeps values as files in the given directory.
+func NewDiskStore(dirname string) ByteStore {
+	return &diskStore{dir: dirname, accessed: make(map[string]time.Time)}
+}
+
+// Records the access to the key, in memory to leave the modification time
+// of the files unchanged for serving.
+func (s *diskStore) access(key string) {
+	s.mu.Lock()
+	s.accessed[key] = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *diskStore) Store(key string, value []byte) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return s.rename(f.Name(), key)
+}
+
+func (s *diskStore) Get(key string) ([]byte, error) {
+	value, err := ioutil.ReadFile(s.filename(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err == nil {
+		s.access(key)
+	}
+	return value, err
+}
+
+func (s *diskStore) Open(key string) (*os.File, error) {
+	f, err := os.Open(s.filename(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err == nil {
+		s.access(key)
+	}
+	return f, err
+}
+
+func (s *diskStore) filename(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key)+ext)
+}
+
+// Moves the temporary file into place for the key, which may contain slashes
+// when namespaced using NewPrefixStore.
+func (s *diskStore) rename(tmp, key string) error {
+	filename := s.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return err
+	}
+	s.access(key)
+	return nil
+}
+
+func (s *diskStore) DeletePrefix(prefix string) (int, error) {
+	return s.deleteWhere(func(key string, info os.FileInfo) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// Deletes the values last accessed before the time, except for the kept keys.
+// Accesses are tracked in memory, so values not accessed since the store was
+// created use the time they were stored.
+func (s *diskStore) GC(before time.Time, keep map[string]bool) (int, error) {
+	return s.deleteWhere(func(key string, info os.FileInfo) bool {
+		if keep[key] {
+			return false
+		}
+		s.mu.Lock()
+		accessed, ok := s.accessed[key]
+		s.mu.Unlock()
+		if !ok {
+			accessed = info.ModTime()
+		}
+		return accessed.Before(before)
+	})
+}
+
+// Deletes the values whose key and file match, returning the number deleted.
+func (s *diskStore) deleteWhere(match func(key string, info os.FileInfo) bool) (int, error) {
+	deleted := 0
+	err := filepath.Walk(s.dir, func(filename string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, filename)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimSuffix(filepath.ToSlash(rel), ext)
+		if !match(key, info) {
+			return nil
+		}
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		delete(s.accessed, key)
+		s.mu.Unlock()
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *diskStore) Create() (StoreWriter, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	return &diskStoreWriter{File: f, store: s}, nil
+}
+
+type diskStoreWriter struct {
+	*os.File
+	store *diskStore
+}
+
+func (w *diskStoreWriter) Commit(key string) error {
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	return w.store.rename(w.Name(), key)
+}
+
+func (w *diskStoreWriter) Abort() error {
+	w.File.Close()
+	return os.Remove(w.Name())
+}
@@ -0,0 +1,77 @@
+package commonjs_test
+
+import (
+	"github.com/daaku/go.commonjs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestDiskStore(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "commonjs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := commonjs.NewDiskStore(dir)
+	if err := s.Store("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	value, err := s.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "bar" {
+		t.Fatalf("did not find expected value, found %s", value)
+	}
+	value, err = s.Get("baz")
+	if value != nil || err != nil {
+		t.Fatal("was expecting nil, nil for a missing value")
+	}
+}
+
+func TestAppDiskStoreRange(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "commonjs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewDiskStore(dir),
+	}
+	actualURL, err := p.ModulesURL([]string{"bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{
+		URL:    &url.URL{Path: actualURL},
+		Header: http.Header{"Range": {"bytes=0-5"}},
+	})
+	if w.Code != 206 {
+		t.Fatalf("was expecting a 206, got %d", w.Code)
+	}
+	if w.Body.String() != "define" {
+		t.Fatalf("did not find expected content, found %s", w.Body.String())
+	}
+}
+
+func TestAppDiskStoreNotFound(t *testing.T) {
+	t.Parallel()
+	p := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewDiskStore("_test/nonexistent"),
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, &http.Request{URL: &url.URL{Path: "/r/d613ea9.js"}})
+	if w.Code != 404 {
+		t.Fatalf("was expecting a 404, got %d", w.Code)
+	}
+}
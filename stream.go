@@ -0,0 +1,125 @@
+package commonjs
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// A ByteStore which can store values as they are written, allowing packages
+// to be built without buffering them entirely in memory.
+type StreamStore interface {
+	ByteStore
+
+	// Create a writer for a new value, stored once the key is known.
+	Create() (StoreWriter, error)
+}
+
+// Writes a value to a StreamStore. Either Commit or Abort must be called.
+type StoreWriter interface {
+	io.Writer
+
+	// Store the written value under the key.
+	Commit(key string) error
+
+	// Discard the written value.
+	Abort() error
+}
+
+// A package which was built and stored.
+type builtPackage struct {
+	url       string
+	integrity string
+	size      int
+	info      []ModuleInfo
+}
+
+// Builds the package in memory, then stores it.
+func (a *App) bufferPackage(modules []string, exclude map[string]bool, b *build) (*builtPackage, error) {
+	content, info, err := a.content(modules, exclude, b)
+	if err != nil {
+		return nil, err
+	}
+	url, err := a.storePackage(modules, content, ext)
+	if err != nil {
+		return nil, err
+	}
+	return &builtPackage{
+		url:       url,
+		integrity: Integrity(content),
+		size:      len(content),
+		info:      info,
+	}, nil
+}
+
+// Builds the package writing it to the store one module at a time, so only a
+// single module is buffered at once. The header cannot include licenses, since
+// they are only known once all modules are written.
+func (a *App) streamPackage(s StreamStore, modules []string, exclude map[string]bool, b *build) (*builtPackage, error) {
+	names, err := a.packageModules(modules, exclude, b)
+	if err != nil {
+		return nil, err
+	}
+	sw, err := s.Create()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := a.BuildFingerprint()
+	h := a.contentHash(fingerprint)
+	sri := sha512.New384()
+	c := &sizeWriter{Writer: io.MultiWriter(sw, h, sri)}
+	info, err := a.streamDefines(c, names, b)
+	if err != nil {
+		sw.Abort()
+		return nil, err
+	}
+	key := hashKey(h, a.hashLength())
+	name, err := a.packageName(key, modules)
+	if err != nil {
+		sw.Abort()
+		return nil, err
+	}
+	if err := sw.Commit(key); err != nil {
+		return nil, err
+	}
+	a.storedKey(key, fingerprint)
+	return &builtPackage{
+		url:       a.packagePath(name + ext),
+		integrity: integrity(sri.Sum(nil)),
+		size:      c.n,
+		info:      info,
+	}, nil
+}
+
+// Writes the header and the define() calls for the named modules.
+func (a *App) streamDefines(w io.Writer, names []string, b *build) ([]ModuleInfo, error) {
+	if _, err := w.Write(a.header(b)); err != nil {
+		return nil, err
+	}
+	info, err := a.writeDefines(w, names, b, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b.bootstrap); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// The key for the hashed content.
+func hashKey(h hash.Hash, length int) string {
+	return hex.EncodeToString(h.Sum(nil))[:length]
+}
+
+// Counts the bytes written.
+type sizeWriter struct {
+	io.Writer
+	n int
+}
+
+func (c *sizeWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,57 @@
+package commonjs_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestStreamPackage(t *testing.T) {
+	t.Parallel()
+	modules := []commonjs.Module{
+		commonjs.NewScriptModule("a", []byte(strings.Repeat("a", 600))),
+		commonjs.NewScriptModule("b", []byte(strings.Repeat("b", 600))),
+		commonjs.NewScriptModule("main", []byte("require('a');require('b')")),
+	}
+	memory := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules:      modules,
+		Banner:       "v1",
+	}
+	disk := &commonjs.App{
+		MountPath:    "r",
+		ContentStore: commonjs.NewDiskStore(t.TempDir()),
+		Modules:      modules,
+		Banner:       "v1",
+		// smaller than the package, but larger than any one module
+		MaxBuildBytes: 1000,
+	}
+	expected, err := memory.ModulesURL([]string{"main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := disk.ModulesURL([]string{"main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != expected {
+		t.Fatalf("was expecting %s, got %s", expected, actual)
+	}
+	expectedManifest, err := memory.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualManifest, err := disk.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actualManifest, expectedManifest) {
+		t.Fatalf("was expecting manifest %s, got %s", expectedManifest, actualManifest)
+	}
+	if n := disk.BuildStats().Bytes; n != 0 {
+		t.Fatalf("was expecting no buffered bytes, got %d", n)
+	}
+}
@@ -0,0 +1,168 @@
+package commonjs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const styleExt = ".css"
+
+var reImport = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)['"]?\s*\)?[^;]*;`)
+
+type styleContentModule struct {
+	Module
+}
+
+// Wraps a module where the content is CSS. The @import rules are the
+// dependencies of the module.
+func NewStyleContentModule(m Module) Module {
+	return &styleContentModule{Module: m}
+}
+
+func (m *styleContentModule) Require() ([]string, error) {
+	content, err := m.Module.Content()
+	if err != nil {
+		return nil, err
+	}
+	return ParseImport(content)
+}
+
+func (m *styleContentModule) Ext() string {
+	return cssExt
+}
+
+func (m *styleContentModule) unwrap() Module { return m.Module }
+
+// Find all imported stylesheets in the given CSS content. External imports,
+// like those with a scheme, are not included.
+func ParseImport(content []byte) ([]string, error) {
+	var l []string
+	for _, match := range reImport.FindAllSubmatch(content, -1) {
+		if name := string(match[1]); !isExternalImport(name) {
+			l = append(l, name)
+		}
+	}
+	return l, nil
+}
+
+// Check if the imported stylesheet is not a module.
+func isExternalImport(name string) bool {
+	return strings.HasPrefix(name, "//") || strings.Contains(name, ":")
+}
+
+// Returns a URL for the stylesheet combining the given CSS modules and their
+// imports. Imports are included before the modules importing them, and
+// external imports are moved to the top. The URLs are cached, but unlike
+// ModulesURL they are not included in manifests.
+func (a *App) StylesURL(modules []string) (string, error) {
+	key := packageSpec{modules: modules}.key()
+	a.mu.Lock()
+	url, ok := a.styleURLs[key]
+	a.mu.Unlock()
+	if ok {
+		return url, nil
+	}
+
+	content, err := a.Styles(modules)
+	if err != nil {
+		return "", err
+	}
+	if url, err = a.storePackage(modules, content, styleExt); err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	if a.styleURLs == nil {
+		a.styleURLs = make(map[string]string)
+	}
+	a.styleURLs[key] = url
+	a.mu.Unlock()
+	return url, nil
+}
+
+// Provides the stylesheet combining the given CSS modules and their imports,
+// with Transform applied.
+func (a *App) Styles(modules []string) ([]byte, error) {
+	b, err := a.buildLimiter().start(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer b.done()
+	s := &styleBuild{
+		app:   a,
+		build: b,
+		seen:  make(map[string]bool),
+		body:  new(bytes.Buffer),
+	}
+	for _, name := range modules {
+		if err := s.add(a.Alias(name)); err != nil {
+			return nil, err
+		}
+	}
+	out := new(bytes.Buffer)
+	for _, rule := range s.external {
+		out.Write(rule)
+		out.WriteString("\n")
+	}
+	out.Write(s.body.Bytes())
+	return out.Bytes(), nil
+}
+
+type styleBuild struct {
+	app      *App
+	build    *build
+	seen     map[string]bool
+	external [][]byte
+	body     *bytes.Buffer
+}
+
+// Adds the named module after its imports.
+func (s *styleBuild) add(name string) error {
+	if s.seen[name] {
+		return nil
+	}
+	s.seen[name] = true
+	m, err := s.app.Module(name)
+	if err != nil {
+		return err
+	}
+	if ext := strings.TrimPrefix(m.Ext(), "."); ext != cssExt {
+		return fmt.Errorf("module %s is not a stylesheet", name)
+	}
+	if s.app.Transform != nil {
+		if m, err = s.app.Transform.Transform(m); err != nil {
+			return err
+		}
+	}
+	content, err := m.Content()
+	if err != nil {
+		return err
+	}
+	if err = s.build.grow(len(content)); err != nil {
+		return err
+	}
+	var imports []string
+	content = reImport.ReplaceAllFunc(content, func(rule []byte) []byte {
+		imported := string(reImport.FindSubmatch(rule)[1])
+		if isExternalImport(imported) {
+			s.external = append(s.external, rule)
+		} else {
+			imports = append(imports, imported)
+		}
+		return nil
+	})
+	for _, imported := range imports {
+		if err := s.add(s.app.Alias(ResolveName(name, imported))); err != nil {
+			return err
+		}
+	}
+	if content, err = s.app.rewriteAssets(name, content, reStyleURL, resolveStyleURL); err != nil {
+		return err
+	}
+	s.body.Write(bytes.TrimSpace(content))
+	s.body.WriteString("\n")
+	return nil
+}
@@ -0,0 +1,64 @@
+package commonjs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestParseImport(t *testing.T) {
+	t.Parallel()
+	content := []byte(`@import "a.css"; @import url('./b.css') screen; @import url(//x/c.css);`)
+	actual, err := commonjs.ParseImport(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a.css", "./b.css"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v got %v", expected, actual)
+	}
+}
+
+func TestStyles(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider("_test")},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	actualURL, err := app.StylesURL([]string{"css/main.css"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, &http.Request{URL: &url.URL{Path: actualURL}})
+	if w.Code != 200 {
+		t.Fatalf("was expecting a 200, got %d for %s", w.Code, actualURL)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/css; charset=utf-8" {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	expected := `@import url("https://fonts.example.com/a.css");
+* { padding: 0; }
+body { margin: 0; }
+.main { color: red; }
+`
+	if w.Body.String() != expected {
+		println(w.Body.String())
+		t.Fatal("did not find expected content, instead found content above")
+	}
+}
+
+func TestStylesNotStylesheet(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Providers: []commonjs.Provider{commonjs.NewDirProvider("_test")},
+	}
+	if _, err := app.Styles([]string{"a/foo"}); err == nil {
+		t.Fatal("was expecting an error")
+	}
+}
@@ -0,0 +1,207 @@
+package commonjs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errTieredStoreClosed = errors.New("TieredStore is closed")
+
+// Controls when a TieredStore writes values to the slow store.
+type WriteMode int
+
+const (
+	WriteThrough WriteMode = iota // write to the slow store before Store returns
+	WriteBack                     // write to the slow store in the background
+)
+
+// Options for NewTieredStoreWithOptions.
+type TieredOptions struct {
+	Mode    WriteMode                   // optional write mode, defaults to WriteThrough
+	Queue   int                         // optional number of values waiting for WriteBack before Store blocks, defaults to 64
+	OnError func(key string, err error) // optional handler for WriteBack failures, which are also returned by Flush
+}
+
+// A ByteStore reading and writing through a fast store, like the one from
+// NewMemoryStore, in front of a slow but persistent one, like a disk, Redis
+// or object storage.
+type TieredStore struct {
+	fast, slow ByteStore
+	opts       TieredOptions
+	queue      chan tieredWrite
+	mu         sync.Mutex
+	pending    int           // queued values not yet written to the slow store
+	idle       chan struct{} // closed once there are no pending values
+	closed     bool
+	err        error
+	closeOnce  sync.Once
+	done       chan struct{}
+}
+
+type tieredWrite struct {
+	key, filename string
+	value         []byte
+}
+
+// Provides a write-through TieredStore.
+func NewTieredStore(fast, slow ByteStore) *TieredStore {
+	return NewTieredStoreWithOptions(fast, slow, TieredOptions{})
+}
+
+// Provides a TieredStore with the given options. App.Close flushes the values
+// queued for WriteBack, since the store implements StoreFlusher, and Close
+// stops the background writer.
+func NewTieredStoreWithOptions(fast, slow ByteStore, opts TieredOptions) *TieredStore {
+	s := &TieredStore{fast: fast, slow: slow, opts: opts, done: make(chan struct{})}
+	if opts.Mode == WriteBack {
+		size := opts.Queue
+		if size <= 0 {
+			size = 64
+		}
+		s.queue = make(chan tieredWrite, size)
+		go s.run()
+	} else {
+		close(s.done)
+	}
+	return s
+}
+
+// Store the value in the fast store, and in the slow store according to the
+// write mode.
+func (s *TieredStore) Store(key string, value []byte) error {
+	return s.write(tieredWrite{key: key, value: value})
+}
+
+// Store the value in the fast store, and publish it in the slow store if it is
+// a PublishStore, according to the write mode.
+func (s *TieredStore) Publish(key, filename string, value []byte) error {
+	return s.write(tieredWrite{key: key, filename: filename, value: value})
+}
+
+// Get the value from the fast store, falling back to the slow store and
+// keeping values found there in the fast store.
+func (s *TieredStore) Get(key string) ([]byte, error) {
+	value, err := s.fast.Get(key)
+	if err != nil || value != nil {
+		return value, err
+	}
+	if value, err = s.slow.Get(key); err != nil || value == nil {
+		return value, err
+	}
+	if err := s.fast.Store(key, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *TieredStore) write(w tieredWrite) error {
+	if err := s.fast.Store(w.key, w.value); err != nil {
+		return err
+	}
+	if s.queue == nil {
+		return s.writeSlow(w)
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errTieredStoreClosed
+	}
+	s.pending++
+	s.mu.Unlock()
+	s.queue <- w
+	return nil
+}
+
+func (s *TieredStore) writeSlow(w tieredWrite) error {
+	if ps, ok := s.slow.(PublishStore); ok && w.filename != "" {
+		return ps.Publish(w.key, w.filename, w.value)
+	}
+	return s.slow.Store(w.key, w.value)
+}
+
+// Writes the queued values to the slow store.
+func (s *TieredStore) run() {
+	defer close(s.done)
+	for w := range s.queue {
+		if err := s.writeSlow(w); err != nil {
+			if s.opts.OnError != nil {
+				s.opts.OnError(w.key, err)
+			}
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+		s.mu.Lock()
+		s.pending--
+		if s.pending == 0 && s.idle != nil {
+			close(s.idle)
+			s.idle = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Waits for the pending values to be written to the slow store.
+func (s *TieredStore) wait(ctx context.Context) error {
+	s.mu.Lock()
+	if s.pending == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.idle == nil {
+		s.idle = make(chan struct{})
+	}
+	idle := s.idle
+	s.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Waits for the queued values to be written to the slow store, and flushes it
+// if it is a StoreFlusher. Returns the last WriteBack failure since the
+// previous Flush.
+func (s *TieredStore) Flush(ctx context.Context) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	err := s.err
+	s.err = nil
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if f, ok := s.slow.(StoreFlusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Flushes the queued values and stops the WriteBack. Writes after the store
+// is closed fail.
+func (s *TieredStore) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	// no writes are queued once the pending ones are done
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	err := s.Flush(ctx)
+	s.closeOnce.Do(func() {
+		if s.queue != nil {
+			close(s.queue)
+		}
+	})
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return err
+}
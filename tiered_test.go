@@ -0,0 +1,120 @@
+package commonjs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+// A ByteStore blocking writes until released, and failing them if asked to.
+type slowStore struct {
+	commonjs.ByteStore
+	release chan struct{}
+	fail    error
+	mu      sync.Mutex
+	gets    int
+}
+
+func (s *slowStore) Store(key string, value []byte) error {
+	<-s.release
+	if s.fail != nil {
+		return s.fail
+	}
+	return s.ByteStore.Store(key, value)
+}
+
+func (s *slowStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	s.gets++
+	s.mu.Unlock()
+	return s.ByteStore.Get(key)
+}
+
+func TestTieredStoreWriteThrough(t *testing.T) {
+	t.Parallel()
+	fast, slow := commonjs.NewMemoryStore(), commonjs.NewMemoryStore()
+	store := commonjs.NewTieredStore(fast, slow)
+	if err := store.Store("a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := slow.Get("a"); string(value) != "1" {
+		t.Fatalf("was expecting the value in the slow store, got %q", value)
+	}
+	if err := slow.Store("b", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.Get("b"); err != nil || string(value) != "2" {
+		t.Fatalf("unexpected value %q, %v", value, err)
+	}
+	if value, _ := fast.Get("b"); string(value) != "2" {
+		t.Fatal("was expecting the value to be kept in the fast store")
+	}
+	if value, err := store.Get("c"); err != nil || value != nil {
+		t.Fatalf("was expecting nil, nil for a missing value, got %q, %v", value, err)
+	}
+}
+
+func TestTieredStoreWriteBack(t *testing.T) {
+	t.Parallel()
+	slow := &slowStore{ByteStore: commonjs.NewMemoryStore(), release: make(chan struct{})}
+	var failed []string
+	store := commonjs.NewTieredStoreWithOptions(commonjs.NewMemoryStore(), slow, commonjs.TieredOptions{
+		Mode:    commonjs.WriteBack,
+		OnError: func(key string, err error) { failed = append(failed, key) },
+	})
+	if err := store.Store("a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.Get("a"); err != nil || string(value) != "1" {
+		t.Fatalf("was expecting the value from the fast store, got %q, %v", value, err)
+	}
+	if slow.gets != 0 {
+		t.Fatal("was not expecting a read from the slow store")
+	}
+	close(slow.release)
+	if err := store.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := slow.ByteStore.Get("a"); string(value) != "1" {
+		t.Fatalf("was expecting the value in the slow store after Flush, got %q", value)
+	}
+
+	slow.fail = errors.New("slow store failed")
+	if err := store.Store("b", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(context.Background()); err != slow.fail {
+		t.Fatalf("was expecting the write back failure, got %v", err)
+	}
+	if len(failed) != 1 || failed[0] != "b" {
+		t.Fatalf("unexpected failures %v", failed)
+	}
+}
+
+func TestTieredStoreConcurrentClose(t *testing.T) {
+	t.Parallel()
+	store := commonjs.NewTieredStoreWithOptions(
+		commonjs.NewMemoryStore(), commonjs.NewMemoryStore(),
+		commonjs.TieredOptions{Mode: commonjs.WriteBack, Queue: 1})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				store.Store("a", []byte("1"))
+				store.Flush(context.Background())
+			}
+		}()
+	}
+	if err := store.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if err := store.Store("b", []byte("2")); err == nil {
+		t.Fatal("was expecting writes to fail after close")
+	}
+}
@@ -0,0 +1,75 @@
+package commonjs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Controls how Precompile handles App.Modules and Vendor modules which are not
+// reachable from any of the entry points.
+type UnusedMode int
+
+const (
+	UnusedIgnore UnusedMode = iota // do not check for unused modules
+	UnusedWarn                     // log a warning listing the unused modules
+	UnusedFail                     // fail with an UnusedModulesError
+)
+
+// Indicates modules are not reachable from any of the entry points.
+type UnusedModulesError struct {
+	Modules []string
+}
+
+func (e *UnusedModulesError) Error() string {
+	return "unused modules: " + strings.Join(e.Modules, ", ")
+}
+
+// Returns the sorted names of the App.Modules and Vendor modules which are not
+// reachable from any of the entry points. Modules only loaded with
+// require.load should be listed as entry points, since they are not found by
+// following require() calls.
+func (a *App) UnusedModules(entrypoints [][]string) ([]string, error) {
+	used := make(map[string]bool)
+	for _, modules := range entrypoints {
+		if err := a.buildDeps(modules, used); err != nil {
+			return nil, err
+		}
+	}
+	seen := make(map[string]bool)
+	var unused []string
+	check := func(name string) {
+		name = a.Alias(name)
+		if !used[name] && !seen[name] {
+			seen[name] = true
+			unused = append(unused, name)
+		}
+	}
+	for _, m := range a.Modules {
+		check(m.Name())
+	}
+	for _, name := range a.Vendor {
+		check(name)
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+// Checks for unused modules according to the Unused mode.
+func (a *App) checkUnused(entrypoints [][]string) error {
+	if a.Unused == UnusedIgnore {
+		return nil
+	}
+	unused, err := a.UnusedModules(entrypoints)
+	if err != nil {
+		return fmt.Errorf("checking for unused modules: %w", err)
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	if a.Unused == UnusedFail {
+		return &UnusedModulesError{Modules: unused}
+	}
+	a.log(LogWarn, "unused modules: %s", strings.Join(unused, ", "))
+	return nil
+}
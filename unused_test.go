@@ -0,0 +1,43 @@
+package commonjs_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestUnusedModules(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		ContentStore: commonjs.NewMemoryStore(),
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("page", []byte("require('widget')")),
+			commonjs.NewScriptModule("widget", []byte("js")),
+			commonjs.NewScriptModule("stale", []byte("js")),
+			commonjs.NewScriptModule("old-lib", []byte("js")),
+		},
+		Vendor: []string{"old-lib"},
+	}
+	unused, err := app.UnusedModules([][]string{{"page"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unused, []string{"old-lib", "stale"}) {
+		t.Fatalf("unexpected unused modules %v", unused)
+	}
+
+	if err := app.Precompile([][]string{{"page"}}); err != nil {
+		t.Fatalf("was not expecting an error without an Unused mode, got %s", err)
+	}
+	app.Unused = commonjs.UnusedFail
+	err = app.Precompile([][]string{{"page"}})
+	var ue *commonjs.UnusedModulesError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Modules, unused) {
+		t.Fatalf("was expecting an unused modules error, got %v", err)
+	}
+	if err := app.Precompile([][]string{{"page"}, {"stale", "old-lib"}}); err != nil {
+		t.Fatal(err)
+	}
+}
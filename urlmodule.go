@@ -0,0 +1,377 @@
+package commonjs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultURLTimeout    = 30 * time.Second
+	defaultURLBackoff    = 100 * time.Millisecond
+	defaultURLRetryAfter = time.Minute
+)
+
+// Options for fetching modules from a URL.
+type URLOptions struct {
+	Client   *http.Client  // optional client, defaults to http.DefaultClient
+	Timeout  time.Duration // timeout for each attempt, defaults to 30 seconds
+	Retries  int           // number of retries after the first attempt
+	Backoff  time.Duration // initial wait between retries, doubled each time
+	Fallback Module        // optional module, like a vendored copy, used when the URL fails
+
+	// How long the Fallback content is used after all attempts fail before the
+	// URL is tried again, defaults to one minute.
+	RetryAfter time.Duration
+
+	// If set, cached content older than this is revalidated using a conditional
+	// GET with the ETag and Last-Modified headers from the previous response.
+	// Stale content continues to be used if revalidation fails, and the next
+	// attempt is made after RefreshAfter.
+	RefreshAfter time.Duration
+
+	// If set, the hex encoded SHA-256 the fetched content must match.
+	SHA256 string
+}
+
+// Indicates the content fetched for a URL module did not match the expected
+// checksum.
+type ChecksumError struct {
+	Module   string
+	URL      string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("module %s from %s has sha256 %s but expected %s",
+		e.Module, e.URL, e.Actual, e.Expected)
+}
+
+// A Module may implement Refresher to allow revalidating cached content. Note
+// that packages already built by an App are not rebuilt.
+type Refresher interface {
+	Refresh() error
+}
+
+type urlModule struct {
+	name    string
+	url     string
+	ext     string
+	opts    URLOptions
+	mu      sync.Mutex
+	content []byte
+	etag    string
+	lastMod string
+	checked time.Time // when the content was last fetched or revalidation was attempted
+	local   string    // path to the vendored copy, if any
+
+	fetching *urlFetch // the fetch in progress, if any
+	fallback []byte    // the Fallback content used since failed, if any
+	failed   time.Time // when all attempts last failed
+}
+
+// A fetch in progress, shared by the callers waiting for it.
+type urlFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// A response for a single attempt to fetch a URL module.
+type urlResponse struct {
+	content     []byte
+	etag        string
+	lastMod     string
+	notModified bool
+}
+
+// Define a module where the content is pulled from a URL.
+func NewURLModule(name string, url string) Module {
+	return NewURLModuleWithOptions(name, url, URLOptions{})
+}
+
+// Define a module where the content is pulled from a URL and verified against
+// the hex encoded SHA-256 checksum.
+func NewPinnedURLModule(name string, url string, sha256 string) Module {
+	return NewURLModuleWithOptions(name, url, URLOptions{SHA256: sha256})
+}
+
+// Define a module where the content is pulled from a URL, using the given
+// options. Successfully fetched content is cached, failures are not and will
+// be retried on the next use. If a Fallback is provided, its content is used
+// when all attempts fail, until the URL is tried again after RetryAfter.
+func NewURLModuleWithOptions(name string, url string, opts URLOptions) Module {
+	return &urlModule{
+		name: name,
+		url:  url,
+		ext:  urlExt(url),
+		opts: opts,
+	}
+}
+
+func (m *urlModule) Name() string {
+	return m.name
+}
+
+func (m *urlModule) Content() ([]byte, error) {
+	return m.ContentContext(context.Background())
+}
+
+func (m *urlModule) ContentContext(ctx context.Context) ([]byte, error) {
+	m.mu.Lock()
+	content := m.content
+	fresh := m.local != "" || m.opts.RefreshAfter == 0 || time.Since(m.checked) < m.opts.RefreshAfter
+	fallback := m.fallback
+	if fallback != nil && time.Since(m.failed) >= m.retryAfter() {
+		fallback = nil
+	}
+	m.mu.Unlock()
+	if content != nil && fresh {
+		return content, nil
+	}
+	if content == nil && fallback != nil {
+		return fallback, nil
+	}
+	if err := m.refresh(ctx); err != nil {
+		if content != nil {
+			// stale content is used if revalidation fails, until the next
+			// attempt after RefreshAfter
+			m.mu.Lock()
+			m.checked = time.Now()
+			m.mu.Unlock()
+			return content, nil
+		}
+		if m.opts.Fallback == nil {
+			return nil, err
+		}
+		if fallback, err = contentContext(ctx, m.opts.Fallback); err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.fallback = fallback
+		m.failed = time.Now()
+		m.mu.Unlock()
+		return fallback, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.content, nil
+}
+
+// How long the Fallback content is used before the URL is tried again.
+func (m *urlModule) retryAfter() time.Duration {
+	if m.opts.RetryAfter == 0 {
+		return defaultURLRetryAfter
+	}
+	return m.opts.RetryAfter
+}
+
+// The extension of the URL path, ignoring any query string or fragment.
+func urlExt(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return path.Ext(rawurl)
+	}
+	return path.Ext(u.Path)
+}
+
+// The content fetched from the URL, without using the Fallback.
+func (m *urlModule) remoteContent(ctx context.Context) ([]byte, error) {
+	m.mu.Lock()
+	content := m.content
+	m.mu.Unlock()
+	if content != nil {
+		return content, nil
+	}
+	if err := m.refresh(ctx); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.content, nil
+}
+
+// Revalidates the cached content, or fetches it if necessary.
+func (m *urlModule) Refresh() error {
+	m.mu.Lock()
+	local := m.local
+	m.mu.Unlock()
+	if local != "" {
+		return nil
+	}
+	return m.refresh(context.Background())
+}
+
+// Serve the vendored copy instead of fetching the URL.
+func (m *urlModule) useVendored(filename string, content []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.local = filename
+	m.content = content
+}
+
+// Fetch or revalidate the content, updating the cached content on success. A
+// fetch already in progress is shared, and the lock is not held while
+// fetching.
+func (m *urlModule) refresh(ctx context.Context) error {
+	m.mu.Lock()
+	f := m.fetching
+	if f == nil {
+		f = &urlFetch{done: make(chan struct{})}
+		m.fetching = f
+		var prev *urlResponse
+		if m.content != nil {
+			prev = &urlResponse{etag: m.etag, lastMod: m.lastMod}
+		}
+		m.mu.Unlock()
+		resp, err := m.fetch(ctx, prev)
+		m.mu.Lock()
+		if err == nil {
+			err = m.update(resp)
+		}
+		f.err = err
+		m.fetching = nil
+		close(f.done)
+	}
+	m.mu.Unlock()
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Updates the cached content from the response, which must match the
+// checksum. Must be called with the lock held.
+func (m *urlModule) update(resp *urlResponse) error {
+	if resp.notModified {
+		m.checked = time.Now()
+		return nil
+	}
+	if m.opts.SHA256 != "" {
+		sum := sha256.Sum256(resp.content)
+		actual := hex.EncodeToString(sum[:])
+		if !strings.EqualFold(actual, m.opts.SHA256) {
+			return &ChecksumError{
+				Module:   m.name,
+				URL:      m.url,
+				Expected: m.opts.SHA256,
+				Actual:   actual,
+			}
+		}
+	}
+	m.checked = time.Now()
+	m.content = resp.content
+	m.etag = resp.etag
+	m.lastMod = resp.lastMod
+	m.fallback = nil
+	return nil
+}
+
+func (m *urlModule) Require() ([]string, error) {
+	return m.RequireContext(context.Background())
+}
+
+func (m *urlModule) RequireContext(ctx context.Context) ([]string, error) {
+	content, err := m.ContentContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRequire(content)
+}
+
+func (m *urlModule) Ext() string {
+	return m.ext
+}
+
+func (m *urlModule) Origin() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.local != "" {
+		return m.local
+	}
+	return m.url
+}
+
+// Fetch the content, retrying with backoff on failure. The requests are
+// conditional on the previous response, if any.
+func (m *urlModule) fetch(ctx context.Context, prev *urlResponse) (*urlResponse, error) {
+	backoff := m.opts.Backoff
+	if backoff == 0 {
+		backoff = defaultURLBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		resp, retry, err := m.get(ctx, prev)
+		if err == nil {
+			return resp, nil
+		}
+		if !retry || attempt >= m.opts.Retries {
+			return nil, err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+// A single attempt to fetch the content, indicating if a failure may be
+// retried.
+func (m *urlModule) get(ctx context.Context, prev *urlResponse) (*urlResponse, bool, error) {
+	timeout := m.opts.Timeout
+	if timeout == 0 {
+		timeout = defaultURLTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", m.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if prev != nil {
+		if prev.etag != "" {
+			req.Header.Set("If-None-Match", prev.etag)
+		}
+		if prev.lastMod != "" {
+			req.Header.Set("If-Modified-Since", prev.lastMod)
+		}
+	}
+	client := m.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() != context.Canceled, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		return &urlResponse{notModified: true}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status %d for %s", resp.StatusCode, m.url)
+		return nil, resp.StatusCode >= 500, err
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	return &urlResponse{
+		content: content,
+		etag:    resp.Header.Get("ETag"),
+		lastMod: resp.Header.Get("Last-Modified"),
+	}, false, nil
+}
@@ -0,0 +1,276 @@
+package commonjs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestURLModuleRetry(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte("exports.foo = 1"))
+	}))
+	defer s.Close()
+	m := commonjs.NewURLModuleWithOptions("foo", s.URL+"/foo.js", commonjs.URLOptions{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+	for i := 0; i < 2; i++ {
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "exports.foo = 1" {
+			t.Fatalf("did not find expected content, found %s", content)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("was expecting 3 requests, got %d", n)
+	}
+}
+
+func TestURLModuleNotFoundIsNotRetried(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer s.Close()
+	m := commonjs.NewURLModuleWithOptions("foo", s.URL+"/foo.js", commonjs.URLOptions{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+	if _, err := m.Content(); err == nil {
+		t.Fatal("was expecting an error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("was expecting 1 request, got %d", n)
+	}
+}
+
+func TestURLModuleTimeoutFallback(t *testing.T) {
+	t.Parallel()
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer s.Close()
+	defer close(done)
+	m := commonjs.NewURLModuleWithOptions("foo", s.URL+"/foo.js", commonjs.URLOptions{
+		Timeout:  10 * time.Millisecond,
+		Fallback: commonjs.NewScriptModule("foo", []byte("exports.local = 1")),
+	})
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "exports.local = 1" {
+		t.Fatalf("did not find expected content, found %s", content)
+	}
+}
+
+func TestURLModuleFallbackUntilRetry(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte("exports.remote = 1"))
+	}))
+	defer s.Close()
+	m := commonjs.NewURLModuleWithOptions("foo", s.URL+"/foo.js", commonjs.URLOptions{
+		Fallback:   commonjs.NewScriptModule("foo", []byte("exports.local = 1")),
+		RetryAfter: 50 * time.Millisecond,
+	})
+	for i := 0; i < 3; i++ {
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "exports.local = 1" {
+			t.Fatalf("was expecting the fallback content, found %s", content)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("was expecting the fallback to be used until the retry, got %d requests", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	content, err := m.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "exports.remote = 1" {
+		t.Fatalf("was expecting the remote content after the retry, found %s", content)
+	}
+}
+
+func TestURLModuleFetchDoesNotHoldLock(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("exports.foo = 1"))
+	}))
+	defer s.Close()
+	defer close(release)
+	m := commonjs.NewURLModule("foo", s.URL+"/foo.js")
+	go m.Content()
+	time.Sleep(10 * time.Millisecond)
+	done := make(chan string)
+	go func() { done <- m.(commonjs.Originator).Origin() }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Origin was blocked by the fetch in progress")
+	}
+}
+
+func TestURLModuleRefresh(t *testing.T) {
+	t.Parallel()
+	var requests, conditional int32
+	var version atomic.Value
+	version.Store("1")
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		etag := `"` + version.Load().(string) + `"`
+		if r.Header.Get("If-None-Match") != "" {
+			atomic.AddInt32(&conditional, 1)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("exports.version = " + version.Load().(string)))
+	}))
+	defer s.Close()
+	m := commonjs.NewURLModule("foo", s.URL+"/foo.js")
+	expect := func(expected string) {
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Fatalf("was expecting %s, found %s", expected, content)
+		}
+	}
+	refresh := func() {
+		if err := m.(commonjs.Refresher).Refresh(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect("exports.version = 1")
+	refresh()
+	expect("exports.version = 1")
+	version.Store("2")
+	expect("exports.version = 1")
+	refresh()
+	expect("exports.version = 2")
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("was expecting 3 requests, got %d", n)
+	}
+	if n := atomic.LoadInt32(&conditional); n != 2 {
+		t.Fatalf("was expecting 2 conditional requests, got %d", n)
+	}
+}
+
+func TestURLModuleRefreshAfter(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(500)
+			return
+		}
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("exports.foo = 1"))
+	}))
+	defer s.Close()
+	m := commonjs.NewURLModuleWithOptions("foo", s.URL+"/foo.js", commonjs.URLOptions{
+		RefreshAfter: time.Nanosecond,
+	})
+	for i := 0; i < 2; i++ {
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "exports.foo = 1" {
+			t.Fatalf("was expecting stale content, found %s", content)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("was expecting 2 requests, got %d", n)
+	}
+}
+
+func TestURLModuleRefreshFailureBacksOff(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(500)
+			return
+		}
+		w.Write([]byte("exports.foo = 1"))
+	}))
+	defer s.Close()
+	m := commonjs.NewURLModuleWithOptions("foo", s.URL+"/foo.js", commonjs.URLOptions{
+		RefreshAfter: 50 * time.Millisecond,
+	})
+	for i := 0; i < 3; i++ {
+		if i == 1 {
+			time.Sleep(60 * time.Millisecond)
+		}
+		content, err := m.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "exports.foo = 1" {
+			t.Fatalf("was expecting stale content, found %s", content)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("was expecting a single failed revalidation, got %d requests", n)
+	}
+}
+
+func TestPinnedURLModule(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bar"))
+	}))
+	defer s.Close()
+	const sum = "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+	m := commonjs.NewPinnedURLModule("foo", s.URL+"/foo.js", sum)
+	if _, err := m.Content(); err != nil {
+		t.Fatal(err)
+	}
+
+	m = commonjs.NewPinnedURLModule("foo", s.URL+"/foo.js", strings.Repeat("0", 64))
+	_, err := m.Content()
+	var checksumErr *commonjs.ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("was expecting a checksum error, got %v", err)
+	}
+	if checksumErr.Module != "foo" || checksumErr.Actual != sum {
+		t.Fatalf("did not find expected error, found %+v", checksumErr)
+	}
+}
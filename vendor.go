@@ -0,0 +1,131 @@
+package commonjs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The name of the lock file written by VendorModules, listing the URL and
+// integrity of each vendored module.
+const VendorLockName = "vendor.lock.json"
+
+type vendorLock struct {
+	Modules []vendorEntry `json:"modules"`
+}
+
+type vendorEntry struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	File      string `json:"file"`
+	Integrity string `json:"integrity"`
+}
+
+// The URL modules in the App, including those wrapped by other modules.
+func (a *App) urlModules() []*urlModule {
+	var modules []*urlModule
+	for _, m := range a.Modules {
+		for m != nil {
+			if um, ok := m.(*urlModule); ok {
+				modules = append(modules, um)
+				break
+			}
+			u, ok := m.(unwrapper)
+			if !ok {
+				break
+			}
+			m = u.unwrap()
+		}
+	}
+	return modules
+}
+
+// Downloads the URL modules in the App and writes them to the directory along
+// with a lock file of their URLs and integrity values. The App then serves the
+// vendored copies, as it would after UseVendoredModules. This should be called
+// before the App starts serving. Note that URL modules may be shared with
+// other Apps, which will also use the vendored copies.
+func (a *App) VendorModules(dir string) error {
+	var lock vendorLock
+	modules := a.urlModules()
+	for _, m := range modules {
+		if clean := path.Clean(m.name); clean != m.name || path.IsAbs(clean) ||
+			clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("module name %q cannot be vendored", m.name)
+		}
+		// the Fallback is not vendored in place of the URL
+		content, err := m.remoteContent(context.Background())
+		if err != nil {
+			return fmt.Errorf("vendoring module %s: %w", m.name, err)
+		}
+		file := m.name + m.ext
+		filename := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, content, 0644); err != nil {
+			return err
+		}
+		lock.Modules = append(lock.Modules, vendorEntry{
+			Name:      m.name,
+			URL:       m.url,
+			File:      file,
+			Integrity: Integrity(content),
+		})
+	}
+	sort.Slice(lock.Modules, func(i, j int) bool {
+		return lock.Modules[i].Name < lock.Modules[j].Name
+	})
+	b, err := json.MarshalIndent(&lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := ioutil.WriteFile(filepath.Join(dir, VendorLockName), b, 0644); err != nil {
+		return err
+	}
+	return a.UseVendoredModules(dir)
+}
+
+// Switches the URL modules in the App to serve the copies written to the
+// directory by VendorModules, allowing for reproducible offline builds. The
+// content of each copy is verified against the lock file, and an error is
+// returned if any URL module was not vendored. This should be called before
+// the App starts serving.
+func (a *App) UseVendoredModules(dir string) error {
+	b, err := ioutil.ReadFile(filepath.Join(dir, VendorLockName))
+	if err != nil {
+		return err
+	}
+	var lock vendorLock
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return fmt.Errorf("invalid lock file %s: %s", VendorLockName, err)
+	}
+	entries := make(map[string]vendorEntry)
+	for _, e := range lock.Modules {
+		entries[e.URL] = e
+	}
+	for _, m := range a.urlModules() {
+		e, ok := entries[m.url]
+		if !ok {
+			return fmt.Errorf("module %s from %s was not vendored", m.name, m.url)
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(e.File))
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if Integrity(content) != e.Integrity {
+			return fmt.Errorf("vendored module %s in %s does not match the lock file",
+				m.name, filename)
+		}
+		m.useVendored(filename, content)
+	}
+	return nil
+}
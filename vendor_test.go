@@ -0,0 +1,113 @@
+package commonjs_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestVendorModules(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("exports.lib = 1"))
+	}))
+	dir := t.TempDir()
+	newApp := func() *commonjs.App {
+		return &commonjs.App{
+			MountPath:    "r",
+			ContentStore: commonjs.NewMemoryStore(),
+			Modules: []commonjs.Module{
+				commonjs.NewWrapModule(
+					commonjs.NewURLModule("vendor/lib", s.URL+"/lib.js"), nil, nil),
+			},
+		}
+	}
+	if err := newApp().VendorModules(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, commonjs.VendorLockName)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	app := newApp()
+	if err := app.UseVendoredModules(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ModulesURL([]string{"vendor/lib"}); err != nil {
+		t.Fatal(err)
+	}
+	origin, err := app.Origin("vendor/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "vendor", "lib.js"); origin != expected {
+		t.Fatalf("was expecting origin %s, got %s", expected, origin)
+	}
+
+	filename := filepath.Join(dir, "vendor", "lib.js")
+	if err := ioutil.WriteFile(filename, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := newApp().UseVendoredModules(dir); err == nil {
+		t.Fatal("was expecting an error for a modified vendored module")
+	}
+}
+
+func TestUseVendoredModulesMissing(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	err := ioutil.WriteFile(
+		filepath.Join(dir, commonjs.VendorLockName), []byte(`{"modules":[]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewURLModule("lib", "http://example.com/lib.js"),
+		},
+	}
+	if err := app.UseVendoredModules(dir); err == nil {
+		t.Fatal("was expecting an error for a module missing from the lock file")
+	}
+}
+
+func TestVendorModulesUnsafe(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.js" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte("exports.lib = 1"))
+	}))
+	defer s.Close()
+	dir := t.TempDir()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewURLModule("lib", s.URL+"/lib.js?v=1"),
+		},
+	}
+	if err := app.VendorModules(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lib.js")); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []commonjs.Module{
+		commonjs.NewURLModule("../escape", s.URL+"/lib.js"),
+		commonjs.NewURLModuleWithOptions("down", s.URL+"/down.js", commonjs.URLOptions{
+			Fallback: commonjs.NewScriptModule("down", []byte("fallback")),
+		}),
+	} {
+		app := &commonjs.App{Modules: []commonjs.Module{m}}
+		if err := app.VendorModules(dir); err == nil {
+			t.Fatalf("was expecting an error vendoring %s", m.Name())
+		}
+	}
+}
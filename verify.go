@@ -0,0 +1,87 @@
+package commonjs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Indicates a module requires another module no provider can supply.
+type MissingRequireError struct {
+	Module  string // the requiring module
+	Require string // the missing module
+}
+
+func (e *MissingRequireError) Error() string {
+	return fmt.Sprintf("module %s requires %s which was not found", e.Module, e.Require)
+}
+
+// Indicates App.Verify found missing requires.
+type VerifyError struct {
+	Missing []MissingRequireError
+}
+
+func (e *VerifyError) Error() string {
+	lines := make([]string, len(e.Missing))
+	for ix, m := range e.Missing {
+		lines[ix] = m.Error()
+	}
+	return "verify failed:\n" + strings.Join(lines, "\n")
+}
+
+// Checks that the given modules and all their dependencies can be found,
+// returning a VerifyError listing every missing require. If no modules are
+// given, all modules listed by the App are checked. This allows for catching
+// problems at build time instead of in the browser.
+func (a *App) Verify(modules []string) error {
+	if len(modules) == 0 {
+		var err error
+		if modules, err = a.ModuleNames(); err != nil {
+			return err
+		}
+	}
+	var missing []MissingRequireError
+	seen := make(map[string]bool)
+	var visit func(parent, name string) error
+	visit = func(parent, name string) error {
+		name = a.Alias(name)
+		if seen[name] {
+			return nil
+		}
+		m, err := a.Module(name)
+		if err != nil {
+			if parent != "" && IsNotFound(err) {
+				missing = append(missing, MissingRequireError{Module: parent, Require: name})
+				return nil
+			}
+			return err
+		}
+		seen[name] = true
+		d, err := a.resolvedRequire(context.Background(), name, m)
+		if err != nil {
+			return err
+		}
+		for _, dep := range d {
+			if err := visit(name, dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range modules {
+		if err := visit("", name); err != nil {
+			return err
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Module != missing[j].Module {
+			return missing[i].Module < missing[j].Module
+		}
+		return missing[i].Require < missing[j].Require
+	})
+	return &VerifyError{Missing: missing}
+}
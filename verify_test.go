@@ -0,0 +1,56 @@
+package commonjs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("a", []byte("require('b'); require('./missing')")),
+			commonjs.NewScriptModule("b", []byte("require('gone')")),
+			commonjs.NewScriptModule("c", []byte("require('gone')")),
+		},
+	}
+	err := app.Verify([]string{"a", "c"})
+	var verifyErr *commonjs.VerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("was expecting a verify error, got %v", err)
+	}
+	expected := []commonjs.MissingRequireError{
+		{Module: "a", Require: "missing"},
+		{Module: "b", Require: "gone"},
+		{Module: "c", Require: "gone"},
+	}
+	if len(verifyErr.Missing) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, verifyErr.Missing)
+	}
+	for ix := range expected {
+		if verifyErr.Missing[ix] != expected[ix] {
+			t.Fatalf("expected %v got %v", expected, verifyErr.Missing)
+		}
+	}
+	if err := app.Verify([]string{"does-not-exist"}); !commonjs.IsNotFound(err) {
+		t.Fatalf("was expecting a not found error, got %v", err)
+	}
+}
+
+func TestMissingRequireAtBuild(t *testing.T) {
+	t.Parallel()
+	app := &commonjs.App{
+		Modules: []commonjs.Module{
+			commonjs.NewScriptModule("a", []byte("require('b')")),
+			commonjs.NewScriptModule("b", []byte("require('gone')")),
+		},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	_, err := app.ModulesURL([]string{"a"})
+	var missing *commonjs.MissingRequireError
+	if !errors.As(err, &missing) || missing.Module != "b" || missing.Require != "gone" {
+		t.Fatalf("was expecting a missing require error, got %v", err)
+	}
+}
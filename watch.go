@@ -0,0 +1,201 @@
+package commonjs
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Polls the modules for changes during development. Changes discard the cached
+// packages and are sent to subscribers like the HotReload endpoint.
+type Watcher struct {
+	app  *App
+	mu   sync.Mutex
+	sums map[string][sha256.Size]byte
+	subs map[chan []string]bool
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Starts watching the modules listed by ModuleNames, checking them for changes
+// at the given interval. The Watcher is stopped when the App is closed.
+// Providers caching content, like CachingProvider, will hide changes.
+func (a *App) Watch(interval time.Duration) (*Watcher, error) {
+	w := &Watcher{
+		app:  a,
+		subs: make(map[chan []string]bool),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if _, err := w.Check(); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	a.watcher = w
+	a.mu.Unlock()
+	a.onClose(w.Close)
+	go w.run(interval)
+	return w, nil
+}
+
+func (w *Watcher) run(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if _, err := w.Check(); err != nil {
+				w.app.log(LogError, "watching modules: %s", err)
+			}
+		}
+	}
+}
+
+// Checks the modules for changes now, returning the names of the changed,
+// added and removed modules. The first check only records the modules.
+func (w *Watcher) Check() ([]string, error) {
+	names, err := w.app.ModuleNames()
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[string][sha256.Size]byte, len(names))
+	for _, name := range names {
+		m, err := w.app.Module(name)
+		if err != nil {
+			return nil, err
+		}
+		if isInlineOnly(m) {
+			continue
+		}
+		content, err := m.Content()
+		if err != nil {
+			return nil, fmt.Errorf("reading module %s: %w", name, err)
+		}
+		sums[name] = sha256.Sum256(content)
+	}
+
+	w.mu.Lock()
+	first := w.sums == nil
+	var changed []string
+	for _, name := range names {
+		sum, ok := sums[name]
+		if !ok {
+			continue
+		}
+		if previous, ok := w.sums[name]; !ok || previous != sum {
+			changed = append(changed, name)
+		}
+	}
+	for name := range w.sums {
+		if _, ok := sums[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	w.sums = sums
+	w.mu.Unlock()
+	if first || len(changed) == 0 {
+		return nil, nil
+	}
+
+	w.app.log(LogInfo, "modules changed: %v", changed)
+	w.app.InvalidatePackages()
+	w.mu.Lock()
+	for sub := range w.subs {
+		select {
+		case sub <- changed:
+		default:
+			w.app.log(LogWarn, "dropping module changes for slow subscriber")
+		}
+	}
+	w.mu.Unlock()
+	return changed, nil
+}
+
+// Subscribes to the names of changed modules. The returned function must be
+// called to unsubscribe. The channel is closed when the Watcher is closed.
+func (w *Watcher) Subscribe() (<-chan []string, func()) {
+	sub := make(chan []string, 16)
+	w.mu.Lock()
+	if w.subs == nil {
+		close(sub)
+	} else {
+		w.subs[sub] = true
+	}
+	w.mu.Unlock()
+	return sub, func() {
+		w.mu.Lock()
+		if w.subs[sub] {
+			delete(w.subs, sub)
+			close(sub)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// Stops watching and closes the subscriptions.
+func (w *Watcher) Close(ctx context.Context) error {
+	w.once.Do(func() {
+		close(w.stop)
+		w.mu.Lock()
+		for sub := range w.subs {
+			close(sub)
+		}
+		w.subs = nil
+		w.mu.Unlock()
+	})
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Streams a server-sent event for each set of changed modules seen by the
+// Watcher, with the data provided by the given function.
+func (a *App) serveEvents(w http.ResponseWriter, r *http.Request, event string, data func([]string) ([]byte, error)) {
+	a.mu.Lock()
+	watcher := a.watcher
+	a.mu.Unlock()
+	if watcher == nil {
+		a.serveError(w, r, 404, "not watching modules", nil)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		a.serveError(w, r, 500, "streaming not supported", nil)
+		return
+	}
+	changes, unsubscribe := watcher.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+	fmt.Fprint(w, ": watching\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case names, ok := <-changes:
+			if !ok {
+				return
+			}
+			out, err := data(names)
+			if err != nil {
+				a.log(LogError, "%s %s: %s", r.Method, r.URL, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, out)
+			flusher.Flush()
+		}
+	}
+}
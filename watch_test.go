@@ -0,0 +1,63 @@
+package commonjs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.commonjs"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.js")
+	if err := ioutil.WriteFile(filename, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := &commonjs.App{
+		MountPath:    "r",
+		Providers:    []commonjs.Provider{commonjs.NewDirProvider(dir)},
+		ContentStore: commonjs.NewMemoryStore(),
+	}
+	w, err := app.Watch(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, unsubscribe := w.Subscribe()
+	defer unsubscribe()
+	before, err := app.ModulesURL([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := w.Check(); err != nil || changed != nil {
+		t.Fatalf("was expecting no changes, got %v %v", changed, err)
+	}
+	if err := ioutil.WriteFile(filename, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if names := <-changes; !reflect.DeepEqual(names, []string{"a"}) {
+		t.Fatalf("was expecting a change to a, got %v", names)
+	}
+	after, err := app.ModulesURL([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Fatal("was expecting a new package url after the change")
+	}
+
+	if err := app.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-changes; ok {
+		t.Fatal("was expecting the subscription to be closed")
+	}
+}